@@ -0,0 +1,105 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/service"
+    "go-crud-example/pkg/actor"
+    "go-crud-example/pkg/middleware"
+    "io"
+    "strings"
+    "text/tabwriter"
+    "time"
+)
+
+const apiKeyUsage = "usage: apikey create -name <name> -scopes <action,...> [-expires <duration>] | list | revoke <id>"
+
+// apiKeyActorPrefix отличает в журнале аудита действия по API-ключу от действий по JWT
+const apiKeyActorPrefix = "apikey:"
+
+// apiKeyAuthenticator проверяет API-ключи для AuthMiddleware
+func apiKeyAuthenticator(svc service.APIKeyService) middleware.APIKeyFunc {
+    return func(ctx context.Context, key string) (string, []string, *time.Time, error) {
+        found, err := svc.AuthenticateAPIKey(ctx, key)
+        if errors.Is(err, service.ErrInvalidAPIKey) {
+            return "", nil, nil, middleware.ErrInvalidAPIKey
+        }
+        if err != nil {
+            return "", nil, nil, err
+        }
+        return apiKeyActorPrefix + found.ID, found.Scopes, found.ExpiresAt, nil
+    }
+}
+
+// runAPIKey выполняет подкоманду apikey. Позволяет выпустить первый ключ с
+// правом api_keys:manage, когда JWT не настроен.
+func runAPIKey(ctx context.Context, svc service.APIKeyService, args []string, out io.Writer) error {
+    if len(args) == 0 {
+        return errors.New(apiKeyUsage)
+    }
+    ctx = actor.NewContext(ctx, actor.System)
+
+    switch args[0] {
+    case "create":
+        flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+        flags.SetOutput(out)
+        name := flags.String("name", "", "key name")
+        scopes := flags.String("scopes", "", "comma-separated actions allowed to the key")
+        expires := flags.Duration("expires", 0, "key lifetime, 0 for a key without expiry")
+        if err := flags.Parse(args[1:]); err != nil {
+            return err
+        }
+
+        req := model.APIKeyRequest{Name: *name}
+        for _, scope := range strings.Split(*scopes, ",") {
+            if scope = strings.TrimSpace(scope); scope != "" {
+                req.Scopes = append(req.Scopes, scope)
+            }
+        }
+        if *expires > 0 {
+            expiresAt := time.Now().Add(*expires)
+            req.ExpiresAt = &expiresAt
+        }
+
+        created, err := svc.CreateAPIKey(ctx, req)
+        if err != nil {
+            return err
+        }
+        fmt.Fprintf(out, "id: %s\nkey: %s\n", created.ID, created.Key)
+        fmt.Fprintln(out, "store the key now, it cannot be shown again")
+        return nil
+    case "list":
+        keys, err := svc.ListAPIKeys(ctx)
+        if err != nil {
+            return err
+        }
+
+        w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+        fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tEXPIRES AT\tSTATUS")
+        now := time.Now()
+        for _, k := range keys {
+            expiresAt, status := "never", "active"
+            if k.ExpiresAt != nil {
+                expiresAt = k.ExpiresAt.Format(time.RFC3339)
+            }
+            if k.RevokedAt != nil {
+                status = "revoked"
+            } else if !k.Active(now) {
+                status = "expired"
+            }
+            fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), expiresAt, status)
+        }
+        return w.Flush()
+    case "revoke":
+        if len(args) != 2 {
+            return errors.New(apiKeyUsage)
+        }
+        _, err := svc.RevokeAPIKey(ctx, args[1])
+        return err
+    default:
+        return errors.New(apiKeyUsage)
+    }
+}
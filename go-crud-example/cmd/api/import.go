@@ -0,0 +1,103 @@
+package main
+
+import (
+    "context"
+    "encoding/csv"
+    "errors"
+    "flag"
+    "fmt"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/service"
+    "go-crud-example/pkg/actor"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+const importUsage = "usage: import [-format csv|ndjson] [-dry-run] [-batch-size n] [-report errors.csv] <file|->"
+
+// runImport выполняет подкоманду import: создает пользователей из файла и
+// печатает итог. Возвращает ошибку, если хотя бы одна строка не импортирована.
+func runImport(ctx context.Context, svc service.UserService, args []string, in io.Reader, out io.Writer) error {
+    flags := flag.NewFlagSet("import", flag.ContinueOnError)
+    flags.SetOutput(out)
+    format := flags.String("format", "", "file format: csv or ndjson, by default detected from the extension")
+    dryRun := flags.Bool("dry-run", false, "validate rows without creating users")
+    batchSize := flags.Int("batch-size", model.DefaultImportBatchSize, "users per insert")
+    reportPath := flags.String("report", "", "write the per-line error report to this CSV file")
+    if err := flags.Parse(args); err != nil {
+        return err
+    }
+    if flags.NArg() != 1 {
+        return errors.New(importUsage)
+    }
+
+    path := flags.Arg(0)
+    opts := model.ImportOptions{Format: *format, DryRun: *dryRun, BatchSize: *batchSize}
+    if opts.Format == "" {
+        switch strings.ToLower(filepath.Ext(path)) {
+        case ".csv":
+            opts.Format = model.ImportFormatCSV
+        case ".ndjson", ".jsonl":
+            opts.Format = model.ImportFormatNDJSON
+        default:
+            return fmt.Errorf("cannot detect format of %q, set -format", path)
+        }
+    }
+
+    if path != "-" {
+        file, err := os.Open(path)
+        if err != nil {
+            return err
+        }
+        defer file.Close()
+        in = file
+    }
+
+    report, err := svc.ImportUsers(actor.NewContext(ctx, actor.System), in, opts)
+    if err != nil {
+        return err
+    }
+
+    mode := ""
+    if report.DryRun {
+        mode = " (dry run)"
+    }
+    fmt.Fprintf(out, "total: %d, imported: %d, failed: %d%s\n", report.Total, report.Imported, report.Failed, mode)
+    if *reportPath != "" {
+        if err := writeImportReport(*reportPath, report); err != nil {
+            return err
+        }
+    } else {
+        for _, e := range report.Errors {
+            if e.Field != "" {
+                fmt.Fprintf(out, "line %d: %s: %s\n", e.Line, e.Field, e.Message)
+            } else {
+                fmt.Fprintf(out, "line %d: %s\n", e.Line, e.Message)
+            }
+        }
+    }
+
+    if report.Failed > 0 {
+        return fmt.Errorf("%d of %d rows failed to import", report.Failed, report.Total)
+    }
+    return nil
+}
+
+// writeImportReport сохраняет ошибки импорта в CSV: строка, поле, сообщение
+func writeImportReport(path string, report *model.ImportReport) error {
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+
+    writer := csv.NewWriter(file)
+    _ = writer.Write([]string{"line", "field", "message"})
+    for _, e := range report.Errors {
+        _ = writer.Write([]string{strconv.Itoa(e.Line), e.Field, e.Message})
+    }
+    writer.Flush()
+    return errors.Join(writer.Error(), file.Close())
+}
@@ -1,20 +1,35 @@
 package main
 
 import (
+    "context"
     "database/sql"
+    "errors"
     "fmt"
     _ "go-crud-example/docs"
+    "go-crud-example/internal/authz"
     "go-crud-example/internal/handler"
+    "go-crud-example/internal/migrations"
     "go-crud-example/internal/repository"
     "go-crud-example/internal/service"
     "go-crud-example/pkg/config"
+    "go-crud-example/pkg/idempotency"
+    "go-crud-example/pkg/jwt"
+    "go-crud-example/pkg/lifecycle"
     "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/metrics"
     "go-crud-example/pkg/middleware"
+    "go-crud-example/pkg/ratelimit"
     "log"
+    "log/slog"
     "net/http"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
 
     "github.com/gorilla/mux"
     _ "github.com/lib/pq"
+    _ "modernc.org/sqlite"
     "github.com/prometheus/client_golang/prometheus/promhttp"
     httpSwagger "github.com/swaggo/http-swagger"
 )
@@ -33,48 +48,304 @@ func main() {
     }
 
     // Инициализируем логгер
-    logger := logger.NewLogger()
+    logger, err := logger.NewLogger(cfg.Log)
+    if err != nil {
+        log.Fatal(err)
+    }
+    slog.SetDefault(logger)
+
+    if err := run(cfg, logger); err != nil {
+        logger.Error("application failed", slog.Any("error", err))
+        os.Exit(1)
+    }
+}
+
+func run(cfg *config.Config, logger *slog.Logger) error {
+    // Подкоманда migrate управляет схемой БД и завершает работу
+    if len(os.Args) > 1 && os.Args[1] == "migrate" {
+        if cfg.Database.Driver == config.StorageDriverMemory {
+            return errors.New("migrate is not supported by the memory storage driver")
+        }
+
+        db, err := initDB(cfg.Database)
+        if err != nil {
+            return err
+        }
+        defer db.Close()
+        return runMigrate(context.Background(), db, migrationDialect(cfg.Database.Driver), os.Args[2:], os.Stdout)
+    }
+
+    // Подкоманда import создает пользователей из файла и завершает работу
+    if len(os.Args) > 1 && os.Args[1] == "import" {
+        if cfg.Database.Driver == config.StorageDriverMemory {
+            return errors.New("import is not supported by the memory storage driver")
+        }
 
-    // Инициализируем подключение к БД
-    db, err := initDB(cfg.Database)
+        db, err := initDB(cfg.Database)
+        if err != nil {
+            return err
+        }
+        defer db.Close()
+
+        ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+        defer stop()
+        userService := service.NewUserService(newRepository(db, cfg.Database.Driver))
+        return runImport(ctx, userService, os.Args[2:], os.Stdin, os.Stdout)
+    }
+
+    // Подкоманда apikey выпускает, перечисляет и отзывает API-ключи
+    if len(os.Args) > 1 && os.Args[1] == "apikey" {
+        if cfg.Database.Driver == config.StorageDriverMemory {
+            return errors.New("apikey is not supported by the memory storage driver")
+        }
+
+        db, err := initDB(cfg.Database)
+        if err != nil {
+            return err
+        }
+        defer db.Close()
+        apiKeyService := service.NewAPIKeyService(newAPIKeyRepository(db, cfg.Database.Driver))
+        return runAPIKey(context.Background(), apiKeyService, os.Args[2:], os.Stdout)
+    }
+
+    // Менеджер жизненного цикла останавливает серверы по SIGINT/SIGTERM
+    manager := lifecycle.NewManager(logger, cfg.Server.ShutdownTimeout, cfg.Server.ShutdownDelay)
+
+    // Инициализируем хранилище
+    stores, err := initStorage(cfg.Database, manager)
     if err != nil {
-        logger.Fatal(err)
+        return err
     }
-    defer db.Close()
+    logger.Info("storage initialized", slog.String("driver", cfg.Database.Driver))
 
     // Инициализируем слои приложения
-    userRepo := repository.NewUserRepository(db)
+    userRepo := repository.NewInstrumentedUserRepository(stores.users)
     userService := service.NewUserService(userRepo)
+    apiKeyService := service.NewAPIKeyService(stores.apiKeys)
+
+    // Права проверяются по ролям из токена и scopes ключа, поэтому только при
+    // включенной аутентификации
+    if cfg.Auth.Enabled() {
+        policy, err := initPolicy(cfg.Auth)
+        if err != nil {
+            return err
+        }
+        userService = service.NewAuthorizedUserService(userService, policy)
+        apiKeyService = service.NewAuthorizedAPIKeyService(apiKeyService, policy)
+    }
     userHandler := handler.NewUserHandler(userService, logger)
+    if cfg.Auth.APIKeys {
+        userHandler.WithAPIKeys(apiKeyService)
+    }
+
+    // Окончательно удаляем пользователей после срока хранения
+    if cfg.Purge.Retention > 0 {
+        purger := service.NewPurger(userRepo, cfg.Purge.Retention, cfg.Purge.Interval, logger)
+        manager.Go("purger", purger.Run)
+    }
 
     // Создаем роутер
     router := mux.NewRouter()
 
-    // Добавляем middleware для логирования
+    // Добавляем идентификатор запроса, метрики и middleware для логирования
+    router.Use(middleware.RequestIDMiddleware)
+    router.Use(middleware.MetricsMiddleware)
     router.Use(middleware.LoggingMiddleware(logger))
 
+    // Проверяем JWT и API-ключи, кроме открытых маршрутов вроде /health
+    if cfg.Auth.Enabled() {
+        authOptions := middleware.AuthOptions{Exempt: cfg.Auth.Exempt}
+        if cfg.Auth.JWTEnabled() {
+            verifier, err := initVerifier(cfg.Auth)
+            if err != nil {
+                return err
+            }
+            authOptions.Verifier = verifier
+        }
+        if cfg.Auth.APIKeys {
+            authOptions.APIKey = apiKeyAuthenticator(apiKeyService)
+        }
+        router.Use(middleware.AuthMiddleware(authOptions))
+    } else {
+        logger.Warn("authentication is disabled, set JWT_SECRET, JWT_JWKS or API_KEYS_ENABLED to enable it")
+    }
+
+    // Ограничиваем частоту запросов клиента, после аутентификации, чтобы знать клиента
+    if cfg.RateLimit.Enabled() {
+        router.Use(middleware.RateLimitMiddleware(middleware.RateLimitOptions{
+            Store:   ratelimit.NewMemoryStore(),
+            Default: cfg.RateLimit.Default,
+            Routes:  cfg.RateLimit.Routes,
+            Exempt:  cfg.RateLimit.Exempt,
+        }))
+    }
+
+    // Повторяем сохраненный ответ на запрос с уже использованным Idempotency-Key
+    if cfg.Idempotency.TTL > 0 {
+        router.Use(middleware.IdempotencyMiddleware(middleware.IdempotencyOptions{
+            Store:       stores.idempotency,
+            TTL:         cfg.Idempotency.TTL,
+            LockTimeout: cfg.Idempotency.LockTimeout,
+            Routes:      cfg.Idempotency.Routes,
+        }))
+        manager.Go("idempotency-cleanup", idempotency.Cleanup(stores.idempotency, time.Hour, logger))
+    }
+
+    // Ограничиваем время обработки запроса, кроме потоковых выгрузок
+    router.Use(middleware.TimeoutMiddleware(cfg.Server.RequestTimeout, handler.StreamingRoutes...))
+
     // Регистрируем маршруты
     userHandler.RegisterRoutes(router)
 
+    // Readiness probe переключается в 503 в начале остановки
+    router.Handle("/ready", manager.ReadinessHandler()).Methods("GET")
+
     // Добавляем Swagger
     router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
-    // Запускаем HTTP сервер на порту 8000
-    go func() {
-        serverAddr := fmt.Sprintf(":%s", cfg.Server.Port)
-        logger.Printf("Сервер запускается на порту %s...", cfg.Server.Port)
-        logger.Fatal(http.ListenAndServe(serverAddr, router))
-    }()
+    // HTTP сервер API
+    manager.AddServer("api", &http.Server{
+        Addr:              fmt.Sprintf(":%s", cfg.Server.Port),
+        Handler:           router,
+        ReadHeaderTimeout: 10 * time.Second,
+    })
+
+    // Сервер метрик
+    manager.AddServer("metrics", &http.Server{
+        Addr:              fmt.Sprintf(":%s", cfg.Server.MetricsPort),
+        Handler:           promhttp.Handler(),
+        ReadHeaderTimeout: 10 * time.Second,
+    })
 
-    // Запускаем сервер метрик на порту 9090
-    logger.Printf("Сервер метрик запускается на порту 9090...")
-    if err := http.ListenAndServe(":9090", promhttp.Handler()); err != nil {
-        logger.Fatal(err)
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    return manager.Run(ctx)
+}
+
+// stores - хранилища выбранного драйвера
+type stores struct {
+    users       repository.UserRepository
+    apiKeys     repository.APIKeyRepository
+    idempotency idempotency.Store
+}
+
+// initStorage создает хранилища выбранного драйвера. Пул соединений
+// регистрируется в менеджере первым, чтобы закрыться последним.
+func initStorage(cfg config.DatabaseConfig, manager *lifecycle.Manager) (*stores, error) {
+    if cfg.Driver == config.StorageDriverMemory {
+        return &stores{
+            users:       repository.NewMemoryUserRepository(),
+            apiKeys:     repository.NewMemoryAPIKeyRepository(),
+            idempotency: idempotency.NewMemoryStore(),
+        }, nil
     }
+
+    db, err := initDB(cfg)
+    if err != nil {
+        return nil, err
+    }
+
+    // Применяем миграции при старте
+    if cfg.AutoMigrate {
+        if err := migrateUp(db, cfg.Driver); err != nil {
+            return nil, errors.Join(err, db.Close())
+        }
+    }
+
+    // Метрики пула соединений
+    if err := metrics.RegisterDBStats(db, cfg.DBName); err != nil {
+        return nil, errors.Join(err, db.Close())
+    }
+
+    manager.OnShutdown("database", func(context.Context) error {
+        return db.Close()
+    })
+
+    result := &stores{
+        users:       newRepository(db, cfg.Driver),
+        apiKeys:     newAPIKeyRepository(db, cfg.Driver),
+        idempotency: repository.NewIdempotencyStore(db),
+    }
+    if cfg.Driver == config.StorageDriverSQLite {
+        result.idempotency = repository.NewSQLiteIdempotencyStore(db)
+    }
+    return result, nil
+}
+
+// newRepository создает SQL репозиторий для драйвера БД
+func newRepository(db *sql.DB, driver string) repository.UserRepository {
+    if driver == config.StorageDriverSQLite {
+        return repository.NewSQLiteUserRepository(db)
+    }
+    return repository.NewUserRepository(db)
+}
+
+// newAPIKeyRepository создает SQL репозиторий API-ключей для драйвера БД
+func newAPIKeyRepository(db *sql.DB, driver string) repository.APIKeyRepository {
+    if driver == config.StorageDriverSQLite {
+        return repository.NewSQLiteAPIKeyRepository(db)
+    }
+    return repository.NewAPIKeyRepository(db)
+}
+
+func migrateUp(db *sql.DB, driver string) error {
+    migrator, err := migrations.NewMigrator(db, migrationDialect(driver))
+    if err != nil {
+        return err
+    }
+    return migrator.Up(context.Background())
+}
+
+func migrationDialect(driver string) migrations.Dialect {
+    if driver == config.StorageDriverSQLite {
+        return migrations.SQLite
+    }
+    return migrations.Postgres
+}
+
+// initVerifier создает проверку JWT. JWKS загружается сразу, чтобы ошибка
+// в настройках обнаружилась при старте, а не на первом запросе. Секрет HS256
+// проверяется после JWKS: он подходит к любому kid и иначе закрыл бы ключи oct
+// из набора.
+func initVerifier(cfg config.AuthConfig) (*jwt.Verifier, error) {
+    var keys jwt.KeySets
+    if cfg.JWKS != "" {
+        jwks := jwt.NewJWKS(cfg.JWKS, cfg.JWKSRefresh)
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        if err := jwks.Load(ctx); err != nil {
+            return nil, err
+        }
+        keys = append(keys, jwks)
+    }
+    if cfg.JWTSecret != "" {
+        keys = append(keys, jwt.Secret(cfg.JWTSecret))
+    }
+
+    return jwt.NewVerifier(keys, jwt.Options{
+        Issuer:     cfg.Issuer,
+        Audience:   cfg.Audience,
+        Leeway:     cfg.Leeway,
+        RolesClaim: cfg.RolesClaim,
+    }), nil
+}
+
+// initPolicy загружает политику доступа из файла или возвращает встроенную
+func initPolicy(cfg config.AuthConfig) (*authz.Policy, error) {
+    if cfg.PolicyFile == "" {
+        return authz.Default(), nil
+    }
+    return authz.Load(cfg.PolicyFile)
 }
 
 func initDB(cfg config.DatabaseConfig) (*sql.DB, error) {
-    db, err := sql.Open("postgres", cfg.GetDSN())
+    driverName, dsn := "postgres", cfg.GetDSN()
+    if cfg.Driver == config.StorageDriverSQLite {
+        driverName, dsn = "sqlite", cfg.GetSQLiteDSN()
+    }
+
+    db, err := sql.Open(driverName, dsn)
     if err != nil {
         return nil, fmt.Errorf("error connecting to the database: %w", err)
     }
@@ -83,17 +354,5 @@ func initDB(cfg config.DatabaseConfig) (*sql.DB, error) {
         return nil, fmt.Errorf("error connecting to the database: %w", err)
     }
 
-    // Создаем таблицу, если она не существует
-    _, err = db.Exec(`
-        CREATE TABLE IF NOT EXISTS users (
-            id SERIAL PRIMARY KEY,
-            name VARCHAR(100) NOT NULL,
-            age INT NOT NULL
-        )
-    `)
-    if err != nil {
-        return nil, fmt.Errorf("error creating table: %w", err)
-    }
-
     return db, nil
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "go-crud-example/internal/migrations"
+    "io"
+    "strconv"
+    "text/tabwriter"
+    "time"
+)
+
+const migrateUsage = "usage: migrate up|down|status|goto <version>"
+
+// runMigrate выполняет подкоманду migrate
+func runMigrate(ctx context.Context, db *sql.DB, dialect migrations.Dialect, args []string, out io.Writer) error {
+    if len(args) == 0 {
+        return fmt.Errorf(migrateUsage)
+    }
+
+    migrator, err := migrations.NewMigrator(db, dialect)
+    if err != nil {
+        return err
+    }
+
+    switch args[0] {
+    case "up":
+        return migrator.Up(ctx)
+    case "down":
+        return migrator.Down(ctx)
+    case "goto":
+        if len(args) != 2 {
+            return fmt.Errorf(migrateUsage)
+        }
+        version, err := strconv.ParseInt(args[1], 10, 64)
+        if err != nil {
+            return fmt.Errorf("invalid version %q: %w", args[1], err)
+        }
+        return migrator.Goto(ctx, version)
+    case "status":
+        statuses, err := migrator.Status(ctx)
+        if err != nil {
+            return err
+        }
+
+        w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+        fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+        for _, s := range statuses {
+            appliedAt := "pending"
+            if s.Applied {
+                appliedAt = s.AppliedAt.Format(time.RFC3339)
+            }
+            fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+        }
+        return w.Flush()
+    default:
+        return fmt.Errorf(migrateUsage)
+    }
+}
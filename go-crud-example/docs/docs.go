@@ -17,25 +17,129 @@ const docTemplate = `{
     "paths": {
         "/users": {
             "get": {
-                "description": "Получить список всех пользователей из базы данных",
+                "description": "Получить страницу списка пользователей с keyset-пагинацией, сортировкой и фильтрами",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "users"
                 ],
-                "summary": "Получить список всех пользователей",
+                "summary": "Получить список пользователей",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "maximum": 100,
+                        "minimum": 1,
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из поля next_cursor предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "name",
+                            "-name",
+                            "age",
+                            "-age"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Поле сортировки, префикс - означает убывание",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по началу имени",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "min_age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "max_age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Включить мягко удаленных пользователей",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/go-crud-example_internal_model.User"
+                            "$ref": "#/definitions/go-crud-example_internal_model.UserPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
                             }
                         }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
             },
             "post": {
                 "description": "Создать нового пользователя в базе данных",
@@ -58,6 +162,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/go-crud-example_internal_model.User"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор с тем же ключом получает сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -65,9 +175,87 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/go-crud-example_internal_model.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия пользователя"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "boolean",
+                                "description": "true, если ответ повторен по Idempotency-Key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email is already taken (email_taken) or request with the same Idempotency-Key is in progress (idempotency_key_in_use)",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large (request_too_large)",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key was already used with a different request (idempotency_key_reused)",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
             }
         },
         "/users/{id}": {
@@ -87,6 +275,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag, уже имеющийся у клиента",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -94,15 +288,74 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/go-crud-example_internal_model.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия пользователя"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified",
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия пользователя"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
                         }
                     },
                     "404": {
                         "description": "User not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
             },
             "put": {
                 "description": "Обновить данные существующего пользователя",
@@ -124,6 +377,12 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "ETag версии, которую изменяет клиент",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
                     {
                         "description": "Данные пользователя",
                         "name": "user",
@@ -139,18 +398,86 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/go-crud-example_internal_model.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия пользователя"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
                         }
                     },
                     "404": {
                         "description": "User not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email is already taken (email_taken)",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "User was modified since the given version",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
             },
             "delete": {
-                "description": "Удалить пользователя из базы данных",
+                "description": "Пометить пользователя удаленным. Его можно восстановить до окончательной очистки",
                 "tags": [
                     "users"
                 ],
@@ -168,30 +495,1592 @@ const docTemplate = `{
                     "204": {
                         "description": "No Content"
                     },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
                     "404": {
                         "description": "User not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
                         }
                     }
-                }
-            }
-        }
-    },
-    "definitions": {
-        "go-crud-example_internal_model.User": {
-            "type": "object",
-            "properties": {
-                "age": {
-                    "type": "integer"
                 },
-                "id": {
-                    "type": "string"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            },
+            "patch": {
+                "description": "Частично изменить пользователя. Поддерживаются JSON Merge Patch (RFC 7396) и JSON Patch (RFC 6902). Изменяются только поля, затронутые патчем",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Частично обновить пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag версии, которую изменяет клиент",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Merge patch (объект) или JSON Patch (массив операций)",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия пользователя"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid patch or patched user failed validation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "JSON Patch test operation failed; Email is already taken (email_taken)",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "User was modified since the given version",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large (request_too_large)",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported patch media type",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
                 },
-                "name": {
-                    "type": "string"
-                }
-            }
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/users/{id}:restore": {
+            "post": {
+                "description": "Снять пометку об удалении. Для неудаленного пользователя возвращает его без изменений",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Восстановить пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия пользователя"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "produces": [
+                    "application/json"
+                ],
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/users/{id}:purge": {
+            "post": {
+                "description": "Безвозвратно удалить пользователя, ранее удаленного через DELETE",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Окончательно удалить пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "User is not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/users/{id}/history": {
+            "get": {
+                "description": "Журнал изменений пользователя в порядке записи. Доступен и после окончательного удаления",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "История изменений пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "maximum": 500,
+                        "minimum": 1,
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из поля next_cursor предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Только события не раньше этого момента (RFC 3339)",
+                        "name": "since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.AuditPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/audit": {
+            "get": {
+                "description": "Изменения всех пользователей в порядке записи",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Журнал аудита",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "maximum": 500,
+                        "minimum": 1,
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы из поля next_cursor предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Только события не раньше этого момента (RFC 3339)",
+                        "name": "since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.AuditPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/users:batch": {
+            "post": {
+                "description": "Выполнить до 1000 операций create, update и delete. В режиме atomic (по умолчанию) ошибка любой операции отменяет весь пакет, в режиме best_effort применяются все успешные операции",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Пакетное изменение пользователей",
+                "parameters": [
+                    {
+                        "description": "Пакет операций",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.batchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid batch",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large (request_too_large)",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/users/export": {
+            "get": {
+                "description": "Выгрузить всех пользователей, подходящих под фильтры списка. Формат выбирается по Accept, по умолчанию NDJSON. Ответ передается потоком и не ограничен таймаутом запроса",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/csv"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Выгрузить пользователей",
+                "parameters": [
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "name",
+                            "-name",
+                            "age",
+                            "-age"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Поле сортировки, префикс - означает убывание",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по началу имени",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "min_age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "max_age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Включить мягко удаленных пользователей",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток пользователей: по одному JSON объекту на строку или CSV с заголовком",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Unsupported Accept",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/users/import": {
+            "post": {
+                "description": "Создать пользователей из CSV (колонки name и age) или NDJSON файла. Файл передается телом запроса или полем file формы multipart/form-data. Строки проверяются по отдельности, корректные создаются пакетами, ошибки возвращаются в отчете с номерами строк",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Импорт пользователей",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Только проверить строки, ничего не создавая",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Формат файла, по умолчанию по Content-Type",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отчет об импорте, CSV (line,field,message) при Accept: text/csv",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid import",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported file format",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/users/search": {
+            "get": {
+                "description": "Поиск по началу слов имени и email и по похожести имени (устойчив к опечаткам). Результаты упорядочены по релевантности, совпадения в highlights обернуты в <mark>",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Найти пользователей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "maxLength": 200,
+                        "description": "Строка поиска",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "maximum": 100,
+                        "minimum": 1,
+                        "description": "Максимум результатов",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.SearchResults"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/api-keys": {
+            "get": {
+                "description": "Все ключи, включая отозванные и истекшие. Сами ключи не возвращаются. Требует api_keys:manage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Список API-ключей",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "api_keys": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/go-crud-example_internal_model.APIKey"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            },
+            "post": {
+                "description": "Выпускает ключ со scopes - действиями политики доступа. Открытый ключ возвращается только в этом ответе. Требует api_keys:manage",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Создать API-ключ",
+                "parameters": [
+                    {
+                        "description": "Параметры ключа",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.CreatedAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid name, scopes or expiry",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing api_keys:manage, requested scopes exceed the caller's rights or the key outlives the caller's key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        },
+        "/api-keys/{id}": {
+            "delete": {
+                "description": "Ключ перестает приниматься сразу. Повторный отзыв не меняет revoked_at. Требует api_keys:manage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Отозвать API-ключ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID ключа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_internal_model.APIKey"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token or API key",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Role or API key scopes do not allow the operation",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Секунд до следующей попытки"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Request timed out",
+                        "schema": {
+                            "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ]
+            }
+        }
+    },
+    "definitions": {
+        "go-crud-example_internal_model.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Начало ключа, по которому его можно узнать",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "go-crud-example_internal_model.APIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "Срок действия, без него ключ бессрочный",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "scopes": {
+                    "description": "Действия политики доступа, например users:import, или *",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "go-crud-example_internal_model.AuditEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete",
+                        "restore",
+                        "purge"
+                    ],
+                    "example": "update"
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "anonymous"
+                },
+                "after": {
+                    "type": "object",
+                    "description": "Значения измененных полей после операции, для purge отсутствует"
+                },
+                "before": {
+                    "type": "object",
+                    "description": "Значения измененных полей до операции, для create отсутствует"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "1"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "1"
+                }
+            }
+        },
+        "go-crud-example_internal_model.AuditPage": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-crud-example_internal_model.AuditEvent"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string",
+                    "description": "Курсор следующей страницы, пуст на последней"
+                }
+            }
+        },
+        "go-crud-example_internal_model.BatchOperation": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "ID пользователя для update и delete",
+                    "example": "1"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ],
+                    "example": "create"
+                },
+                "user": {
+                    "$ref": "#/definitions/go-crud-example_internal_model.User"
+                },
+                "version": {
+                    "type": "integer",
+                    "description": "Ожидаемая версия для update, 0 - без проверки"
+                }
+            }
+        },
+        "go-crud-example_internal_model.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ],
+                    "default": "atomic"
+                },
+                "operations": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/go-crud-example_internal_model.BatchOperation"
+                    }
+                }
+            }
+        },
+        "go-crud-example_internal_model.CreatedAPIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Открытый ключ, больше нигде не доступен",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Начало ключа, по которому его можно узнать",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "go-crud-example_internal_model.ImportError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "age"
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must satisfy lte=150"
+                }
+            }
+        },
+        "go-crud-example_internal_model.ImportReport": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-crud-example_internal_model.ImportError"
+                    }
+                },
+                "errors_truncated": {
+                    "type": "boolean",
+                    "description": "В errors только первые 1000 ошибок"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer",
+                    "description": "При dry_run - число строк, которые были бы импортированы"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-crud-example_internal_model.SearchResult": {
+            "type": "object",
+            "properties": {
+                "highlights": {
+                    "description": "Совпавшие поля, текст экранирован как HTML",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "score": {
+                    "description": "Релевантность, сравнима только внутри одного ответа",
+                    "type": "number"
+                },
+                "user": {
+                    "$ref": "#/definitions/go-crud-example_internal_model.User"
+                }
+            }
+        },
+        "go-crud-example_internal_model.SearchResults": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-crud-example_internal_model.SearchResult"
+                    }
+                }
+            }
+        },
+        "go-crud-example_internal_model.User": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string",
+                    "format": "email",
+                    "maxLength": 254,
+                    "description": "Необязательный, уникален без учета регистра",
+                    "example": "john@example.com"
+                },
+                "version": {
+                    "type": "integer",
+                    "description": "Версия записи, только для чтения"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "description": "Время мягкого удаления, только для удаленных пользователей"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "description": "Время создания, только для чтения"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "description": "Время последнего изменения, только для чтения"
+                }
+            }
+        },
+        "go-crud-example_internal_model.UserPage": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-crud-example_internal_model.User"
+                    }
+                }
+            }
+        },
+        "go-crud-example_pkg_problem.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
+        "go-crud-example_pkg_problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-crud-example_pkg_problem.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.batchItemResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/go-crud-example_pkg_problem.Problem"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "user": {
+                    "$ref": "#/definitions/go-crud-example_internal_model.User"
+                }
+            }
+        },
+        "internal_handler.batchResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_handler.batchItemResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "JWT в заголовке: Bearer <token>",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
+        "ApiKeyAuth": {
+            "description": "API-ключ в заголовке X-API-Key или Authorization: ApiKey <key>",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
         }
     }
 }`
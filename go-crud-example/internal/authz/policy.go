@@ -0,0 +1,182 @@
+// Package authz описывает, какие действия над пользователями разрешены ролям.
+// Политика задается JSON-файлом, без файла используется встроенная политика
+// default_policy.json.
+package authz
+
+import (
+    "bytes"
+    "context"
+    _ "embed"
+    "encoding/json"
+    "fmt"
+    "os"
+    "sort"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/actor"
+)
+
+// Действия, на которые выдаются права
+const (
+    ActionList        = "users:list"
+    ActionRead        = "users:read"
+    ActionReadDeleted = "users:read_deleted"
+    ActionSearch      = "users:search"
+    ActionExport      = "users:export"
+    ActionCreate      = "users:create"
+    ActionUpdate      = "users:update"
+    ActionDelete      = "users:delete"
+    ActionRestore     = "users:restore"
+    ActionPurge       = "users:purge"
+    ActionImport      = "users:import"
+    ActionAudit       = "audit:read"
+    ActionAPIKeys     = "api_keys:manage"
+
+    // AllActions в allow разрешает роли любое действие
+    AllActions = "*"
+)
+
+// targetActions - действия над одним пользователем. Только их можно разрешить
+// роли в self: для них известно, чья запись затронута.
+var targetActions = map[string]bool{
+    ActionRead:    true,
+    ActionUpdate:  true,
+    ActionDelete:  true,
+    ActionRestore: true,
+    ActionPurge:   true,
+    ActionAudit:   true,
+}
+
+var otherActions = map[string]bool{
+    ActionList:        true,
+    ActionReadDeleted: true,
+    ActionSearch:      true,
+    ActionExport:      true,
+    ActionCreate:      true,
+    ActionImport:      true,
+    ActionAPIKeys:     true,
+}
+
+//go:embed default_policy.json
+var defaultPolicy []byte
+
+// Role - права одной роли
+type Role struct {
+    // Allow - действия над любыми пользователями
+    Allow []string `json:"allow"`
+    // Self - действия только над собственной записью, id которой равен sub токена
+    Self []string `json:"self"`
+}
+
+// Policy - роли и их права
+type Policy struct {
+    // DefaultRoles выдаются каждому аутентифицированному актору в дополнение
+    // к ролям из токена
+    DefaultRoles []string        `json:"default_roles"`
+    Roles        map[string]Role `json:"roles"`
+}
+
+// Default возвращает встроенную политику: admin, editor, viewer и user
+func Default() *Policy {
+    policy, err := Parse(defaultPolicy)
+    if err != nil {
+        panic(fmt.Sprintf("invalid default policy: %v", err))
+    }
+    return policy
+}
+
+// Load читает политику из JSON-файла
+func Load(path string) (*Policy, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read policy: %w", err)
+    }
+    policy, err := Parse(data)
+    if err != nil {
+        return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+    }
+    return policy, nil
+}
+
+// Parse разбирает и проверяет политику. Неизвестные поля, действия и роли -
+// ошибка, чтобы опечатка не выдала или не отняла права незаметно.
+func Parse(data []byte) (*Policy, error) {
+    var policy Policy
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+    if err := decoder.Decode(&policy); err != nil {
+        return nil, err
+    }
+
+    for _, name := range sortedRoles(policy.Roles) {
+        role := policy.Roles[name]
+        for _, action := range role.Allow {
+            if !IsAction(action) {
+                return nil, fmt.Errorf("role %q: unknown action %q", name, action)
+            }
+        }
+        for _, action := range role.Self {
+            if !targetActions[action] {
+                return nil, fmt.Errorf("role %q: action %q cannot be limited to self", name, action)
+            }
+        }
+    }
+    for _, name := range policy.DefaultRoles {
+        if _, ok := policy.Roles[name]; !ok {
+            return nil, fmt.Errorf("unknown default role %q", name)
+        }
+    }
+    return &policy, nil
+}
+
+// IsAction сообщает, что action - известное действие или AllActions
+func IsAction(action string) bool {
+    return action == AllActions || targetActions[action] || otherActions[action]
+}
+
+// Authorize проверяет, что актор из ctx может выполнить action. userID - id
+// затронутого пользователя для правил self, пустой для действий над списком.
+// Актор со scopes (API-ключ) может выполнить только перечисленные в них
+// действия, роли и правила self для него не применяются.
+// Отказ оборачивает model.ErrForbidden.
+func (p *Policy) Authorize(ctx context.Context, action, userID string) error {
+    if scopes, ok := actor.ScopesFromContext(ctx); ok {
+        if contains(scopes, action) || contains(scopes, AllActions) {
+            return nil
+        }
+        return fmt.Errorf("%w: %s is not allowed", model.ErrForbidden, action)
+    }
+
+    subject := actor.FromContext(ctx)
+    self := userID != "" && subject == userID
+
+    roles := actor.RolesFromContext(ctx)
+    for _, name := range append(roles[:len(roles):len(roles)], p.DefaultRoles...) {
+        role, ok := p.Roles[name]
+        if !ok {
+            continue
+        }
+        if contains(role.Allow, action) || contains(role.Allow, AllActions) || (self && contains(role.Self, action)) {
+            return nil
+        }
+    }
+    return fmt.Errorf("%w: %s is not allowed", model.ErrForbidden, action)
+}
+
+func contains(list []string, value string) bool {
+    for _, v := range list {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
+
+func sortedRoles(roles map[string]Role) []string {
+    names := make([]string, 0, len(roles))
+    for name := range roles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
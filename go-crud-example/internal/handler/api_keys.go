@@ -0,0 +1,61 @@
+package handler
+
+import (
+    "encoding/json"
+    "log/slog"
+    "net/http"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/service"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/problem"
+)
+
+// WithAPIKeys включает маршруты управления API-ключами /api-keys
+func (h *UserHandler) WithAPIKeys(keys service.APIKeyService) *UserHandler {
+    h.apiKeys = keys
+    return h
+}
+
+// CreateAPIKey выпускает ключ. Открытый ключ возвращается только в этом ответе.
+func (h *UserHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+    var req model.APIKeyRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request body")
+        return
+    }
+
+    created, err := h.apiKeys.CreateAPIKey(r.Context(), req)
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+    logger.AddAttrs(r.Context(), slog.String("api_key_id", created.ID))
+
+    w.Header().Set("Cache-Control", "no-store")
+    h.writeJSON(w, r, http.StatusCreated, created)
+}
+
+func (h *UserHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+    keys, err := h.apiKeys.ListAPIKeys(r.Context())
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    h.writeJSON(w, r, http.StatusOK, map[string][]model.APIKey{"api_keys": keys})
+}
+
+// RevokeAPIKey отзывает ключ и возвращает его с заполненным revoked_at
+func (h *UserHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+    logger.AddAttrs(r.Context(), slog.String("api_key_id", id))
+    key, err := h.apiKeys.RevokeAPIKey(r.Context(), id)
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    h.writeJSON(w, r, http.StatusOK, key)
+}
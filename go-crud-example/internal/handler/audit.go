@@ -0,0 +1,76 @@
+package handler
+
+import (
+    "fmt"
+    "log/slog"
+    "net/http"
+    "net/url"
+    "time"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/logger"
+)
+
+// GetUserHistory возвращает журнал изменений одного пользователя. История
+// доступна и после окончательного удаления пользователя.
+func (h *UserHandler) GetUserHistory(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+    logger.AddAttrs(r.Context(), slog.String("user_id", id))
+
+    query, err := parseAuditQuery(r.URL.Query())
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+    query.UserID = id
+
+    h.writeAuditPage(w, r, query)
+}
+
+// GetAuditEvents возвращает журнал изменений всех пользователей
+func (h *UserHandler) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+    query, err := parseAuditQuery(r.URL.Query())
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    h.writeAuditPage(w, r, query)
+}
+
+func (h *UserHandler) writeAuditPage(w http.ResponseWriter, r *http.Request, query model.AuditQuery) {
+    page, err := h.service.GetAuditEvents(r.Context(), query)
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    h.writeJSON(w, r, http.StatusOK, page)
+}
+
+// parseAuditQuery читает параметры since (RFC 3339), cursor и limit
+func parseAuditQuery(values url.Values) (model.AuditQuery, error) {
+    query := model.AuditQuery{Cursor: values.Get("cursor")}
+
+    limit, err := parseIntParam(values, "limit")
+    if err != nil {
+        return query, err
+    }
+    if limit != nil {
+        if *limit <= 0 {
+            return query, fmt.Errorf("%w: limit must be positive", model.ErrInvalidQuery)
+        }
+        query.Limit = *limit
+    }
+
+    if raw := values.Get("since"); raw != "" {
+        since, err := time.Parse(time.RFC3339Nano, raw)
+        if err != nil {
+            return query, fmt.Errorf("%w: since must be an RFC 3339 timestamp", model.ErrInvalidQuery)
+        }
+        query.Since = &since
+    }
+
+    return query, nil
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+    "encoding/json"
+    "log/slog"
+    "net/http"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/problem"
+)
+
+// maxBatchBodySize ограничивает тело пакета: около 4 КиБ на каждую из
+// model.MaxBatchOperations операций. Тело декодируется целиком до проверки
+// числа операций.
+const maxBatchBodySize = 4 << 20
+
+// batchResponse - итог пакета: результаты в порядке операций запроса
+type batchResponse struct {
+    Succeeded int               `json:"succeeded"`
+    Failed    int               `json:"failed"`
+    Results   []batchItemResult `json:"results"`
+}
+
+// batchItemResult повторяет ответ, который вернул бы одиночный запрос операции
+type batchItemResult struct {
+    Index  int              `json:"index"`
+    Status int              `json:"status"`
+    User   *model.User      `json:"user,omitempty"`
+    Error  *problem.Problem `json:"error,omitempty"`
+}
+
+// BatchUsers выполняет до model.MaxBatchOperations операций create, update и
+// delete. Ответ 200 содержит результат каждой операции; в атомарном режиме
+// (по умолчанию) при любой ошибке не применяется ни одна операция.
+func (h *UserHandler) BatchUsers(w http.ResponseWriter, r *http.Request) {
+    var batch model.BatchRequest
+    decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+    decoder.DisallowUnknownFields()
+    if err := decoder.Decode(&batch); err != nil {
+        writeBodyError(w, r, err)
+        return
+    }
+
+    results, err := h.service.BatchUsers(r.Context(), batch)
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    response := batchResponse{Results: make([]batchItemResult, len(results))}
+    for i, result := range results {
+        item := batchItemResult{Index: i, User: result.User}
+        if result.Err != nil {
+            item.Error = h.problemFor(r, result.Err)
+            item.Status = item.Error.Status
+            response.Failed++
+        } else {
+            item.Status = batchStatus(batch.Operations[i].Op)
+            response.Succeeded++
+        }
+        response.Results[i] = item
+    }
+    logger.AddAttrs(r.Context(), slog.Int("batch_succeeded", response.Succeeded), slog.Int("batch_failed", response.Failed))
+
+    h.writeJSON(w, r, http.StatusOK, response)
+}
+
+// batchStatus - статус успешной операции, как у соответствующего одиночного запроса
+func batchStatus(op string) int {
+    if op == model.BatchOpDelete {
+        return http.StatusNoContent
+    }
+    return http.StatusOK
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "net/http"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+    "go-crud-example/pkg/jsonpatch"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/problem"
+)
+
+// Коды ошибок, специфичные для пользователей
+const (
+    CodeUserNotFound         = "user_not_found"
+    CodeUserConflict         = "user_conflict"
+    CodeEmailTaken           = "email_taken"
+    CodeUserNotDeleted       = "user_not_deleted"
+    CodeInvalidQuery         = "invalid_query"
+    CodeInvalidPatch         = "invalid_patch"
+    CodePatchTestFailed      = "patch_test_failed"
+    CodeUnsupportedMediaType = "unsupported_media_type"
+    CodeNotAcceptable        = "not_acceptable"
+    CodeInvalidBatch         = "invalid_batch"
+    CodeBatchAborted         = "batch_aborted"
+    CodeInvalidImport        = "invalid_import"
+    CodeForbidden            = "forbidden"
+    CodeAPIKeyNotFound       = "api_key_not_found"
+    CodeInvalidAPIKey        = "invalid_api_key"
+    CodeRequestTooLarge      = "request_too_large"
+)
+
+// writeError преобразует ошибку сервиса в problem+json ответ. Текст
+// непредвиденных ошибок в ответ не попадает, только в лог.
+func (h *UserHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+    if errors.Is(err, context.Canceled) && !isTimeout(r.Context(), err) {
+        // Клиент отключился, отвечать некому
+        logger.FromContext(r.Context(), h.logger).Info("request canceled by client")
+        return
+    }
+    problem.Write(w, r, h.problemFor(r, err))
+}
+
+// writeBodyError отвечает на ошибку чтения тела запроса, ограниченного http.MaxBytesReader
+func writeBodyError(w http.ResponseWriter, r *http.Request, err error) {
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+        problem.Error(w, r, http.StatusRequestEntityTooLarge, CodeRequestTooLarge,
+            fmt.Sprintf("Request body must not exceed %d bytes", tooLarge.Limit))
+        return
+    }
+    problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request body")
+}
+
+// problemFor описывает ошибку сервиса для клиента
+func (h *UserHandler) problemFor(r *http.Request, err error) *problem.Problem {
+    var validationErr *model.ValidationError
+
+    switch {
+    case isTimeout(r.Context(), err):
+        return problem.New(http.StatusGatewayTimeout, problem.CodeRequestTimeout, "Request timed out")
+    case errors.As(err, &validationErr):
+        p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "Request body failed validation")
+        p.Errors = fieldErrors(validationErr)
+        return p
+    case errors.Is(err, service.ErrInvalidUser):
+        return problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "Invalid user")
+    case errors.Is(err, jsonpatch.ErrInvalidPatch):
+        return problem.New(http.StatusBadRequest, CodeInvalidPatch, err.Error())
+    case errors.Is(err, jsonpatch.ErrTestFailed):
+        return problem.New(http.StatusConflict, CodePatchTestFailed, err.Error())
+    case errors.Is(err, model.ErrInvalidQuery), errors.Is(err, repository.ErrInvalidCursor):
+        return problem.New(http.StatusBadRequest, CodeInvalidQuery, err.Error())
+    case errors.Is(err, service.ErrInvalidBatch):
+        return problem.New(http.StatusBadRequest, CodeInvalidBatch, err.Error())
+    case errors.Is(err, service.ErrInvalidImport):
+        return problem.New(http.StatusBadRequest, CodeInvalidImport, err.Error())
+    case errors.Is(err, service.ErrBatchAborted):
+        return problem.New(http.StatusFailedDependency, CodeBatchAborted, "Batch was aborted because another operation failed")
+    case errors.Is(err, service.ErrForbidden):
+        return problem.New(http.StatusForbidden, CodeForbidden, err.Error())
+    case errors.Is(err, service.ErrInvalidAPIKeyRequest):
+        return problem.New(http.StatusBadRequest, CodeInvalidAPIKey, err.Error())
+    case errors.Is(err, service.ErrAPIKeyNotFound):
+        return problem.New(http.StatusNotFound, CodeAPIKeyNotFound, "API key not found")
+    case errors.Is(err, service.ErrUserNotFound):
+        return problem.New(http.StatusNotFound, CodeUserNotFound, "User not found")
+    case errors.Is(err, service.ErrEmailTaken):
+        return problem.New(http.StatusConflict, CodeEmailTaken, "Email is already taken by another user")
+    case errors.Is(err, service.ErrUserConflict):
+        return problem.New(http.StatusConflict, CodeUserConflict, "User conflicts with existing data")
+    case errors.Is(err, service.ErrUserNotDeleted):
+        return problem.New(http.StatusConflict, CodeUserNotDeleted, "User must be deleted before it can be purged")
+    case errors.Is(err, service.ErrVersionMismatch):
+        return problem.New(http.StatusPreconditionFailed, problem.CodePreconditionFailed, "User was modified, fetch the current version and retry")
+    default:
+        logger.FromContext(r.Context(), h.logger).Error("request failed", slog.Any("error", err))
+        return problem.New(http.StatusInternalServerError, problem.CodeInternal, "Internal server error")
+    }
+}
+
+// fieldErrors переводит нарушения валидации в описание по полям
+func fieldErrors(err *model.ValidationError) []problem.FieldError {
+    result := make([]problem.FieldError, 0, len(err.Violations))
+    for _, v := range err.Violations {
+        result = append(result, problem.FieldError{
+            Field:   v.Field,
+            Rule:    v.Rule,
+            Message: v.Message(),
+        })
+    }
+    return result
+}
+
+// Запрос не уложился в отведенное время. Драйвер БД не всегда возвращает
+// context.DeadlineExceeded, поэтому проверяем и сам контекст запроса.
+func isTimeout(ctx context.Context, err error) bool {
+    return errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
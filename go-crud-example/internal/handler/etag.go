@@ -0,0 +1,82 @@
+package handler
+
+import (
+    "net/http"
+    "strconv"
+    "strings"
+
+    "go-crud-example/internal/model"
+)
+
+// userETag - сильный ETag представления пользователя, построенный по его версии
+func userETag(user *model.User) string {
+    return `"` + strconv.FormatInt(user.Version, 10) + `"`
+}
+
+// setUserETag добавляет ETag текущей версии пользователя в ответ
+func setUserETag(w http.ResponseWriter, user *model.User) {
+    w.Header().Set("ETag", userETag(user))
+}
+
+// parseETags разбирает список entity-tag из If-Match / If-None-Match.
+// Возвращает wildcard=true для "*".
+func parseETags(header string) (tags []string, wildcard bool) {
+    for _, part := range strings.Split(header, ",") {
+        tag := strings.TrimSpace(part)
+        switch {
+        case tag == "":
+            continue
+        case tag == "*":
+            return nil, true
+        }
+        tags = append(tags, tag)
+    }
+    return tags, false
+}
+
+// noneMatch проверяет If-None-Match слабым сравнением (RFC 9110, 13.1.2):
+// true означает, что у клиента уже есть актуальное представление
+func noneMatch(r *http.Request, etag string) bool {
+    header := r.Header.Get("If-None-Match")
+    if header == "" {
+        return false
+    }
+
+    tags, wildcard := parseETags(header)
+    if wildcard {
+        return true
+    }
+    for _, tag := range tags {
+        if strings.TrimPrefix(tag, "W/") == etag {
+            return true
+        }
+    }
+    return false
+}
+
+// ifMatchVersions возвращает версии из If-Match для сильного сравнения.
+// ok=false, если заголовок отсутствует или равен "*", то есть версия не проверяется.
+// Слабые и чужие теги не совпадают ни с одной версией и в результат не попадают.
+func ifMatchVersions(r *http.Request) (versions []int64, ok bool) {
+    header := r.Header.Get("If-Match")
+    if header == "" {
+        return nil, false
+    }
+
+    tags, wildcard := parseETags(header)
+    if wildcard {
+        return nil, false
+    }
+    versions = []int64{}
+    for _, tag := range tags {
+        if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+            continue
+        }
+        version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+        if err != nil || version <= 0 {
+            continue
+        }
+        versions = append(versions, version)
+    }
+    return versions, true
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "log/slog"
+    "mime"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/problem"
+)
+
+// Форматы выгрузки пользователей
+const (
+    csvType    = "text/csv"
+    ndjsonType = "application/x-ndjson"
+)
+
+// exportFlushRows - через сколько строк выгрузка отправляется клиенту
+const exportFlushRows = 500
+
+// StreamingRoutes - маршруты, тело запроса или ответа которых передается
+// потоком и может идти дольше таймаута обычного запроса
+var StreamingRoutes = []string{"/users/export", "/users/import"}
+
+// exportColumns - заголовок CSV выгрузки
+var exportColumns = []string{"id", "name", "age", "email", "version", "deleted_at", "created_at", "updated_at"}
+
+// ExportUsers выгружает всех пользователей, подходящих под фильтры списка, в CSV
+// или NDJSON в зависимости от Accept. Строки читаются из курсора БД и сразу
+// отправляются клиенту, поэтому память не растет с размером таблицы.
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+    query, err := parseUserQuery(r.URL.Query())
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+    // Выгрузка не постраничная
+    query.Limit, query.Cursor = 0, ""
+
+    contentType, ok := negotiateExportType(r.Header.Get("Accept"))
+    if !ok {
+        problem.Error(w, r, http.StatusNotAcceptable, CodeNotAcceptable,
+            "Supported export formats are "+csvType+" and "+ndjsonType)
+        return
+    }
+
+    export := newUserExporter(w, contentType)
+    err = h.service.ExportUsers(r.Context(), query, export.write)
+    if err == nil {
+        err = export.finish()
+    }
+    logger.AddAttrs(r.Context(), slog.Int("exported_rows", export.rows))
+    if err == nil {
+        return
+    }
+
+    if !export.started {
+        h.writeError(w, r, err)
+        return
+    }
+    // Заголовки уже отправлены: обрываем соединение, чтобы клиент не принял
+    // неполную выгрузку за полную
+    logger.FromContext(r.Context(), h.logger).Error("export interrupted", slog.Any("error", err))
+    panic(http.ErrAbortHandler)
+}
+
+// userExporter пишет пользователей в ответ в выбранном формате
+type userExporter struct {
+    w          http.ResponseWriter
+    controller *http.ResponseController
+    buf        *bufio.Writer
+    csv        *csv.Writer
+    json       *json.Encoder
+
+    contentType string
+    started     bool
+    rows        int
+}
+
+func newUserExporter(w http.ResponseWriter, contentType string) *userExporter {
+    e := &userExporter{
+        w:           w,
+        controller:  http.NewResponseController(w),
+        buf:         bufio.NewWriter(w),
+        contentType: contentType,
+    }
+    if contentType == csvType {
+        e.csv = csv.NewWriter(e.buf)
+    } else {
+        e.json = json.NewEncoder(e.buf)
+    }
+    return e
+}
+
+// start отправляет заголовки ответа перед первой строкой
+func (e *userExporter) start() error {
+    if e.started {
+        return nil
+    }
+    e.started = true
+
+    if e.csv != nil {
+        e.w.Header().Set("Content-Type", e.contentType+"; charset=utf-8")
+        e.w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+    } else {
+        e.w.Header().Set("Content-Type", e.contentType)
+    }
+    e.w.Header().Set("X-Content-Type-Options", "nosniff")
+    e.w.WriteHeader(http.StatusOK)
+
+    if e.csv != nil {
+        return e.csv.Write(exportColumns)
+    }
+    return nil
+}
+
+func (e *userExporter) write(u model.User) error {
+    if err := e.start(); err != nil {
+        return err
+    }
+
+    if e.csv != nil {
+        deletedAt := ""
+        if u.DeletedAt != nil {
+            deletedAt = u.DeletedAt.Format(time.RFC3339Nano)
+        }
+        record := []string{
+            u.ID,
+            u.Name,
+            strconv.Itoa(u.Age),
+            u.Email,
+            strconv.FormatInt(u.Version, 10),
+            deletedAt,
+            u.CreatedAt.Format(time.RFC3339Nano),
+            u.UpdatedAt.Format(time.RFC3339Nano),
+        }
+        if err := e.csv.Write(record); err != nil {
+            return err
+        }
+    } else if err := e.json.Encode(u); err != nil {
+        return err
+    }
+
+    e.rows++
+    if e.rows%exportFlushRows == 0 {
+        return e.flush()
+    }
+    return nil
+}
+
+// finish отправляет остаток буфера. Пустая выгрузка тоже получает заголовки.
+func (e *userExporter) finish() error {
+    if err := e.start(); err != nil {
+        return err
+    }
+    return e.flush()
+}
+
+func (e *userExporter) flush() error {
+    if e.csv != nil {
+        e.csv.Flush()
+        if err := e.csv.Error(); err != nil {
+            return err
+        }
+    }
+    if err := e.buf.Flush(); err != nil {
+        return err
+    }
+    // Не все ResponseWriter поддерживают Flush, тогда данные уйдут по мере заполнения буфера
+    if err := e.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+        return err
+    }
+    return nil
+}
+
+// negotiateExportType выбирает формат выгрузки по заголовку Accept с учетом
+// q-значений. Без Accept выгрузка идет в NDJSON.
+func negotiateExportType(accept string) (string, bool) {
+    if strings.TrimSpace(accept) == "" {
+        return ndjsonType, true
+    }
+
+    type mediaRange struct {
+        mediaType string
+        q         float64
+    }
+    var ranges []mediaRange
+    for _, part := range strings.Split(accept, ",") {
+        mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+        if err != nil {
+            continue
+        }
+        q := 1.0
+        if raw, ok := params["q"]; ok {
+            if q, err = strconv.ParseFloat(raw, 64); err != nil {
+                continue
+            }
+        }
+        if q > 0 {
+            ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+        }
+    }
+    sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+    for _, mr := range ranges {
+        switch mr.mediaType {
+        case csvType, "text/*":
+            return csvType, true
+        case ndjsonType, "application/ndjson", "application/*", "*/*":
+            return ndjsonType, true
+        }
+    }
+    return "", false
+}
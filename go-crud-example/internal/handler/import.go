@@ -0,0 +1,126 @@
+package handler
+
+import (
+    "encoding/csv"
+    "fmt"
+    "io"
+    "log/slog"
+    "mime"
+    "net/http"
+    "path/filepath"
+    "strconv"
+    "strings"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/problem"
+)
+
+// importFormats сопоставляет media type и расширения файлов с форматом импорта
+var importFormats = map[string]string{
+    csvType:              model.ImportFormatCSV,
+    ndjsonType:           model.ImportFormatNDJSON,
+    "application/ndjson": model.ImportFormatNDJSON,
+    ".csv":               model.ImportFormatCSV,
+    ".ndjson":            model.ImportFormatNDJSON,
+    ".jsonl":             model.ImportFormatNDJSON,
+}
+
+// ImportUsers создает пользователей из CSV или NDJSON файла. Файл передается
+// телом запроса или полем file формы multipart/form-data и читается потоком.
+// Формат определяется по Content-Type, расширению файла или параметру format.
+// Ответ - отчет с ошибками по строкам, в CSV при Accept: text/csv.
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+    values := r.URL.Query()
+    opts := model.ImportOptions{Format: values.Get("format")}
+    if raw := values.Get("dry_run"); raw != "" {
+        dryRun, err := strconv.ParseBool(raw)
+        if err != nil {
+            h.writeError(w, r, fmt.Errorf("%w: dry_run must be a boolean", model.ErrInvalidQuery))
+            return
+        }
+        opts.DryRun = dryRun
+    }
+
+    body, format, err := importSource(r)
+    if err != nil {
+        problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+        return
+    }
+    if opts.Format == "" {
+        opts.Format = format
+    }
+    if opts.Format == "" {
+        w.Header().Set("Accept-Post", csvType+", "+ndjsonType)
+        problem.Error(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+            "Upload "+csvType+" or "+ndjsonType+", or set the format parameter")
+        return
+    }
+
+    report, err := h.service.ImportUsers(r.Context(), body, opts)
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+    logger.AddAttrs(r.Context(),
+        slog.Int("import_total", report.Total),
+        slog.Int("import_imported", report.Imported),
+        slog.Int("import_failed", report.Failed),
+    )
+
+    if reportType, _ := negotiateExportType(r.Header.Get("Accept")); reportType == csvType {
+        writeImportReportCSV(w, report)
+        return
+    }
+    h.writeJSON(w, r, http.StatusOK, report)
+}
+
+// importSource возвращает тело файла импорта и формат, определенный по его типу
+func importSource(r *http.Request) (io.Reader, string, error) {
+    mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+    if mediaType != "multipart/form-data" {
+        return r.Body, importFormats[mediaType], nil
+    }
+
+    reader, err := r.MultipartReader()
+    if err != nil {
+        return nil, "", err
+    }
+    for {
+        part, err := reader.NextPart()
+        if err == io.EOF {
+            return nil, "", fmt.Errorf("form field file is missing")
+        }
+        if err != nil {
+            return nil, "", err
+        }
+        if part.FormName() != "file" {
+            continue
+        }
+
+        partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+        format, ok := importFormats[partType]
+        if !ok {
+            format = importFormats[strings.ToLower(filepath.Ext(part.FileName()))]
+        }
+        return part, format, nil
+    }
+}
+
+// writeImportReportCSV отдает ошибки импорта CSV файлом: строка, поле, сообщение
+func writeImportReportCSV(w http.ResponseWriter, report *model.ImportReport) {
+    w.Header().Set("Content-Type", csvType+"; charset=utf-8")
+    w.Header().Set("Content-Disposition", `attachment; filename="import-report.csv"`)
+    w.Header().Set("X-Import-Total", strconv.Itoa(report.Total))
+    w.Header().Set("X-Import-Imported", strconv.Itoa(report.Imported))
+    w.Header().Set("X-Import-Failed", strconv.Itoa(report.Failed))
+    w.Header().Set("X-Import-Errors-Truncated", strconv.FormatBool(report.ErrorsTruncated))
+    w.WriteHeader(http.StatusOK)
+
+    writer := csv.NewWriter(w)
+    _ = writer.Write([]string{"line", "field", "message"})
+    for _, e := range report.Errors {
+        _ = writer.Write([]string{strconv.Itoa(e.Line), e.Field, e.Message})
+    }
+    writer.Flush()
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+    "fmt"
+    "net/http"
+    "net/url"
+
+    "go-crud-example/internal/model"
+)
+
+// SearchUsers ищет пользователей по имени и email. Результаты упорядочены по
+// релевантности, совпадения в полях размечены в highlights.
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+    query, err := parseSearchQuery(r.URL.Query())
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    results, err := h.service.SearchUsers(r.Context(), query)
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    h.writeJSON(w, r, http.StatusOK, results)
+}
+
+// parseSearchQuery читает параметры q и limit
+func parseSearchQuery(values url.Values) (model.SearchQuery, error) {
+    query := model.SearchQuery{Q: values.Get("q")}
+
+    limit, err := parseIntParam(values, "limit")
+    if err != nil {
+        return query, err
+    }
+    if limit != nil {
+        if *limit <= 0 {
+            return query, fmt.Errorf("%w: limit must be positive", model.ErrInvalidQuery)
+        }
+        query.Limit = *limit
+    }
+
+    return query, nil
+}
@@ -2,22 +2,33 @@ package handler
 
 import (
     "encoding/json"
+    "fmt"
     "github.com/gorilla/mux"
     "go-crud-example/internal/model"
     "go-crud-example/internal/service"
-    "go-crud-example/internal/repository"
     "github.com/prometheus/client_golang/prometheus/promhttp"
-    "log"
+    "go-crud-example/pkg/jsonpatch"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/problem"
+    "io"
+    "log/slog"
+    "mime"
     "net/http"
+    "net/url"
+    "strconv"
     "time"
 )
 
+// maxPatchBodySize ограничивает тело PATCH, которое читается в память целиком
+const maxPatchBodySize = 1 << 20
+
 type UserHandler struct {
     service service.UserService
-    logger  *log.Logger
+    apiKeys service.APIKeyService
+    logger  *slog.Logger
 }
 
-func NewUserHandler(service service.UserService, logger *log.Logger) *UserHandler {
+func NewUserHandler(service service.UserService, logger *slog.Logger) *UserHandler {
     return &UserHandler{
         service: service,
         logger:  logger,
@@ -25,119 +36,277 @@ func NewUserHandler(service service.UserService, logger *log.Logger) *UserHandle
 }
 
 func (h *UserHandler) RegisterRoutes(router *mux.Router) {
+    router.HandleFunc("/users/{id}:restore", h.RestoreUser).Methods("POST")
+    router.HandleFunc("/users/{id}:purge", h.PurgeUser).Methods("POST")
+    router.HandleFunc("/users:batch", h.BatchUsers).Methods("POST")
     router.HandleFunc("/users", h.CreateUser).Methods("POST")
     router.HandleFunc("/users", h.GetUsers).Methods("GET")
+    router.HandleFunc("/users/search", h.SearchUsers).Methods("GET")
+    router.HandleFunc("/users/export", h.ExportUsers).Methods("GET")
+    router.HandleFunc("/users/import", h.ImportUsers).Methods("POST")
     router.HandleFunc("/users/{id}", h.GetUser).Methods("GET")
     router.HandleFunc("/users/{id}", h.UpdateUser).Methods("PUT")
+    router.HandleFunc("/users/{id}", h.PatchUser).Methods("PATCH")
     router.HandleFunc("/users/{id}", h.DeleteUser).Methods("DELETE")
+    router.HandleFunc("/users/{id}/history", h.GetUserHistory).Methods("GET")
+    router.HandleFunc("/audit", h.GetAuditEvents).Methods("GET")
+
+    if h.apiKeys != nil {
+        router.HandleFunc("/api-keys", h.CreateAPIKey).Methods("POST")
+        router.HandleFunc("/api-keys", h.ListAPIKeys).Methods("GET")
+        router.HandleFunc("/api-keys/{id}", h.RevokeAPIKey).Methods("DELETE")
+    }
 
     // Metrics endpoint
     router.Handle("/metrics", promhttp.Handler())
 
     // Health check endpoint
     router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-        w.Header().Set("Content-Type", "application/json")
-        if err := json.NewEncoder(w).Encode(map[string]string{
+        h.writeJSON(w, r, http.StatusOK, map[string]string{
             "status":    "OK",
             "timestamp": time.Now().Format(time.RFC3339),
-        }); err != nil {
-            http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-            return
-        }
+        })
     }).Methods("GET")
 }
 
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
     var user model.User
     if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request body")
         return
     }
 
-    if err := h.service.CreateUser(&user); err != nil {
-        if err == service.ErrInvalidUser {
-            http.Error(w, err.Error(), http.StatusBadRequest)
-            return
-        }
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+    if err := h.service.CreateUser(r.Context(), &user); err != nil {
+        h.writeError(w, r, err)
         return
     }
+    logger.AddAttrs(r.Context(), slog.String("user_id", user.ID))
 
-    w.Header().Set("Content-Type", "application/json")
-    if err := json.NewEncoder(w).Encode(user); err != nil {
-        http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-        return
-    }
+    setUserETag(w, &user)
+    h.writeJSON(w, r, http.StatusOK, user)
 }
 
 func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-    users, err := h.service.GetUsers()
+    query, err := parseUserQuery(r.URL.Query())
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        h.writeError(w, r, err)
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    if err := json.NewEncoder(w).Encode(users); err != nil {
-        http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+    page, err := h.service.GetUsers(r.Context(), query)
+    if err != nil {
+        h.writeError(w, r, err)
         return
     }
+
+    h.writeJSON(w, r, http.StatusOK, page)
 }
 
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
     id := mux.Vars(r)["id"]
-    user, err := h.service.GetUser(id)
+    logger.AddAttrs(r.Context(), slog.String("user_id", id))
+    user, err := h.service.GetUser(r.Context(), id)
     if err != nil {
-        if err == repository.ErrUserNotFound {
-            http.Error(w, err.Error(), http.StatusNotFound)
-            return
-        }
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        h.writeError(w, r, err)
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    if err := json.NewEncoder(w).Encode(user); err != nil {
-        http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+    setUserETag(w, user)
+    if noneMatch(r, userETag(user)) {
+        w.WriteHeader(http.StatusNotModified)
         return
     }
+    h.writeJSON(w, r, http.StatusOK, user)
 }
 
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
     id := mux.Vars(r)["id"]
+    logger.AddAttrs(r.Context(), slog.String("user_id", id))
     var user model.User
     if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request body")
         return
     }
 
     user.ID = id
-    if err := h.service.UpdateUser(&user); err != nil {
-        if err == repository.ErrUserNotFound {
-            http.Error(w, err.Error(), http.StatusNotFound)
+    // Версия из тела игнорируется, ожидаемую версию задает только If-Match
+    user.Version = 0
+    if versions, ok := ifMatchVersions(r); ok {
+        version, err := h.expectedVersion(r, id, versions)
+        if err != nil {
+            h.writeError(w, r, err)
             return
         }
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
+        user.Version = version
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    if err := json.NewEncoder(w).Encode(user); err != nil {
-        http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+    if err := h.service.UpdateUser(r.Context(), &user); err != nil {
+        h.writeError(w, r, err)
         return
     }
+
+    setUserETag(w, &user)
+    h.writeJSON(w, r, http.StatusOK, user)
 }
 
-func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+// PatchUser применяет JSON Merge Patch или JSON Patch в зависимости от Content-Type
+func (h *UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
     id := mux.Vars(r)["id"]
-    if err := h.service.DeleteUser(id); err != nil {
-        if err == repository.ErrUserNotFound {
-            http.Error(w, err.Error(), http.StatusNotFound)
+    logger.AddAttrs(r.Context(), slog.String("user_id", id))
+
+    mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+    var apply func(doc, patch []byte) ([]byte, error)
+    switch mediaType {
+    case jsonpatch.MergePatchType:
+        apply = jsonpatch.MergePatch
+    case jsonpatch.JSONPatchType:
+        apply = jsonpatch.Apply
+    default:
+        w.Header().Set("Accept-Patch", jsonpatch.MergePatchType+", "+jsonpatch.JSONPatchType)
+        problem.Error(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+            fmt.Sprintf("Content-Type must be %s or %s", jsonpatch.MergePatchType, jsonpatch.JSONPatchType))
+        return
+    }
+
+    body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBodySize))
+    if err != nil {
+        writeBodyError(w, r, err)
+        return
+    }
+
+    var version int64
+    if versions, ok := ifMatchVersions(r); ok {
+        if version, err = h.expectedVersion(r, id, versions); err != nil {
+            h.writeError(w, r, err)
             return
         }
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+
+    user, err := h.service.PatchUser(r.Context(), id, version, func(doc []byte) ([]byte, error) {
+        return apply(doc, body)
+    })
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    setUserETag(w, user)
+    h.writeJSON(w, r, http.StatusOK, user)
+}
+
+// expectedVersion выбирает версию для условного обновления из If-Match.
+// Если тегов несколько, подходит тот, что совпадает с текущей версией.
+func (h *UserHandler) expectedVersion(r *http.Request, id string, versions []int64) (int64, error) {
+    switch len(versions) {
+    case 0:
+        return 0, service.ErrVersionMismatch
+    case 1:
+        return versions[0], nil
+    }
+
+    current, err := h.service.GetUser(r.Context(), id)
+    if err != nil {
+        return 0, err
+    }
+    for _, version := range versions {
+        if version == current.Version {
+            return version, nil
+        }
+    }
+    return 0, service.ErrVersionMismatch
+}
+
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+    logger.AddAttrs(r.Context(), slog.String("user_id", id))
+    if err := h.service.DeleteUser(r.Context(), id); err != nil {
+        h.writeError(w, r, err)
         return
     }
 
     w.WriteHeader(http.StatusNoContent)
 }
+
+// RestoreUser восстанавливает мягко удаленного пользователя
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+    logger.AddAttrs(r.Context(), slog.String("user_id", id))
+    user, err := h.service.RestoreUser(r.Context(), id)
+    if err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    setUserETag(w, user)
+    h.writeJSON(w, r, http.StatusOK, user)
+}
+
+// PurgeUser окончательно удаляет пользователя, ранее удаленного через DELETE
+func (h *UserHandler) PurgeUser(w http.ResponseWriter, r *http.Request) {
+    id := mux.Vars(r)["id"]
+    logger.AddAttrs(r.Context(), slog.String("user_id", id))
+    if err := h.service.PurgeUser(r.Context(), id); err != nil {
+        h.writeError(w, r, err)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// parseUserQuery читает параметры пагинации, сортировки и фильтрации списка
+func parseUserQuery(values url.Values) (model.UserQuery, error) {
+    query := model.UserQuery{
+        Cursor:     values.Get("cursor"),
+        Sort:       values.Get("sort"),
+        NamePrefix: values.Get("name_prefix"),
+    }
+
+    limit, err := parseIntParam(values, "limit")
+    if err != nil {
+        return query, err
+    }
+    if limit != nil {
+        if *limit <= 0 {
+            return query, fmt.Errorf("%w: limit must be positive", model.ErrInvalidQuery)
+        }
+        query.Limit = *limit
+    }
+
+    if query.MinAge, err = parseIntParam(values, "min_age"); err != nil {
+        return query, err
+    }
+    if query.MaxAge, err = parseIntParam(values, "max_age"); err != nil {
+        return query, err
+    }
+
+    if raw := values.Get("include_deleted"); raw != "" {
+        if query.IncludeDeleted, err = strconv.ParseBool(raw); err != nil {
+            return query, fmt.Errorf("%w: include_deleted must be a boolean", model.ErrInvalidQuery)
+        }
+    }
+
+    return query, nil
+}
+
+func parseIntParam(values url.Values, key string) (*int, error) {
+    raw := values.Get(key)
+    if raw == "" {
+        return nil, nil
+    }
+
+    value, err := strconv.Atoi(raw)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %s must be an integer", model.ErrInvalidQuery, key)
+    }
+    return &value, nil
+}
+
+// writeJSON отправляет JSON ответ. Если кодирование не удалось, заголовки
+// уже отправлены, поэтому ошибку остается только залогировать.
+func (h *UserHandler) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    if err := json.NewEncoder(w).Encode(v); err != nil {
+        logger.FromContext(r.Context(), h.logger).Error("failed to encode response", slog.Any("error", err))
+    }
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+    "context"
+    "database/sql"
+)
+
+// Dialect описывает различия СУБД, важные для мигратора: каталог с
+// миграциями, DDL служебной таблицы и межпроцессную блокировку
+type Dialect struct {
+    name                 string
+    createMigrationTable string
+    lock                 func(ctx context.Context, conn *sql.Conn) error
+    unlock               func(ctx context.Context, conn *sql.Conn) error
+}
+
+// Ключ advisory lock, под которым выполняются миграции. Блокировка не дает
+// нескольким репликам одновременно менять схему при старте.
+const advisoryLockKey int64 = 7_201_150_911
+
+var Postgres = Dialect{
+    name: "postgres",
+    createMigrationTable: `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version BIGINT PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )
+    `,
+    lock: func(ctx context.Context, conn *sql.Conn) error {
+        _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey)
+        return err
+    },
+    unlock: func(ctx context.Context, conn *sql.Conn) error {
+        _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+        return err
+    },
+}
+
+// SQLite используется в однопроцессных развертываниях, а запись в базу и так
+// сериализуется самой SQLite, поэтому отдельная блокировка не нужна
+var SQLite = Dialect{
+    name: "sqlite",
+    createMigrationTable: `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            applied_at TIMESTAMP NOT NULL
+        )
+    `,
+    lock:   func(context.Context, *sql.Conn) error { return nil },
+    unlock: func(context.Context, *sql.Conn) error { return nil },
+}
+
+func (d Dialect) String() string {
+    return d.name
+}
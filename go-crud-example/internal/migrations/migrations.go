@@ -0,0 +1,84 @@
+package migrations
+
+import (
+    "embed"
+    "fmt"
+    "io/fs"
+    "path"
+    "regexp"
+    "sort"
+    "strconv"
+)
+
+// Миграции каждой СУБД лежат в своем каталоге и имеют одинаковые версии
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Имя файла миграции: <версия>_<название>.<up|down>.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+    Version int64
+    Name    string
+    Up      string
+    Down    string
+}
+
+// All возвращает встроенные миграции диалекта, отсортированные по версии
+func All(dialect Dialect) ([]Migration, error) {
+    return load(files, dialect.name)
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+    entries, err := fs.ReadDir(fsys, dir)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read migrations: %w", err)
+    }
+
+    byVersion := make(map[int64]*Migration)
+    for _, entry := range entries {
+        match := fileNamePattern.FindStringSubmatch(entry.Name())
+        if match == nil {
+            return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+        }
+
+        version, err := strconv.ParseInt(match[1], 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("invalid migration version in %q: %w", entry.Name(), err)
+        }
+
+        content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+        if err != nil {
+            return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+        }
+
+        m, exists := byVersion[version]
+        if !exists {
+            m = &Migration{Version: version, Name: match[2]}
+            byVersion[version] = m
+        }
+        if m.Name != match[2] {
+            return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+        }
+
+        if match[3] == "up" {
+            m.Up = string(content)
+        } else {
+            m.Down = string(content)
+        }
+    }
+
+    migrations := make([]Migration, 0, len(byVersion))
+    for _, m := range byVersion {
+        if m.Up == "" || m.Down == "" {
+            return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+        }
+        migrations = append(migrations, *m)
+    }
+    sort.Slice(migrations, func(i, j int) bool {
+        return migrations[i].Version < migrations[j].Version
+    })
+
+    return migrations, nil
+}
@@ -0,0 +1,205 @@
+package migrations
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+)
+
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+type Migrator struct {
+    db         *sql.DB
+    dialect    Dialect
+    migrations []Migration
+}
+
+// Status - состояние одной миграции
+type Status struct {
+    Version   int64
+    Name      string
+    Applied   bool
+    AppliedAt time.Time
+}
+
+func NewMigrator(db *sql.DB, dialect Dialect) (*Migrator, error) {
+    migrations, err := All(dialect)
+    if err != nil {
+        return nil, err
+    }
+    return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Up применяет все непримененные миграции
+func (m *Migrator) Up(ctx context.Context) error {
+    if len(m.migrations) == 0 {
+        return nil
+    }
+    return m.Goto(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down откатывает последнюю примененную миграцию
+func (m *Migrator) Down(ctx context.Context) error {
+    return m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := m.applied(ctx, conn)
+        if err != nil {
+            return err
+        }
+
+        for i := len(m.migrations) - 1; i >= 0; i-- {
+            if _, ok := applied[m.migrations[i].Version]; ok {
+                return m.rollback(ctx, conn, m.migrations[i])
+            }
+        }
+        return nil
+    })
+}
+
+// Goto приводит схему к указанной версии: применяет недостающие миграции
+// до нее включительно и откатывает все более поздние. Версия 0 откатывает все.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+    if version != 0 && m.find(version) < 0 {
+        return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+    }
+
+    return m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := m.applied(ctx, conn)
+        if err != nil {
+            return err
+        }
+
+        for i := len(m.migrations) - 1; i >= 0; i-- {
+            migration := m.migrations[i]
+            if _, ok := applied[migration.Version]; ok && migration.Version > version {
+                if err := m.rollback(ctx, conn, migration); err != nil {
+                    return err
+                }
+            }
+        }
+
+        for _, migration := range m.migrations {
+            if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+                if err := m.apply(ctx, conn, migration); err != nil {
+                    return err
+                }
+            }
+        }
+        return nil
+    })
+}
+
+// Status возвращает список всех известных миграций и отметку о применении
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+    var statuses []Status
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := m.applied(ctx, conn)
+        if err != nil {
+            return err
+        }
+
+        for _, migration := range m.migrations {
+            appliedAt, ok := applied[migration.Version]
+            statuses = append(statuses, Status{
+                Version:   migration.Version,
+                Name:      migration.Name,
+                Applied:   ok,
+                AppliedAt: appliedAt,
+            })
+        }
+        return nil
+    })
+    return statuses, err
+}
+
+// withLock выполняет fn на выделенном соединении под блокировкой диалекта.
+// Сессионная блокировка привязана к соединению, поэтому все запросы
+// миграций идут через него же.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+    conn, err := m.db.Conn(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to acquire connection: %w", err)
+    }
+    defer conn.Close()
+
+    if err := m.dialect.lock(ctx, conn); err != nil {
+        return fmt.Errorf("failed to acquire migration lock: %w", err)
+    }
+    defer func() {
+        // Разблокируем даже при отмененном контексте запроса
+        _ = m.dialect.unlock(context.Background(), conn)
+    }()
+
+    if _, err := conn.ExecContext(ctx, m.dialect.createMigrationTable); err != nil {
+        return fmt.Errorf("failed to create schema_migrations table: %w", err)
+    }
+
+    return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+    rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+    if err != nil {
+        return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+    }
+    defer rows.Close()
+
+    applied := make(map[int64]time.Time)
+    for rows.Next() {
+        var version int64
+        var appliedAt time.Time
+        if err := rows.Scan(&version, &appliedAt); err != nil {
+            return nil, err
+        }
+        applied[version] = appliedAt
+    }
+    return applied, rows.Err()
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+    return inTx(ctx, conn, func(tx *sql.Tx) error {
+        if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+            return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+        }
+        _, err := tx.ExecContext(ctx,
+            "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+            migration.Version,
+            migration.Name,
+            time.Now().UTC(),
+        )
+        return err
+    })
+}
+
+func (m *Migrator) rollback(ctx context.Context, conn *sql.Conn, migration Migration) error {
+    return inTx(ctx, conn, func(tx *sql.Tx) error {
+        if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+            return fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+        }
+        _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+        return err
+    })
+}
+
+func (m *Migrator) find(version int64) int {
+    for i, migration := range m.migrations {
+        if migration.Version == version {
+            return i
+        }
+    }
+    return -1
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+
+    if err := fn(tx); err != nil {
+        _ = tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_audit_events_user_id ON audit_events (user_id, id);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_email;
+ALTER TABLE users DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN created_at;
+ALTER TABLE users DROP COLUMN email;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN email VARCHAR(254);
+ALTER TABLE users ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE UNIQUE INDEX idx_users_email ON users (LOWER(email));
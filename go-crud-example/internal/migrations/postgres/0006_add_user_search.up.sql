@@ -0,0 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+ALTER TABLE users ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || COALESCE(email, ''))) STORED;
+CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector);
+CREATE INDEX idx_users_name_trgm ON users USING GIN (name gin_trgm_ops);
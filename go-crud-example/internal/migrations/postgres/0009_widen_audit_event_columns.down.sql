@@ -0,0 +1,2 @@
+ALTER TABLE audit_events ALTER COLUMN actor TYPE VARCHAR(255) USING LEFT(actor, 255);
+ALTER TABLE audit_events ALTER COLUMN request_id TYPE VARCHAR(64) USING LEFT(request_id, 64);
@@ -0,0 +1,3 @@
+-- request_id принимает X-Request-ID до 128 символов, actor - sub токена любой длины
+ALTER TABLE audit_events ALTER COLUMN request_id TYPE VARCHAR(128);
+ALTER TABLE audit_events ALTER COLUMN actor TYPE TEXT;
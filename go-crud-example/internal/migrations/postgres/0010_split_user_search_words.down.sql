@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_search_vector;
+ALTER TABLE users DROP COLUMN search_vector;
+ALTER TABLE users ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || COALESCE(email, ''))) STORED;
+CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector);
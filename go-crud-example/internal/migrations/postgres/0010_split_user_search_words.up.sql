@@ -0,0 +1,9 @@
+-- Слова выделяются так же, как в поиске перебором: любые символы, кроме букв
+-- и цифр, - разделители, поэтому домен email ищется отдельным словом.
+-- Выражение генерируемой колонки не изменить, колонка создается заново.
+DROP INDEX IF EXISTS idx_users_search_vector;
+ALTER TABLE users DROP COLUMN search_vector;
+ALTER TABLE users ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple',
+        regexp_replace(name || ' ' || COALESCE(email, ''), '[^[:alnum:]]+', ' ', 'g'))) STORED;
+CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector);
@@ -0,0 +1 @@
+ALTER TABLE api_keys ALTER COLUMN created_by TYPE VARCHAR(255) USING LEFT(created_by, 255);
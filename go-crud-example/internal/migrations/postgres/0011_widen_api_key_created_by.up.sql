@@ -0,0 +1,2 @@
+-- created_by хранит sub токена, длина которого не ограничена
+ALTER TABLE api_keys ALTER COLUMN created_by TYPE TEXT;
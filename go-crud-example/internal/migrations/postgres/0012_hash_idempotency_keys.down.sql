@@ -0,0 +1,3 @@
+-- Хэш не обратим: сохраненные ключи перестают совпадать с запросами и
+-- удаляются очисткой по истечении срока
+ALTER TABLE idempotency_keys ALTER COLUMN key TYPE VARCHAR(512);
@@ -0,0 +1,5 @@
+-- Ключ включает subject клиента, длина которого не ограничена, поэтому
+-- хранится SHA-256 ключа. Сохраненные ключи пересчитываются, чтобы повторы
+-- запросов по-прежнему получали исходный ответ.
+UPDATE idempotency_keys SET key = encode(sha256(convert_to(key, 'UTF8')), 'hex');
+ALTER TABLE idempotency_keys ALTER COLUMN key TYPE CHAR(64);
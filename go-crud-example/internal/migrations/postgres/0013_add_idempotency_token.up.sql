@@ -0,0 +1,3 @@
+-- token отличает текущее занятие ключа от истекшего, чтобы запрос, переживший
+-- свою блокировку, не записал ответ в ключ, занятый другим запросом
+ALTER TABLE idempotency_keys ADD COLUMN token CHAR(32) NOT NULL DEFAULT '';
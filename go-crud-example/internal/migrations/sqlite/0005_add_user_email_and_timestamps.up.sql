@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN email VARCHAR(254);
+-- SQLite не допускает CURRENT_TIMESTAMP по умолчанию в ADD COLUMN
+ALTER TABLE users ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE users SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+CREATE UNIQUE INDEX idx_users_email ON users (LOWER(email));
@@ -0,0 +1,3 @@
+-- SQLite не ограничивает длину VARCHAR, миграция сохраняет одинаковую
+-- нумерацию версий с PostgreSQL
+SELECT 1;
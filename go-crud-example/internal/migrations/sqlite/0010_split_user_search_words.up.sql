@@ -0,0 +1,3 @@
+-- SQLite ищет пользователей без индексов перебором, миграция сохраняет
+-- одинаковую нумерацию версий с PostgreSQL
+SELECT 1;
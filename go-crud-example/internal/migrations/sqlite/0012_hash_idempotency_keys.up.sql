@@ -0,0 +1,5 @@
+-- SQLite не ограничивает длину ключа и не умеет считать SHA-256. Ключи,
+-- сохраненные до перехода на хэши, перестают совпадать с запросами и
+-- удаляются очисткой по истечении срока. Миграция сохраняет одинаковую
+-- нумерацию версий с PostgreSQL.
+SELECT 1;
@@ -0,0 +1,66 @@
+package model
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+const (
+    // APIKeyPrefix начинает каждый выданный ключ, чтобы его было легко узнать
+    // в конфигурации и найти при утечке
+    APIKeyPrefix = "gck_"
+    // APIKeyDisplayLength - длина начала ключа, которое хранится открыто в Prefix
+    APIKeyDisplayLength = len(APIKeyPrefix) + 8
+
+    MaxAPIKeyNameLength = 100
+)
+
+// APIKey - ключ доступа сервисного клиента. Сам ключ не хранится, только его
+// SHA-256. Prefix - начало ключа, по которому его можно узнать в списке.
+// Scopes - действия политики доступа, разрешенные ключу.
+type APIKey struct {
+    ID        string     `json:"id"`
+    Name      string     `json:"name"`
+    Prefix    string     `json:"prefix"`
+    Scopes    []string   `json:"scopes"`
+    CreatedBy string     `json:"created_by"`
+    CreatedAt time.Time  `json:"created_at"`
+    ExpiresAt *time.Time `json:"expires_at,omitempty"`
+    RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active сообщает, что ключ не отозван и не истек к моменту now
+func (k *APIKey) Active(now time.Time) bool {
+    return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyRequest - параметры нового ключа. Без ExpiresAt ключ бессрочный.
+type APIKeyRequest struct {
+    Name      string     `json:"name"`
+    Scopes    []string   `json:"scopes"`
+    ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate проверяет имя, наличие scopes и что срок действия еще не истек.
+// Допустимость самих scopes проверяет сервис по политике доступа.
+func (r *APIKeyRequest) Validate(now time.Time) error {
+    r.Name = strings.TrimSpace(r.Name)
+    if r.Name == "" || len(r.Name) > MaxAPIKeyNameLength {
+        return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidAPIKeyRequest, MaxAPIKeyNameLength)
+    }
+    if len(r.Scopes) == 0 {
+        return fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyRequest)
+    }
+    if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+        return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyRequest)
+    }
+    return nil
+}
+
+// CreatedAPIKey - только что созданный ключ. Key возвращается один раз, в
+// ответе на создание, и больше нигде не доступен.
+type CreatedAPIKey struct {
+    APIKey
+    Key string `json:"key"`
+}
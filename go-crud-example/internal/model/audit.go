@@ -0,0 +1,62 @@
+package model
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+)
+
+const (
+    DefaultAuditListLimit = 50
+    MaxAuditListLimit     = 500
+)
+
+// Действия над пользователем, фиксируемые в журнале аудита
+const (
+    AuditActionCreate  = "create"
+    AuditActionUpdate  = "update"
+    AuditActionDelete  = "delete"
+    AuditActionRestore = "restore"
+    AuditActionPurge   = "purge"
+)
+
+// AuditEvent - запись журнала аудита об одном изменении пользователя.
+// Before и After содержат только изменившиеся поля; у созданного пользователя
+// нет Before, у окончательно удаленного - After.
+type AuditEvent struct {
+    ID        string          `json:"id"`
+    UserID    string          `json:"user_id"`
+    Action    string          `json:"action"`
+    Actor     string          `json:"actor"`
+    RequestID string          `json:"request_id,omitempty"`
+    Before    json.RawMessage `json:"before,omitempty"`
+    After     json.RawMessage `json:"after,omitempty"`
+    CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditQuery описывает выборку событий аудита в порядке их записи
+type AuditQuery struct {
+    // UserID ограничивает выборку историей одного пользователя
+    UserID string
+    // Since - нижняя граница времени события включительно
+    Since  *time.Time
+    Cursor string
+    Limit  int
+}
+
+// AuditPage - одна страница журнала аудита
+type AuditPage struct {
+    Events     []AuditEvent `json:"events"`
+    NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// Normalize подставляет значения по умолчанию и проверяет параметры запроса
+func (q *AuditQuery) Normalize() error {
+    if q.Limit == 0 {
+        q.Limit = DefaultAuditListLimit
+    }
+    if q.Limit < 0 || q.Limit > MaxAuditListLimit {
+        return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxAuditListLimit)
+    }
+    return nil
+}
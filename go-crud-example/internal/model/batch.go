@@ -0,0 +1,92 @@
+package model
+
+import (
+    "errors"
+    "fmt"
+)
+
+// MaxBatchOperations - наибольшее число операций в одном пакете
+const MaxBatchOperations = 1000
+
+// Режимы выполнения пакета
+const (
+    // BatchModeAtomic - все операции применяются вместе или не применяется ни одна
+    BatchModeAtomic = "atomic"
+    // BatchModeBestEffort - неудачная операция не мешает остальным
+    BatchModeBestEffort = "best_effort"
+)
+
+// Операции пакета
+const (
+    BatchOpCreate = "create"
+    BatchOpUpdate = "update"
+    BatchOpDelete = "delete"
+)
+
+var (
+    ErrInvalidBatch = errors.New("invalid batch")
+    // ErrBatchAborted - операция не применена, потому что атомарный пакет отменен
+    // из-за ошибки в другой операции
+    ErrBatchAborted = errors.New("batch aborted")
+)
+
+// BatchOperation - одна операция пакета. create и update передают User, update
+// и delete - ID. Version - ожидаемая версия для update, 0 - без проверки.
+type BatchOperation struct {
+    Op      string `json:"op"`
+    ID      string `json:"id,omitempty"`
+    Version int64  `json:"version,omitempty"`
+    User    *User  `json:"user,omitempty"`
+}
+
+type BatchRequest struct {
+    Mode       string           `json:"mode"`
+    Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResult - итог одной операции пакета. User - созданный или обновленный
+// пользователь, Err - ошибка операции.
+type BatchResult struct {
+    User *User
+    Err  error
+}
+
+// Normalize подставляет режим по умолчанию и проверяет размер пакета
+func (r *BatchRequest) Normalize() error {
+    if r.Mode == "" {
+        r.Mode = BatchModeAtomic
+    }
+    if r.Mode != BatchModeAtomic && r.Mode != BatchModeBestEffort {
+        return fmt.Errorf("%w: mode must be %q or %q", ErrInvalidBatch, BatchModeAtomic, BatchModeBestEffort)
+    }
+    if len(r.Operations) == 0 || len(r.Operations) > MaxBatchOperations {
+        return fmt.Errorf("%w: batch must contain between 1 and %d operations", ErrInvalidBatch, MaxBatchOperations)
+    }
+    return nil
+}
+
+// Validate проверяет, что у операции заданы нужные поля и пользователь корректен
+func (op *BatchOperation) Validate() error {
+    switch op.Op {
+    case BatchOpCreate:
+        if op.ID != "" || op.Version != 0 {
+            return fmt.Errorf("%w: create does not accept id and version", ErrInvalidBatch)
+        }
+    case BatchOpUpdate:
+        if op.ID == "" {
+            return fmt.Errorf("%w: update requires id", ErrInvalidBatch)
+        }
+    case BatchOpDelete:
+        if op.ID == "" || op.User != nil {
+            return fmt.Errorf("%w: delete requires id and no user", ErrInvalidBatch)
+        }
+        return nil
+    default:
+        return fmt.Errorf("%w: unknown operation %q", ErrInvalidBatch, op.Op)
+    }
+
+    if op.User == nil {
+        return fmt.Errorf("%w: %s requires user", ErrInvalidBatch, op.Op)
+    }
+    return op.User.Validate()
+}
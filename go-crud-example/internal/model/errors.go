@@ -0,0 +1,64 @@
+package model
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+// Доменные ошибки, общие для репозиториев и сервисов. Слои оборачивают их
+// через %w, поэтому проверять следует errors.Is, а не сравнением.
+var (
+    ErrUserNotFound = errors.New("user not found")
+    ErrUserConflict = errors.New("user conflicts with existing data")
+    // ErrEmailTaken - email уже занят другим пользователем. Частный случай ErrUserConflict.
+    ErrEmailTaken  = fmt.Errorf("%w: email is already taken", ErrUserConflict)
+    ErrInvalidUser = errors.New("invalid user")
+
+    // ErrVersionMismatch - пользователь изменен после чтения ожидаемой версии
+    ErrVersionMismatch = errors.New("user version mismatch")
+    // ErrUserNotDeleted - операция допустима только для удаленного пользователя
+    ErrUserNotDeleted = errors.New("user is not deleted")
+    // ErrForbidden - у актора нет прав на операцию
+    ErrForbidden = errors.New("forbidden")
+
+    // ErrAPIKeyNotFound - ключа с таким id нет
+    ErrAPIKeyNotFound = errors.New("api key not found")
+    // ErrInvalidAPIKeyRequest - недопустимые параметры нового ключа
+    ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+    // ErrInvalidAPIKey - предъявленный ключ неизвестен, отозван или истек
+    ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// FieldViolation - нарушение правила валидации для одного поля
+type FieldViolation struct {
+    Field string
+    Rule  string
+    Param string
+}
+
+// Message описывает нарушение для клиента, например "must satisfy min=2"
+func (v FieldViolation) Message() string {
+    if v.Param != "" {
+        return fmt.Sprintf("must satisfy %s=%s", v.Rule, v.Param)
+    }
+    return fmt.Sprintf("must satisfy %s", v.Rule)
+}
+
+// ValidationError содержит все нарушения правил валидации пользователя.
+// errors.Is(err, ErrInvalidUser) для нее возвращает true.
+type ValidationError struct {
+    Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+    fields := make([]string, 0, len(e.Violations))
+    for _, v := range e.Violations {
+        fields = append(fields, fmt.Sprintf("%s (%s)", v.Field, v.Rule))
+    }
+    return fmt.Sprintf("%s: %s", ErrInvalidUser, strings.Join(fields, ", "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+    return target == ErrInvalidUser
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// Форматы файлов импорта
+const (
+    ImportFormatCSV    = "csv"
+    ImportFormatNDJSON = "ndjson"
+)
+
+const (
+    DefaultImportBatchSize = 500
+    MaxImportBatchSize     = 5000
+    // MaxImportErrors ограничивает число ошибок в отчете, чтобы файл из
+    // одних ошибочных строк не собирал отчет в памяти без ограничений
+    MaxImportErrors = 1000
+)
+
+// ErrInvalidImport - файл импорта нельзя обработать целиком, например в CSV нет
+// обязательных колонок
+var ErrInvalidImport = errors.New("invalid import")
+
+// ImportOptions - параметры импорта пользователей
+type ImportOptions struct {
+    Format string
+    // DryRun только проверяет строки, ничего не записывая
+    DryRun bool
+    // BatchSize - сколько пользователей создается одной вставкой
+    BatchSize int
+}
+
+// ImportError - ошибка в одной строке файла импорта
+type ImportError struct {
+    Line    int    `json:"line"`
+    Field   string `json:"field,omitempty"`
+    Message string `json:"message"`
+}
+
+// ImportReport - итог импорта. Imported при DryRun - число строк, которые были бы импортированы.
+// Errors содержит не больше MaxImportErrors ошибок с наименьшими номерами строк,
+// ErrorsTruncated сообщает, что остальные отброшены; Failed учитывает все ошибочные строки.
+type ImportReport struct {
+    DryRun          bool          `json:"dry_run"`
+    Total           int           `json:"total"`
+    Imported        int           `json:"imported"`
+    Failed          int           `json:"failed"`
+    Errors          []ImportError `json:"errors"`
+    ErrorsTruncated bool          `json:"errors_truncated"`
+}
+
+// Normalize подставляет значения по умолчанию и проверяет параметры импорта
+func (o *ImportOptions) Normalize() error {
+    if o.Format != ImportFormatCSV && o.Format != ImportFormatNDJSON {
+        return fmt.Errorf("%w: format must be %q or %q", ErrInvalidImport, ImportFormatCSV, ImportFormatNDJSON)
+    }
+    if o.BatchSize == 0 {
+        o.BatchSize = DefaultImportBatchSize
+    }
+    if o.BatchSize < 0 || o.BatchSize > MaxImportBatchSize {
+        return fmt.Errorf("%w: batch size must be between 1 and %d", ErrInvalidImport, MaxImportBatchSize)
+    }
+    return nil
+}
+
+// importRowFailed - сообщение в отчете об ошибке, текст которой не
+// предназначен клиенту
+const importRowFailed = "row could not be imported"
+
+// AddError записывает ошибки строки. Нарушения валидации попадают в отчет по полям.
+// Возвращает false, если текст ошибки в отчет не попал и ее стоит записать в лог.
+func (r *ImportReport) AddError(line int, err error) bool {
+    r.Failed++
+
+    var validationErr *ValidationError
+    if errors.As(err, &validationErr) {
+        for _, v := range validationErr.Violations {
+            r.appendError(ImportError{Line: line, Field: v.Field, Message: v.Message()})
+        }
+        return true
+    }
+
+    var fieldErr *ImportFieldError
+    switch {
+    case errors.As(err, &fieldErr):
+        r.appendError(ImportError{Line: line, Field: fieldErr.Field, Message: fieldErr.Message})
+    case errors.Is(err, ErrEmailTaken):
+        r.appendError(ImportError{Line: line, Field: "email", Message: "is already taken"})
+    // Текст ошибки хранилища в отчет не попадает: в нем имена ограничений,
+    // колонок и значения
+    case errors.Is(err, ErrUserConflict):
+        r.appendError(ImportError{Line: line, Message: ErrUserConflict.Error()})
+    case errors.Is(err, ErrInvalidUser):
+        r.appendError(ImportError{Line: line, Message: ErrInvalidUser.Error()})
+    default:
+        r.appendError(ImportError{Line: line, Message: importRowFailed})
+        return false
+    }
+    return true
+}
+
+// appendError добавляет ошибку. Ошибки вставки находятся позже ошибок разбора
+// следующих строк, поэтому лишние отбрасываются только после сортировки: в
+// памяти хранится не больше 2*MaxImportErrors ошибок.
+func (r *ImportReport) appendError(e ImportError) {
+    r.Errors = append(r.Errors, e)
+    if len(r.Errors) >= 2*MaxImportErrors {
+        r.SortErrors()
+    }
+}
+
+// SortErrors упорядочивает ошибки по номеру строки и оставляет первые
+// MaxImportErrors. Ошибки одной строки сохраняют порядок добавления.
+func (r *ImportReport) SortErrors() {
+    sort.SliceStable(r.Errors, func(i, j int) bool {
+        return r.Errors[i].Line < r.Errors[j].Line
+    })
+    if len(r.Errors) > MaxImportErrors {
+        r.Errors = r.Errors[:MaxImportErrors]
+        r.ErrorsTruncated = true
+    }
+}
+
+// ImportFieldError - значение поля в строке импорта не удалось разобрать.
+// Field пустое, если не удалось разобрать всю строку. Message попадает в отчет.
+type ImportFieldError struct {
+    Field   string
+    Message string
+}
+
+func (e *ImportFieldError) Error() string {
+    if e.Field == "" {
+        return e.Message
+    }
+    return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+    "fmt"
+    "strings"
+    "unicode/utf8"
+)
+
+const (
+    DefaultSearchLimit = 20
+    MaxSearchLimit     = 100
+    // MaxSearchQueryLength ограничивает длину строки поиска в символах
+    MaxSearchQueryLength = 200
+)
+
+// SearchQuery описывает поиск пользователей по имени и email
+type SearchQuery struct {
+    Q     string
+    Limit int
+}
+
+// SearchResult - найденный пользователь. Score - релевантность, сравнима только
+// внутри одного ответа. Highlights содержит совпавшие поля, где совпадения
+// обернуты в <mark>, остальной текст экранирован как HTML.
+type SearchResult struct {
+    User       User              `json:"user"`
+    Score      float64           `json:"score"`
+    Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchResults - результаты поиска в порядке убывания релевантности
+type SearchResults struct {
+    Results []SearchResult `json:"results"`
+}
+
+// Normalize подставляет значения по умолчанию и проверяет параметры поиска
+func (q *SearchQuery) Normalize() error {
+    q.Q = strings.TrimSpace(q.Q)
+    if q.Q == "" {
+        return fmt.Errorf("%w: q is required", ErrInvalidQuery)
+    }
+    if utf8.RuneCountInString(q.Q) > MaxSearchQueryLength {
+        return fmt.Errorf("%w: q must not exceed %d characters", ErrInvalidQuery, MaxSearchQueryLength)
+    }
+
+    if q.Limit == 0 {
+        q.Limit = DefaultSearchLimit
+    }
+    if q.Limit < 0 || q.Limit > MaxSearchLimit {
+        return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxSearchLimit)
+    }
+    return nil
+}
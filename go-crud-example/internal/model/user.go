@@ -1,7 +1,20 @@
 package model
 
+import "time"
+
 type User struct {
     ID   string `json:"id"`
     Name string `json:"name" validate:"required,min=2,max=100"`
     Age  int    `json:"age" validate:"required,gte=0,lte=150"`
+    // Email необязателен и уникален без учета регистра
+    Email string `json:"email,omitempty" validate:"omitempty,email,max=254"`
+    // Version увеличивается при каждом изменении. В Update ненулевое значение
+    // означает ожидаемую текущую версию (оптимистическая блокировка).
+    Version int64 `json:"version"`
+    // DeletedAt заполнен у мягко удаленных пользователей
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
+    // CreatedAt и UpdatedAt проставляет репозиторий, значения из запроса игнорируются.
+    // UpdatedAt меняется вместе с Version.
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
 }
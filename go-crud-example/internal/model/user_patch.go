@@ -0,0 +1,30 @@
+package model
+
+// UserPatch - частичное изменение пользователя. Nil-поля не изменяются.
+// Version - ожидаемая текущая версия, 0 - без проверки.
+type UserPatch struct {
+    Name    *string
+    Age     *int
+    Email   *string
+    Version int64
+}
+
+// IsEmpty сообщает, что патч не меняет ни одного поля
+func (p UserPatch) IsEmpty() bool {
+    return p.Name == nil && p.Age == nil && p.Email == nil
+}
+
+// DiffUsers возвращает патч, переводящий пользователя from в to
+func DiffUsers(from, to *User) UserPatch {
+    var patch UserPatch
+    if from.Name != to.Name {
+        patch.Name = &to.Name
+    }
+    if from.Age != to.Age {
+        patch.Age = &to.Age
+    }
+    if from.Email != to.Email {
+        patch.Email = &to.Email
+    }
+    return patch
+}
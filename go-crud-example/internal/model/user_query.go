@@ -0,0 +1,75 @@
+package model
+
+import (
+    "errors"
+    "fmt"
+)
+
+const (
+    DefaultUserListLimit = 20
+    MaxUserListLimit     = 100
+)
+
+// Допустимые значения параметра sort. Префикс "-" означает сортировку по убыванию.
+const (
+    SortByID       = "id"
+    SortByIDDesc   = "-id"
+    SortByName     = "name"
+    SortByNameDesc = "-name"
+    SortByAge      = "age"
+    SortByAgeDesc  = "-age"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// UserQuery описывает параметры выборки списка пользователей
+type UserQuery struct {
+    Limit      int
+    Cursor     string
+    Sort       string
+    NamePrefix string
+    MinAge     *int
+    MaxAge     *int
+    // IncludeDeleted добавляет в выборку мягко удаленных пользователей
+    IncludeDeleted bool
+}
+
+// UserPage - одна страница списка пользователей
+type UserPage struct {
+    Users      []User `json:"users"`
+    NextCursor string `json:"next_cursor,omitempty"`
+    Total      int    `json:"total"`
+}
+
+// Normalize подставляет значения по умолчанию и проверяет параметры запроса
+func (q *UserQuery) Normalize() error {
+    if q.Limit == 0 {
+        q.Limit = DefaultUserListLimit
+    }
+    if q.Limit < 0 || q.Limit > MaxUserListLimit {
+        return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxUserListLimit)
+    }
+
+    if q.Sort == "" {
+        q.Sort = SortByID
+    }
+    switch q.Sort {
+    case SortByID, SortByIDDesc, SortByName, SortByNameDesc, SortByAge, SortByAgeDesc:
+    default:
+        return fmt.Errorf("%w: unsupported sort %q", ErrInvalidQuery, q.Sort)
+    }
+
+    if q.MinAge != nil && q.MaxAge != nil && *q.MinAge > *q.MaxAge {
+        return fmt.Errorf("%w: min_age must not exceed max_age", ErrInvalidQuery)
+    }
+
+    return nil
+}
+
+// SortField возвращает поле сортировки и направление
+func (q *UserQuery) SortField() (field string, desc bool) {
+    if len(q.Sort) > 0 && q.Sort[0] == '-' {
+        return q.Sort[1:], true
+    }
+    return q.Sort, false
+}
@@ -1,11 +1,46 @@
 package model
 
 import (
+    "errors"
+    "reflect"
+    "strings"
+
     "github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New()
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+    v := validator.New()
+    // В ошибках валидации используем имена полей из JSON, а не из Go структуры
+    v.RegisterTagNameFunc(func(field reflect.StructField) string {
+        name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+        if name == "-" {
+            return ""
+        }
+        return name
+    })
+    return v
+}
 
 func (u *User) Validate() error {
-    return validate.Struct(u)
+    return toValidationError(validate.Struct(u))
+}
+
+// toValidationError переводит ошибки validator в ValidationError
+func toValidationError(err error) error {
+    var errs validator.ValidationErrors
+    if !errors.As(err, &errs) {
+        return err
+    }
+
+    violations := make([]FieldViolation, 0, len(errs))
+    for _, fe := range errs {
+        violations = append(violations, FieldViolation{
+            Field: fe.Field(),
+            Rule:  fe.Tag(),
+            Param: fe.Param(),
+        })
+    }
+    return &ValidationError{Violations: violations}
 }
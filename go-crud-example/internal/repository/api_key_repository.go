@@ -0,0 +1,143 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "strconv"
+    "strings"
+
+    "go-crud-example/internal/model"
+)
+
+// APIKeyRepository хранит API-ключи. Открытый ключ сюда не попадает, поиск
+// идет по его хэшу.
+type APIKeyRepository interface {
+    // Create сохраняет ключ с хэшем hash и проставляет ему ID и CreatedAt
+    Create(ctx context.Context, key *model.APIKey, hash string) error
+    // GetByHash возвращает ключ по хэшу, в том числе отозванный или истекший
+    GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+    // List возвращает все ключи в порядке создания
+    List(ctx context.Context) ([]model.APIKey, error)
+    // Revoke отзывает ключ и возвращает его. Повторный отзыв не меняет RevokedAt.
+    Revoke(ctx context.Context, id string) (*model.APIKey, error)
+}
+
+// apiKeyColumns - колонки api_keys в порядке, ожидаемом scanAPIKey
+const apiKeyColumns = "id, name, prefix, scopes, created_by, created_at, expires_at, revoked_at"
+
+// sqlAPIKeyRepository - общая реализация APIKeyRepository для PostgreSQL и SQLite
+type sqlAPIKeyRepository struct {
+    db             *sql.DB
+    translateError func(ctx context.Context, err error) error
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+    return &sqlAPIKeyRepository{db: db, translateError: translatePostgresError}
+}
+
+func NewSQLiteAPIKeyRepository(db *sql.DB) APIKeyRepository {
+    return &sqlAPIKeyRepository{db: db, translateError: translateSQLiteError}
+}
+
+func (r *sqlAPIKeyRepository) Create(ctx context.Context, key *model.APIKey, hash string) error {
+    created := *key
+    created.CreatedAt = currentTime()
+
+    var id int64
+    err := r.db.QueryRowContext(ctx,
+        "INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+        created.Name, created.Prefix, hash, joinScopes(created.Scopes), created.CreatedBy, created.CreatedAt, created.ExpiresAt,
+    ).Scan(&id)
+    if err != nil {
+        return r.translateError(ctx, err)
+    }
+    created.ID = strconv.FormatInt(id, 10)
+    *key = created
+    return nil
+}
+
+func (r *sqlAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+    row := r.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", hash)
+    return r.scanOne(ctx, row)
+}
+
+func (r *sqlAPIKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+    rows, err := r.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id")
+    if err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+    defer rows.Close()
+
+    keys := []model.APIKey{}
+    for rows.Next() {
+        key, err := scanAPIKey(rows)
+        if err != nil {
+            return nil, r.translateError(ctx, err)
+        }
+        keys = append(keys, *key)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+    return keys, nil
+}
+
+func (r *sqlAPIKeyRepository) Revoke(ctx context.Context, id string) (*model.APIKey, error) {
+    if !isValidUserID(id) {
+        return nil, ErrAPIKeyNotFound
+    }
+    row := r.db.QueryRowContext(ctx,
+        "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2 RETURNING "+apiKeyColumns,
+        currentTime(), id,
+    )
+    return r.scanOne(ctx, row)
+}
+
+func (r *sqlAPIKeyRepository) scanOne(ctx context.Context, row rowScanner) (*model.APIKey, error) {
+    key, err := scanAPIKey(row)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ErrAPIKeyNotFound
+    }
+    if err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+    return key, nil
+}
+
+func scanAPIKey(row rowScanner) (*model.APIKey, error) {
+    var (
+        k                    model.APIKey
+        id                   int64
+        scopes               string
+        expiresAt, revokedAt sql.NullTime
+    )
+    if err := row.Scan(&id, &k.Name, &k.Prefix, &scopes, &k.CreatedBy, &k.CreatedAt, &expiresAt, &revokedAt); err != nil {
+        return nil, err
+    }
+    k.ID = strconv.FormatInt(id, 10)
+    k.Scopes = splitScopes(scopes)
+    k.CreatedAt = k.CreatedAt.UTC()
+    if expiresAt.Valid {
+        t := expiresAt.Time.UTC()
+        k.ExpiresAt = &t
+    }
+    if revokedAt.Valid {
+        t := revokedAt.Time.UTC()
+        k.RevokedAt = &t
+    }
+    return &k, nil
+}
+
+// Scopes хранятся одной строкой через пробел: действия политики пробелов не содержат
+func joinScopes(scopes []string) string {
+    return strings.Join(scopes, " ")
+}
+
+func splitScopes(value string) []string {
+    scopes := strings.Fields(value)
+    if scopes == nil {
+        return []string{}
+    }
+    return scopes
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+    "context"
+    "encoding/json"
+    "reflect"
+    "strconv"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/actor"
+    "go-crud-example/pkg/requestid"
+)
+
+// newAuditEvent описывает изменение пользователя from -> to. У созданного
+// пользователя from == nil, у окончательно удаленного to == nil. Актор и
+// идентификатор запроса берутся из контекста.
+func newAuditEvent(ctx context.Context, action string, from, to *model.User) (model.AuditEvent, error) {
+    event := model.AuditEvent{
+        Action:    action,
+        Actor:     actor.FromContext(ctx),
+        RequestID: requestid.FromContext(ctx),
+        CreatedAt: currentTime(),
+    }
+    if from != nil {
+        event.UserID = from.ID
+    } else {
+        event.UserID = to.ID
+    }
+
+    before, err := auditFields(from)
+    if err != nil {
+        return event, err
+    }
+    after, err := auditFields(to)
+    if err != nil {
+        return event, err
+    }
+
+    // Для изменения оставляем только поля, значение которых поменялось
+    if before != nil && after != nil {
+        for key, value := range before {
+            if reflect.DeepEqual(value, after[key]) {
+                delete(before, key)
+                delete(after, key)
+            }
+        }
+    }
+
+    if event.Before, err = marshalAuditFields(before); err != nil {
+        return event, err
+    }
+    if event.After, err = marshalAuditFields(after); err != nil {
+        return event, err
+    }
+    return event, nil
+}
+
+// auditFields возвращает поля пользователя, которые попадают в журнал.
+// id хранится в событии отдельно, version и updated_at меняются при каждом
+// изменении, created_at - никогда.
+func auditFields(u *model.User) (map[string]interface{}, error) {
+    if u == nil {
+        return nil, nil
+    }
+
+    data, err := json.Marshal(u)
+    if err != nil {
+        return nil, err
+    }
+    var fields map[string]interface{}
+    if err := json.Unmarshal(data, &fields); err != nil {
+        return nil, err
+    }
+    for _, key := range []string{"id", "version", "created_at", "updated_at"} {
+        delete(fields, key)
+    }
+    // Пустые необязательные поля записываются явно, чтобы их изменение попало в журнал
+    for _, key := range []string{"email", "deleted_at"} {
+        if _, ok := fields[key]; !ok {
+            fields[key] = nil
+        }
+    }
+    return fields, nil
+}
+
+func marshalAuditFields(fields map[string]interface{}) (json.RawMessage, error) {
+    if fields == nil {
+        return nil, nil
+    }
+    return json.Marshal(fields)
+}
+
+// decodeAuditCursor возвращает id последнего события предыдущей страницы
+func decodeAuditCursor(value string) (int64, error) {
+    if value == "" {
+        return 0, nil
+    }
+    id, err := strconv.ParseInt(value, 10, 64)
+    if err != nil || id <= 0 {
+        return 0, ErrInvalidCursor
+    }
+    return id, nil
+}
+
+// newAuditPage отрезает лишнее событие, запрошенное для определения следующей страницы
+func newAuditPage(events []model.AuditEvent, limit int) *model.AuditPage {
+    page := &model.AuditPage{Events: events}
+    if len(events) > limit {
+        page.Events = events[:limit]
+        page.NextCursor = page.Events[limit-1].ID
+    }
+    return page
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "go-crud-example/internal/model"
+    "strconv"
+)
+
+// userCursor - позиция последней записи страницы для keyset-пагинации.
+// Sort сохраняется, чтобы курсор нельзя было применить к другой сортировке.
+type userCursor struct {
+    Sort string `json:"s"`
+    Name string `json:"n,omitempty"`
+    Age  int    `json:"a,omitempty"`
+    ID   int64  `json:"id"`
+}
+
+func newUserCursor(sort string, u model.User) (userCursor, error) {
+    id, err := strconv.ParseInt(u.ID, 10, 64)
+    if err != nil {
+        return userCursor{}, fmt.Errorf("invalid user id %q: %w", u.ID, err)
+    }
+    return userCursor{Sort: sort, Name: u.Name, Age: u.Age, ID: id}, nil
+}
+
+func (c userCursor) encode() (string, error) {
+    data, err := json.Marshal(c)
+    if err != nil {
+        return "", err
+    }
+    return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeUserCursor(value, sort string) (*userCursor, error) {
+    if value == "" {
+        return nil, nil
+    }
+
+    data, err := base64.RawURLEncoding.DecodeString(value)
+    if err != nil {
+        return nil, ErrInvalidCursor
+    }
+
+    var c userCursor
+    if err := json.Unmarshal(data, &c); err != nil {
+        return nil, ErrInvalidCursor
+    }
+    if c.Sort != sort {
+        return nil, ErrInvalidCursor
+    }
+    return &c, nil
+}
+
+// value возвращает значение поля сортировки, сохраненное в курсоре
+func (c *userCursor) value(field string) interface{} {
+    switch field {
+    case model.SortByName:
+        return c.Name
+    case model.SortByAge:
+        return c.Age
+    default:
+        return c.ID
+    }
+}
+
+// newUserPage обрезает выборку до лимита (репозиторий запрашивает на одну
+// запись больше) и формирует курсор следующей страницы
+func newUserPage(users []model.User, q model.UserQuery, total int) (*model.UserPage, error) {
+    page := &model.UserPage{Users: users, Total: total}
+    if len(users) <= q.Limit {
+        return page, nil
+    }
+
+    page.Users = users[:q.Limit]
+    cursor, err := newUserCursor(q.Sort, page.Users[q.Limit-1])
+    if err != nil {
+        return nil, err
+    }
+    if page.NextCursor, err = cursor.encode(); err != nil {
+        return nil, err
+    }
+    return page, nil
+}
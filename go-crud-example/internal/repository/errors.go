@@ -1,5 +1,16 @@
 package repository
 
-import "errors"
+import (
+    "errors"
+    "go-crud-example/internal/model"
+)
 
-var ErrUserNotFound = errors.New("user not found")
+var (
+    ErrUserNotFound    = model.ErrUserNotFound
+    ErrUserConflict    = model.ErrUserConflict
+    ErrEmailTaken      = model.ErrEmailTaken
+    ErrVersionMismatch = model.ErrVersionMismatch
+    ErrUserNotDeleted  = model.ErrUserNotDeleted
+    ErrInvalidCursor   = errors.New("invalid cursor")
+    ErrAPIKeyNotFound  = model.ErrAPIKeyNotFound
+)
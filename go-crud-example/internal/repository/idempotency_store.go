@@ -0,0 +1,145 @@
+package repository
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "go-crud-example/pkg/idempotency"
+)
+
+// maxReserveAttempts ограничивает повторы Reserve, если занятый ключ успели
+// освободить между вставкой и чтением
+const maxReserveAttempts = 3
+
+// sqlIdempotencyStore хранит ключи идемпотентности в таблице idempotency_keys.
+// Ключ занимается одним INSERT ... ON CONFLICT, поэтому одновременные запросы
+// с одним ключом безопасны и между экземплярами сервиса. Запись с status 0 -
+// запрос еще выполняется. Ключ включает subject клиента произвольной длины,
+// поэтому в таблице хранится его SHA-256.
+type sqlIdempotencyStore struct {
+    db             *sql.DB
+    translateError func(ctx context.Context, err error) error
+}
+
+func NewIdempotencyStore(db *sql.DB) idempotency.Store {
+    return &sqlIdempotencyStore{db: db, translateError: translatePostgresError}
+}
+
+func NewSQLiteIdempotencyStore(db *sql.DB) idempotency.Store {
+    return &sqlIdempotencyStore{db: db, translateError: translateSQLiteError}
+}
+
+func (s *sqlIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, now, lockedUntil time.Time) (*idempotency.Record, bool, error) {
+    token, err := idempotency.NewToken()
+    if err != nil {
+        return nil, false, err
+    }
+    key = hashIdempotencyKey(key)
+    now, lockedUntil = now.UTC().Truncate(time.Microsecond), lockedUntil.UTC().Truncate(time.Microsecond)
+
+    for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+        // Истекшая запись заменяется, действующая остается без изменений
+        var reserved string
+        err := s.db.QueryRowContext(ctx,
+            `INSERT INTO idempotency_keys (key, fingerprint, status, header, body, created_at, expires_at, token)
+            VALUES ($1, $2, 0, NULL, NULL, $3, $4, $5)
+            ON CONFLICT (key) DO UPDATE SET fingerprint = excluded.fingerprint, status = 0, header = NULL, body = NULL,
+                created_at = excluded.created_at, expires_at = excluded.expires_at, token = excluded.token
+            WHERE idempotency_keys.expires_at <= excluded.created_at
+            RETURNING key`,
+            key, fingerprint, now, lockedUntil, token,
+        ).Scan(&reserved)
+        if err == nil {
+            return &idempotency.Record{Fingerprint: fingerprint, Token: token}, true, nil
+        }
+        if !errors.Is(err, sql.ErrNoRows) {
+            return nil, false, s.translateError(ctx, err)
+        }
+
+        record, err := s.get(ctx, key)
+        if errors.Is(err, sql.ErrNoRows) {
+            continue
+        }
+        if err != nil {
+            return nil, false, s.translateError(ctx, err)
+        }
+        return record, false, nil
+    }
+    return nil, false, fmt.Errorf("failed to reserve idempotency key after %d attempts", maxReserveAttempts)
+}
+
+func (s *sqlIdempotencyStore) get(ctx context.Context, key string) (*idempotency.Record, error) {
+    var (
+        record idempotency.Record
+        status int
+        header sql.NullString
+        body   []byte
+    )
+    err := s.db.QueryRowContext(ctx,
+        "SELECT fingerprint, status, header, body FROM idempotency_keys WHERE key = $1", key,
+    ).Scan(&record.Fingerprint, &status, &header, &body)
+    if err != nil {
+        return nil, err
+    }
+    if status == 0 {
+        return &record, nil
+    }
+
+    response := &idempotency.Response{Status: status, Header: make(map[string][]string), Body: body}
+    if header.Valid {
+        if err := json.Unmarshal([]byte(header.String), &response.Header); err != nil {
+            return nil, fmt.Errorf("invalid stored response header: %w", err)
+        }
+    }
+    record.Response = response
+    return &record, nil
+}
+
+func (s *sqlIdempotencyStore) Complete(ctx context.Context, key, token string, response idempotency.Response, expiresAt time.Time) error {
+    header, err := json.Marshal(response.Header)
+    if err != nil {
+        return err
+    }
+    result, err := s.db.ExecContext(ctx,
+        `UPDATE idempotency_keys SET status = $1, header = $2, body = $3, expires_at = $4
+        WHERE key = $5 AND token = $6 AND status = 0`,
+        response.Status, string(header), response.Body, expiresAt.UTC().Truncate(time.Microsecond), hashIdempotencyKey(key), token,
+    )
+    if err != nil {
+        return s.translateError(ctx, err)
+    }
+    updated, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if updated == 0 {
+        return idempotency.ErrNotReserved
+    }
+    return nil
+}
+
+func (s *sqlIdempotencyStore) Release(ctx context.Context, key, token string) error {
+    _, err := s.db.ExecContext(ctx,
+        "DELETE FROM idempotency_keys WHERE key = $1 AND token = $2 AND status = 0", hashIdempotencyKey(key), token,
+    )
+    return s.translateError(ctx, err)
+}
+
+func (s *sqlIdempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+    result, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now.UTC().Truncate(time.Microsecond))
+    if err != nil {
+        return 0, s.translateError(ctx, err)
+    }
+    return result.RowsAffected()
+}
+
+func hashIdempotencyKey(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    return hex.EncodeToString(sum[:])
+}
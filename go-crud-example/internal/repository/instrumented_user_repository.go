@@ -0,0 +1,92 @@
+package repository
+
+import (
+    "context"
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/metrics"
+    "time"
+)
+
+// instrumentedUserRepository замеряет длительность каждой операции
+// репозитория в метрике database_query_duration_seconds
+type instrumentedUserRepository struct {
+    next UserRepository
+}
+
+func NewInstrumentedUserRepository(next UserRepository) UserRepository {
+    return &instrumentedUserRepository{next: next}
+}
+
+func (r *instrumentedUserRepository) GetAll(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
+    defer observeQuery("get_all", time.Now())
+    return r.next.GetAll(ctx, query)
+}
+
+func (r *instrumentedUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
+    defer observeQuery("get_by_id", time.Now())
+    return r.next.GetByID(ctx, id)
+}
+
+func (r *instrumentedUserRepository) Create(ctx context.Context, user *model.User) error {
+    defer observeQuery("create", time.Now())
+    return r.next.Create(ctx, user)
+}
+
+func (r *instrumentedUserRepository) Update(ctx context.Context, user *model.User) error {
+    defer observeQuery("update", time.Now())
+    return r.next.Update(ctx, user)
+}
+
+func (r *instrumentedUserRepository) Patch(ctx context.Context, id string, patch model.UserPatch) (*model.User, error) {
+    defer observeQuery("patch", time.Now())
+    return r.next.Patch(ctx, id, patch)
+}
+
+func (r *instrumentedUserRepository) Delete(ctx context.Context, id string) error {
+    defer observeQuery("delete", time.Now())
+    return r.next.Delete(ctx, id)
+}
+
+func (r *instrumentedUserRepository) Restore(ctx context.Context, id string) (*model.User, error) {
+    defer observeQuery("restore", time.Now())
+    return r.next.Restore(ctx, id)
+}
+
+func (r *instrumentedUserRepository) Purge(ctx context.Context, id string) error {
+    defer observeQuery("purge", time.Now())
+    return r.next.Purge(ctx, id)
+}
+
+func (r *instrumentedUserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+    defer observeQuery("purge_deleted", time.Now())
+    return r.next.PurgeDeleted(ctx, before)
+}
+
+func (r *instrumentedUserRepository) Export(ctx context.Context, query model.UserQuery, fn func(model.User) error) error {
+    defer observeQuery("export", time.Now())
+    return r.next.Export(ctx, query, fn)
+}
+
+func (r *instrumentedUserRepository) Search(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+    defer observeQuery("search", time.Now())
+    return r.next.Search(ctx, query)
+}
+
+func (r *instrumentedUserRepository) CreateMany(ctx context.Context, users []*model.User) error {
+    defer observeQuery("create_many", time.Now())
+    return r.next.CreateMany(ctx, users)
+}
+
+func (r *instrumentedUserRepository) Batch(ctx context.Context, ops []model.BatchOperation, atomic bool) ([]model.BatchResult, error) {
+    defer observeQuery("batch", time.Now())
+    return r.next.Batch(ctx, ops, atomic)
+}
+
+func (r *instrumentedUserRepository) AuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+    defer observeQuery("audit_events", time.Now())
+    return r.next.AuditEvents(ctx, query)
+}
+
+func observeQuery(queryType string, start time.Time) {
+    metrics.DatabaseQueryDuration.WithLabelValues(queryType).Observe(time.Since(start).Seconds())
+}
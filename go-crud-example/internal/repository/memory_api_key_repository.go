@@ -0,0 +1,91 @@
+package repository
+
+import (
+    "context"
+    "strconv"
+    "sync"
+
+    "go-crud-example/internal/model"
+)
+
+// MemoryAPIKeyRepository хранит API-ключи в памяти процесса, в паре с
+// MemoryUserRepository. Ключи теряются при перезапуске.
+type MemoryAPIKeyRepository struct {
+    mu     sync.RWMutex
+    keys   []model.APIKey
+    hashes map[string]int
+}
+
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+    return &MemoryAPIKeyRepository{hashes: make(map[string]int)}
+}
+
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, key *model.APIKey, hash string) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if _, ok := r.hashes[hash]; ok {
+        return ErrUserConflict
+    }
+    created := *key
+    created.ID = strconv.Itoa(len(r.keys) + 1)
+    created.Scopes = append([]string(nil), key.Scopes...)
+    created.CreatedAt = currentTime()
+
+    r.hashes[hash] = len(r.keys)
+    r.keys = append(r.keys, created)
+    *key = created
+    return nil
+}
+
+func (r *MemoryAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    i, ok := r.hashes[hash]
+    if !ok {
+        return nil, ErrAPIKeyNotFound
+    }
+    key := r.keys[i]
+    return &key, nil
+}
+
+func (r *MemoryAPIKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    return append([]model.APIKey{}, r.keys...), nil
+}
+
+func (r *MemoryAPIKeyRepository) Revoke(ctx context.Context, id string) (*model.APIKey, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    i, err := strconv.Atoi(id)
+    if err != nil || i < 1 || i > len(r.keys) {
+        return nil, ErrAPIKeyNotFound
+    }
+    key := &r.keys[i-1]
+    if key.RevokedAt == nil {
+        revokedAt := currentTime()
+        key.RevokedAt = &revokedAt
+    }
+    revoked := *key
+    return &revoked, nil
+}
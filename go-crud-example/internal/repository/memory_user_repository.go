@@ -0,0 +1,608 @@
+package repository
+
+import (
+    "context"
+    "fmt"
+    "go-crud-example/internal/model"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// MemoryUserRepository хранит пользователей в памяти процесса. Используется
+// для локальной разработки и тестов, когда PostgreSQL недоступен.
+type MemoryUserRepository struct {
+    mu     sync.RWMutex
+    users  map[int64]model.User
+    nextID int64
+    events []model.AuditEvent
+}
+
+// MemorySnapshot - копия состояния MemoryUserRepository для последующего RestoreSnapshot
+type MemorySnapshot struct {
+    users  map[int64]model.User
+    nextID int64
+    events []model.AuditEvent
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+    return &MemoryUserRepository{
+        users:  make(map[int64]model.User),
+        nextID: 1,
+    }
+}
+
+func (r *MemoryUserRepository) GetAll(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+    cursor, err := decodeUserCursor(query.Cursor, query.Sort)
+    if err != nil {
+        return nil, err
+    }
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    matched, less := r.sortedUsers(query)
+
+    start := 0
+    if cursor != nil {
+        last := model.User{ID: strconv.FormatInt(cursor.ID, 10), Name: cursor.Name, Age: cursor.Age}
+        start = sort.Search(len(matched), func(i int) bool {
+            return less(last, matched[i])
+        })
+    }
+
+    end := start + query.Limit + 1
+    if end > len(matched) {
+        end = len(matched)
+    }
+    users := append([]model.User{}, matched[start:end]...)
+
+    return newUserPage(users, query, len(matched))
+}
+
+func (r *MemoryUserRepository) Export(ctx context.Context, query model.UserQuery, fn func(model.User) error) error {
+    if err := query.Normalize(); err != nil {
+        return err
+    }
+
+    // fn вызывается без блокировки, чтобы медленный клиент не задерживал запись
+    users, _ := r.sortedUsers(query)
+    for _, u := range users {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        if err := fn(u); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (r *MemoryUserRepository) Search(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+    return naiveSearch(ctx, query, r.Export)
+}
+
+// sortedUsers возвращает копии пользователей, подходящих под фильтры query, в
+// порядке ее сортировки и функцию сравнения этого порядка
+func (r *MemoryUserRepository) sortedUsers(query model.UserQuery) ([]model.User, func(a, b model.User) bool) {
+    r.mu.RLock()
+    matched := make([]model.User, 0, len(r.users))
+    for _, u := range r.users {
+        if matchesUserQuery(u, query) {
+            matched = append(matched, u)
+        }
+    }
+    r.mu.RUnlock()
+
+    field, desc := query.SortField()
+    less := func(a, b model.User) bool {
+        c := compareUsers(a, b, field)
+        if desc {
+            return c > 0
+        }
+        return c < 0
+    }
+    sort.Slice(matched, func(i, j int) bool {
+        return less(matched[i], matched[j])
+    })
+    return matched, less
+}
+
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    key, ok := parseMemoryID(id)
+    if !ok {
+        return nil, ErrUserNotFound
+    }
+
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    u, exists := r.users[key]
+    if !exists || u.DeletedAt != nil {
+        return nil, ErrUserNotFound
+    }
+    return &u, nil
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *model.User) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.create(ctx, user)
+}
+
+func (r *MemoryUserRepository) CreateMany(ctx context.Context, users []*model.User) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    // Как и в SQL хранилищах, пользователи создаются все вместе или ни один
+    state := r.save()
+    for _, user := range users {
+        if err := r.create(ctx, user); err != nil {
+            r.rollback(state)
+            return err
+        }
+    }
+    return nil
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, user *model.User) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.update(ctx, user)
+}
+
+func (r *MemoryUserRepository) Patch(ctx context.Context, id string, patch model.UserPatch) (*model.User, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    key, ok := parseMemoryID(id)
+    if !ok {
+        return nil, ErrUserNotFound
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    current, exists := r.users[key]
+    if !exists || current.DeletedAt != nil {
+        return nil, ErrUserNotFound
+    }
+    if patch.IsEmpty() {
+        return &current, nil
+    }
+    if patch.Version > 0 && patch.Version != current.Version {
+        return nil, ErrVersionMismatch
+    }
+
+    u := current
+    if patch.Name != nil {
+        u.Name = *patch.Name
+    }
+    if patch.Age != nil {
+        u.Age = *patch.Age
+    }
+    if patch.Email != nil {
+        u.Email = *patch.Email
+        if r.emailTaken(u.Email, key) {
+            return nil, ErrEmailTaken
+        }
+    }
+    u.Version++
+    u.UpdatedAt = currentTime()
+    if err := r.audit(ctx, model.AuditActionUpdate, &current, &u); err != nil {
+        return nil, err
+    }
+    r.users[key] = u
+    return &u, nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id string) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.delete(ctx, id)
+}
+
+func (r *MemoryUserRepository) Restore(ctx context.Context, id string) (*model.User, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    key, ok := parseMemoryID(id)
+    if !ok {
+        return nil, ErrUserNotFound
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    current, exists := r.users[key]
+    if !exists {
+        return nil, ErrUserNotFound
+    }
+    if current.DeletedAt == nil {
+        return &current, nil
+    }
+
+    u := current
+    u.DeletedAt = nil
+    u.Version++
+    u.UpdatedAt = currentTime()
+    if err := r.audit(ctx, model.AuditActionRestore, &current, &u); err != nil {
+        return nil, err
+    }
+    r.users[key] = u
+    return &u, nil
+}
+
+func (r *MemoryUserRepository) Purge(ctx context.Context, id string) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    key, ok := parseMemoryID(id)
+    if !ok {
+        return ErrUserNotFound
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    u, exists := r.users[key]
+    if !exists {
+        return ErrUserNotFound
+    }
+    if u.DeletedAt == nil {
+        return ErrUserNotDeleted
+    }
+    if err := r.audit(ctx, model.AuditActionPurge, &u, nil); err != nil {
+        return err
+    }
+    delete(r.users, key)
+    return nil
+}
+
+func (r *MemoryUserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+    if err := ctx.Err(); err != nil {
+        return 0, err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    // Ключи сортируются, чтобы события шли в том же порядке при каждом запуске
+    var keys []int64
+    for key, u := range r.users {
+        if u.DeletedAt != nil && u.DeletedAt.Before(before) {
+            keys = append(keys, key)
+        }
+    }
+    sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+    for _, key := range keys {
+        u := r.users[key]
+        if err := r.audit(ctx, model.AuditActionPurge, &u, nil); err != nil {
+            return 0, err
+        }
+        delete(r.users, key)
+    }
+    return int64(len(keys)), nil
+}
+
+func (r *MemoryUserRepository) AuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+    after, err := decodeAuditCursor(query.Cursor)
+    if err != nil {
+        return nil, err
+    }
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    // События хранятся в порядке id, поэтому курсор - это позиция в срезе
+    events := []model.AuditEvent{}
+    for _, e := range r.events[min(int(after), len(r.events)):] {
+        if query.UserID != "" && e.UserID != query.UserID {
+            continue
+        }
+        if query.Since != nil && e.CreatedAt.Before(*query.Since) {
+            continue
+        }
+        events = append(events, e)
+        if len(events) > query.Limit {
+            break
+        }
+    }
+    return newAuditPage(events, query.Limit), nil
+}
+
+func (r *MemoryUserRepository) Batch(ctx context.Context, ops []model.BatchOperation, atomic bool) ([]model.BatchResult, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    state := r.save()
+    results := make([]model.BatchResult, len(ops))
+    for i, op := range ops {
+        results[i] = r.applyBatchOperation(ctx, op)
+        if atomic && results[i].Err != nil {
+            r.rollback(state)
+            abortBatch(results[:i])
+            abortBatch(results[i+1:])
+            return results, nil
+        }
+    }
+    return results, nil
+}
+
+// applyBatchOperation выполняет одну операцию пакета под r.mu
+func (r *MemoryUserRepository) applyBatchOperation(ctx context.Context, op model.BatchOperation) model.BatchResult {
+    switch op.Op {
+    case model.BatchOpCreate:
+        u := *op.User
+        if err := r.create(ctx, &u); err != nil {
+            return model.BatchResult{Err: err}
+        }
+        return model.BatchResult{User: &u}
+    case model.BatchOpUpdate:
+        u := *op.User
+        u.ID, u.Version = op.ID, op.Version
+        if err := r.update(ctx, &u); err != nil {
+            return model.BatchResult{Err: err}
+        }
+        u.DeletedAt = nil
+        return model.BatchResult{User: &u}
+    case model.BatchOpDelete:
+        return model.BatchResult{Err: r.delete(ctx, op.ID)}
+    default:
+        return model.BatchResult{Err: fmt.Errorf("%w: unknown operation %q", model.ErrInvalidBatch, op.Op)}
+    }
+}
+
+// create, update и delete изменяют хранилище под r.mu и при ошибке оставляют его прежним
+
+func (r *MemoryUserRepository) create(ctx context.Context, user *model.User) error {
+    if r.emailTaken(user.Email, 0) {
+        return ErrEmailTaken
+    }
+
+    id := r.nextID
+    created := *user
+    created.ID = strconv.FormatInt(id, 10)
+    created.Version = 1
+    created.DeletedAt = nil
+    created.CreatedAt = currentTime()
+    created.UpdatedAt = created.CreatedAt
+    if err := r.audit(ctx, model.AuditActionCreate, nil, &created); err != nil {
+        return err
+    }
+
+    r.nextID++
+    r.users[id] = created
+    *user = created
+    return nil
+}
+
+func (r *MemoryUserRepository) update(ctx context.Context, user *model.User) error {
+    key, ok := parseMemoryID(user.ID)
+    if !ok {
+        return ErrUserNotFound
+    }
+
+    current, exists := r.users[key]
+    if !exists || current.DeletedAt != nil {
+        return ErrUserNotFound
+    }
+    if user.Version > 0 && user.Version != current.Version {
+        return ErrVersionMismatch
+    }
+    if r.emailTaken(user.Email, key) {
+        return ErrEmailTaken
+    }
+
+    stored := *user
+    stored.ID = strconv.FormatInt(key, 10)
+    stored.Version = current.Version + 1
+    stored.DeletedAt = nil
+    stored.CreatedAt = current.CreatedAt
+    stored.UpdatedAt = currentTime()
+    if err := r.audit(ctx, model.AuditActionUpdate, &current, &stored); err != nil {
+        return err
+    }
+    r.users[key] = stored
+    *user = stored
+    return nil
+}
+
+func (r *MemoryUserRepository) delete(ctx context.Context, id string) error {
+    key, ok := parseMemoryID(id)
+    if !ok {
+        return ErrUserNotFound
+    }
+
+    current, exists := r.users[key]
+    if !exists || current.DeletedAt != nil {
+        return ErrUserNotFound
+    }
+    u := current
+    deletedAt := currentTime()
+    u.DeletedAt = &deletedAt
+    u.Version++
+    u.UpdatedAt = deletedAt
+    if err := r.audit(ctx, model.AuditActionDelete, &current, &u); err != nil {
+        return err
+    }
+    r.users[key] = u
+    return nil
+}
+
+// emailTaken сообщает, что email занят пользователем, отличным от except.
+// Как и уникальный индекс в БД, учитываются и удаленные пользователи.
+func (r *MemoryUserRepository) emailTaken(email string, except int64) bool {
+    if email == "" {
+        return false
+    }
+    for key, u := range r.users {
+        if key != except && strings.EqualFold(u.Email, email) {
+            return true
+        }
+    }
+    return false
+}
+
+// memoryState - состояние хранилища для отката неудавшегося пакета
+type memoryState struct {
+    users  map[int64]model.User
+    nextID int64
+    events int
+}
+
+// save запоминает состояние под r.mu
+func (r *MemoryUserRepository) save() memoryState {
+    users := make(map[int64]model.User, len(r.users))
+    for id, u := range r.users {
+        users[id] = u
+    }
+    return memoryState{users: users, nextID: r.nextID, events: len(r.events)}
+}
+
+// rollback возвращает состояние, сохраненное save. События журнала только
+// добавляются, поэтому достаточно отрезать новые.
+func (r *MemoryUserRepository) rollback(state memoryState) {
+    r.users = state.users
+    r.nextID = state.nextID
+    r.events = r.events[:state.events]
+}
+
+// audit добавляет событие журнала аудита. Вызывается под r.mu до изменения
+// r.users, чтобы при ошибке хранилище осталось прежним.
+func (r *MemoryUserRepository) audit(ctx context.Context, action string, from, to *model.User) error {
+    event, err := newAuditEvent(ctx, action, from, to)
+    if err != nil {
+        return err
+    }
+    event.ID = strconv.Itoa(len(r.events) + 1)
+    r.events = append(r.events, event)
+    return nil
+}
+
+// Snapshot сохраняет текущее состояние хранилища
+func (r *MemoryUserRepository) Snapshot() MemorySnapshot {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    users := make(map[int64]model.User, len(r.users))
+    for id, u := range r.users {
+        users[id] = u
+    }
+    events := append([]model.AuditEvent(nil), r.events...)
+    return MemorySnapshot{users: users, nextID: r.nextID, events: events}
+}
+
+// RestoreSnapshot возвращает хранилище к состоянию, сохраненному в Snapshot
+func (r *MemoryUserRepository) RestoreSnapshot(snapshot MemorySnapshot) {
+    users := make(map[int64]model.User, len(snapshot.users))
+    for id, u := range snapshot.users {
+        users[id] = u
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    r.users = users
+    r.nextID = snapshot.nextID
+    r.events = append([]model.AuditEvent(nil), snapshot.events...)
+    if r.nextID == 0 {
+        r.nextID = 1
+    }
+}
+
+func parseMemoryID(id string) (int64, bool) {
+    if !isValidUserID(id) {
+        return 0, false
+    }
+    key, _ := strconv.ParseInt(id, 10, 64)
+    return key, true
+}
+
+func matchesUserQuery(u model.User, q model.UserQuery) bool {
+    if u.DeletedAt != nil && !q.IncludeDeleted {
+        return false
+    }
+    if q.NamePrefix != "" && !strings.HasPrefix(u.Name, q.NamePrefix) {
+        return false
+    }
+    if q.MinAge != nil && u.Age < *q.MinAge {
+        return false
+    }
+    if q.MaxAge != nil && u.Age > *q.MaxAge {
+        return false
+    }
+    return true
+}
+
+// compareUsers сравнивает пользователей по полю сортировки, при равенстве - по id,
+// так же как ORDER BY в PostgresUserRepository
+func compareUsers(a, b model.User, field string) int {
+    switch field {
+    case model.SortByName:
+        if c := strings.Compare(a.Name, b.Name); c != 0 {
+            return c
+        }
+    case model.SortByAge:
+        if a.Age != b.Age {
+            return compareInt64(int64(a.Age), int64(b.Age))
+        }
+    }
+
+    aID, _ := strconv.ParseInt(a.ID, 10, 64)
+    bID, _ := strconv.ParseInt(b.ID, 10, 64)
+    return compareInt64(aID, bID)
+}
+
+func compareInt64(a, b int64) int {
+    switch {
+    case a < b:
+        return -1
+    case a > b:
+        return 1
+    default:
+        return 0
+    }
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+
+    "github.com/lib/pq"
+    "go-crud-example/internal/model"
+)
+
+// Коды ошибок PostgreSQL, см. https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+    pgUniqueViolation     = "23505"
+    pgForeignKeyViolation = "23503"
+    pgNotNullViolation    = "23502"
+    pgCheckViolation      = "23514"
+    pgStringTooLong       = "22001"
+    pgNumericOutOfRange   = "22003"
+    pgQueryCanceled       = "57014"
+)
+
+// translatePostgresError переводит ошибки драйвера в доменные ошибки.
+// Исходная ошибка остается в цепочке для логирования.
+func translatePostgresError(ctx context.Context, err error) error {
+    if err == nil {
+        return nil
+    }
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrUserNotFound
+    }
+
+    var pqErr *pq.Error
+    if !errors.As(err, &pqErr) {
+        return err
+    }
+
+    switch pqErr.Code {
+    case pgUniqueViolation:
+        if pqErr.Constraint == userEmailIndex {
+            return fmt.Errorf("%w: %w", ErrEmailTaken, err)
+        }
+        return fmt.Errorf("%w: %w", ErrUserConflict, err)
+    case pgForeignKeyViolation:
+        return fmt.Errorf("%w: %w", ErrUserConflict, err)
+    case pgNotNullViolation, pgCheckViolation, pgStringTooLong, pgNumericOutOfRange:
+        return fmt.Errorf("%w: %w", model.ErrInvalidUser, err)
+    case pgQueryCanceled:
+        // Запрос отменен драйвером из-за отмены контекста
+        if ctxErr := ctx.Err(); ctxErr != nil {
+            return fmt.Errorf("%w: %w", ctxErr, err)
+        }
+    }
+    return err
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+    "context"
+
+    "go-crud-example/internal/model"
+)
+
+// searchUsersQuery находит пользователей по префиксам слов в search_vector или по
+// триграммной похожести имени (оператор % с порогом pg_trgm.similarity_threshold).
+// Оба условия покрыты GIN-индексами из миграций 0006 и 0010.
+const searchUsersQuery = `SELECT ` + userColumns + `, ts_rank(search_vector, query) + similarity(name, $2) AS score
+FROM users, to_tsquery('simple', $1) AS query
+WHERE deleted_at IS NULL AND (search_vector @@ query OR name % $2)
+ORDER BY score DESC, id
+LIMIT $3`
+
+// Search ищет пользователей полнотекстовым поиском PostgreSQL. Подсветка
+// строится по найденным строкам, чтобы совпадать с остальными хранилищами.
+func (r *PostgresUserRepository) Search(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+    terms := searchTerms(query.Q)
+    results := []model.SearchResult{}
+    if len(terms) == 0 {
+        return results, nil
+    }
+
+    rows, err := r.db.QueryContext(ctx, searchUsersQuery, prefixTSQuery(terms), query.Q, query.Limit)
+    if err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var score float64
+        u, err := scanUser(rows, &score)
+        if err != nil {
+            return nil, r.translateError(ctx, err)
+        }
+        results = append(results, newSearchResult(*u, score, terms))
+    }
+    if err := rows.Err(); err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+    return results, nil
+}
@@ -0,0 +1,1420 @@
+// Package repositorytest содержит набор тестов соответствия для реализаций
+// repository.UserRepository. Каждое хранилище подключает его одной строкой:
+//
+//    repositorytest.Run(t, func(t *testing.T) repository.UserRepository { ... })
+//
+// Фабрика вызывается для каждого подтеста и должна возвращать пустое хранилище.
+package repositorytest
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "strconv"
+    "sync"
+    "testing"
+    "time"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/pkg/actor"
+    "go-crud-example/pkg/requestid"
+)
+
+// Factory создает пустое хранилище для одного подтеста
+type Factory func(t *testing.T) repository.UserRepository
+
+// Run запускает все проверки набора против хранилища из newRepo
+func Run(t *testing.T, newRepo Factory) {
+    t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, newRepo(t)) })
+    t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+    t.Run("Versioning", func(t *testing.T) { testVersioning(t, newRepo(t)) })
+    t.Run("Patch", func(t *testing.T) { testPatch(t, newRepo(t)) })
+    t.Run("Email", func(t *testing.T) { testEmail(t, newRepo(t)) })
+    t.Run("Timestamps", func(t *testing.T) { testTimestamps(t, newRepo(t)) })
+    t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo(t)) })
+    t.Run("SoftDelete", func(t *testing.T) { testSoftDelete(t, newRepo(t)) })
+    t.Run("Restore", func(t *testing.T) { testRestore(t, newRepo(t)) })
+    t.Run("Purge", func(t *testing.T) { testPurge(t, newRepo(t)) })
+    t.Run("PurgeDeleted", func(t *testing.T) { testPurgeDeleted(t, newRepo(t)) })
+    t.Run("NotFound", func(t *testing.T) { testNotFound(t, newRepo(t)) })
+    t.Run("Ordering", func(t *testing.T) { testOrdering(t, newRepo(t)) })
+    t.Run("Pagination", func(t *testing.T) { testPagination(t, newRepo(t)) })
+    t.Run("Filters", func(t *testing.T) { testFilters(t, newRepo(t)) })
+    t.Run("InvalidQuery", func(t *testing.T) { testInvalidQuery(t, newRepo(t)) })
+    t.Run("Export", func(t *testing.T) { testExport(t, newRepo(t)) })
+    t.Run("Search", func(t *testing.T) { testSearch(t, newRepo(t)) })
+    t.Run("ConcurrentCreate", func(t *testing.T) { testConcurrentCreate(t, newRepo(t)) })
+    t.Run("ConcurrentUpdateDelete", func(t *testing.T) { testConcurrentUpdateDelete(t, newRepo(t)) })
+    t.Run("ConcurrentConditionalUpdate", func(t *testing.T) { testConcurrentConditionalUpdate(t, newRepo(t)) })
+    t.Run("CanceledContext", func(t *testing.T) { testCanceledContext(t, newRepo(t)) })
+    t.Run("CreateMany", func(t *testing.T) { testCreateMany(t, newRepo(t)) })
+    t.Run("BatchAtomic", func(t *testing.T) { testBatchAtomic(t, newRepo(t)) })
+    t.Run("BatchBestEffort", func(t *testing.T) { testBatchBestEffort(t, newRepo(t)) })
+    t.Run("Audit", func(t *testing.T) { testAudit(t, newRepo(t)) })
+    t.Run("AuditQuery", func(t *testing.T) { testAuditQuery(t, newRepo(t)) })
+}
+
+func testCreateAndGet(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+
+    first := mustCreate(t, repo, "John", 30)
+    second := mustCreate(t, repo, "Ann", 25)
+    if first.ID == "" || second.ID == "" {
+        t.Fatal("Create() did not assign an ID")
+    }
+    if first.ID == second.ID {
+        t.Fatalf("Create() assigned the same ID %s twice", first.ID)
+    }
+
+    got, err := repo.GetByID(ctx, first.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if *got != *first {
+        t.Errorf("GetByID() = %+v, want %+v", *got, *first)
+    }
+}
+
+func testUpdate(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+
+    user := mustCreate(t, repo, "John", 30)
+    other := mustCreate(t, repo, "Ann", 25)
+
+    updated := &model.User{ID: user.ID, Name: "Johnny", Age: 31}
+    if err := repo.Update(ctx, updated); err != nil {
+        t.Fatalf("Update() error = %v", err)
+    }
+
+    got, err := repo.GetByID(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if *got != *updated {
+        t.Errorf("GetByID() after update = %+v, want %+v", *got, *updated)
+    }
+
+    // Остальные записи не затронуты
+    got, err = repo.GetByID(ctx, other.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if *got != *other {
+        t.Errorf("GetByID() of untouched user = %+v, want %+v", *got, *other)
+    }
+}
+
+func testVersioning(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+
+    user := mustCreate(t, repo, "John", 30)
+    if user.Version != 1 {
+        t.Fatalf("Create() version = %d, want 1", user.Version)
+    }
+
+    // Обновление без ожидаемой версии безусловно и увеличивает версию
+    if err := repo.Update(ctx, &model.User{ID: user.ID, Name: "John", Age: 31}); err != nil {
+        t.Fatalf("Update() error = %v", err)
+    }
+
+    stale := &model.User{ID: user.ID, Name: "Stale", Age: 99, Version: 1}
+    if err := repo.Update(ctx, stale); !errors.Is(err, repository.ErrVersionMismatch) {
+        t.Fatalf("Update() with stale version error = %v, want ErrVersionMismatch", err)
+    }
+
+    current := &model.User{ID: user.ID, Name: "Johnny", Age: 32, Version: 2}
+    if err := repo.Update(ctx, current); err != nil {
+        t.Fatalf("Update() with current version error = %v", err)
+    }
+    if current.Version != 3 {
+        t.Errorf("Update() version = %d, want 3", current.Version)
+    }
+
+    got, err := repo.GetByID(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if *got != *current {
+        t.Errorf("GetByID() = %+v, want %+v", *got, *current)
+    }
+
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if err := repo.Update(ctx, &model.User{ID: user.ID, Name: "John", Age: 30, Version: 3}); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("conditional Update() of deleted user error = %v, want ErrUserNotFound", err)
+    }
+}
+
+func testPatch(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    user := mustCreate(t, repo, "John", 30)
+
+    age := 31
+    got, err := repo.Patch(ctx, user.ID, model.UserPatch{Age: &age})
+    if err != nil {
+        t.Fatalf("Patch() error = %v", err)
+    }
+    want := model.User{ID: user.ID, Name: "John", Age: 31, Version: 2}
+    if withoutTimestamps(*got) != want {
+        t.Errorf("Patch() = %+v, want %+v", *got, want)
+    }
+
+    // Пустой патч ничего не меняет, включая версию
+    got, err = repo.Patch(ctx, user.ID, model.UserPatch{})
+    if err != nil {
+        t.Fatalf("Patch() with no changes error = %v", err)
+    }
+    if withoutTimestamps(*got) != want {
+        t.Errorf("Patch() with no changes = %+v, want %+v", *got, want)
+    }
+
+    name := "Johnny"
+    if _, err := repo.Patch(ctx, user.ID, model.UserPatch{Name: &name, Version: 1}); !errors.Is(err, repository.ErrVersionMismatch) {
+        t.Errorf("Patch() with stale version error = %v, want ErrVersionMismatch", err)
+    }
+    got, err = repo.Patch(ctx, user.ID, model.UserPatch{Name: &name, Version: 2})
+    if err != nil {
+        t.Fatalf("Patch() with current version error = %v", err)
+    }
+    want = model.User{ID: user.ID, Name: "Johnny", Age: 31, Version: 3}
+    if withoutTimestamps(*got) != want {
+        t.Errorf("Patch() = %+v, want %+v", *got, want)
+    }
+
+    stored, err := repo.GetByID(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if withoutTimestamps(*stored) != want {
+        t.Errorf("GetByID() after patch = %+v, want %+v", *stored, want)
+    }
+
+    for _, id := range []string{"999999", "abc"} {
+        if _, err := repo.Patch(ctx, id, model.UserPatch{Age: &age}); !errors.Is(err, repository.ErrUserNotFound) {
+            t.Errorf("Patch(%q) error = %v, want ErrUserNotFound", id, err)
+        }
+        if _, err := repo.Patch(ctx, id, model.UserPatch{Age: &age, Version: 1}); !errors.Is(err, repository.ErrUserNotFound) {
+            t.Errorf("conditional Patch(%q) error = %v, want ErrUserNotFound", id, err)
+        }
+    }
+}
+
+func testEmail(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+
+    user := &model.User{Name: "John", Age: 30, Email: "John@Example.com"}
+    if err := repo.Create(ctx, user); err != nil {
+        t.Fatalf("Create() error = %v", err)
+    }
+    got, err := repo.GetByID(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if got.Email != "John@Example.com" {
+        t.Errorf("GetByID() email = %q, want it stored as given", got.Email)
+    }
+
+    // Email уникален без учета регистра, пустой email не уникален
+    err = repo.Create(ctx, &model.User{Name: "Johnny", Age: 20, Email: "john@example.COM"})
+    if !errors.Is(err, repository.ErrEmailTaken) || !errors.Is(err, repository.ErrUserConflict) {
+        t.Errorf("Create() with taken email error = %v, want ErrEmailTaken", err)
+    }
+    other := mustCreate(t, repo, "Ann", 25)
+    mustCreate(t, repo, "Bob", 40)
+
+    if err := repo.Update(ctx, &model.User{ID: other.ID, Name: "Ann", Age: 25, Email: "JOHN@example.com"}); !errors.Is(err, repository.ErrEmailTaken) {
+        t.Errorf("Update() with taken email error = %v, want ErrEmailTaken", err)
+    }
+    email := "john@example.com"
+    if _, err := repo.Patch(ctx, other.ID, model.UserPatch{Email: &email}); !errors.Is(err, repository.ErrEmailTaken) {
+        t.Errorf("Patch() with taken email error = %v, want ErrEmailTaken", err)
+    }
+    // Смена регистра собственного email - не конфликт
+    if err := repo.Update(ctx, &model.User{ID: user.ID, Name: "John", Age: 30, Email: email}); err != nil {
+        t.Fatalf("Update() of own email error = %v", err)
+    }
+
+    // Неудачная пакетная вставка не создает ни одного пользователя
+    err = repo.CreateMany(ctx, []*model.User{
+        {Name: "Kate", Age: 41, Email: "kate@example.com"},
+        {Name: "Katie", Age: 41, Email: "KATE@example.com"},
+    })
+    if !errors.Is(err, repository.ErrEmailTaken) {
+        t.Errorf("CreateMany() with duplicate emails error = %v, want ErrEmailTaken", err)
+    }
+    page, err := repo.GetAll(ctx, model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    assertNames(t, page.Users, []string{"John", "Ann", "Bob"})
+
+    // Email удаленного пользователя занят до окончательного удаления
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if _, err := repo.Patch(ctx, other.ID, model.UserPatch{Email: &email}); !errors.Is(err, repository.ErrEmailTaken) {
+        t.Errorf("Patch() with email of deleted user error = %v, want ErrEmailTaken", err)
+    }
+    if err := repo.Purge(ctx, user.ID); err != nil {
+        t.Fatalf("Purge() error = %v", err)
+    }
+    patched, err := repo.Patch(ctx, other.ID, model.UserPatch{Email: &email})
+    if err != nil {
+        t.Fatalf("Patch() with freed email error = %v", err)
+    }
+    if patched.Email != email {
+        t.Errorf("Patch() email = %q, want %q", patched.Email, email)
+    }
+
+    // Пустой email очищает поле
+    empty := ""
+    if patched, err = repo.Patch(ctx, other.ID, model.UserPatch{Email: &empty}); err != nil {
+        t.Fatalf("Patch() clearing email error = %v", err)
+    }
+    if patched.Email != "" {
+        t.Errorf("Patch() email = %q, want empty", patched.Email)
+    }
+}
+
+func testTimestamps(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    start := time.Now().Add(-time.Second)
+
+    // Значения из запроса игнорируются
+    user := &model.User{Name: "John", Age: 30, CreatedAt: time.Unix(1, 0), UpdatedAt: time.Unix(1, 0)}
+    if err := repo.Create(ctx, user); err != nil {
+        t.Fatalf("Create() error = %v", err)
+    }
+    if user.CreatedAt.Before(start) || user.CreatedAt.After(time.Now().Add(time.Second)) {
+        t.Fatalf("Create() created_at = %v, want about now", user.CreatedAt)
+    }
+    if !user.UpdatedAt.Equal(user.CreatedAt) {
+        t.Errorf("Create() updated_at = %v, want created_at %v", user.UpdatedAt, user.CreatedAt)
+    }
+    createdAt := user.CreatedAt
+
+    // assertUpdated проверяет, что изменение сдвинуло updated_at, но не created_at
+    last := user.UpdatedAt
+    assertUpdated := func(name string, u *model.User) {
+        t.Helper()
+        if !u.CreatedAt.Equal(createdAt) {
+            t.Errorf("%s created_at = %v, want %v", name, u.CreatedAt, createdAt)
+        }
+        if !u.UpdatedAt.After(last) {
+            t.Errorf("%s updated_at = %v, want after %v", name, u.UpdatedAt, last)
+        }
+        last = u.UpdatedAt
+    }
+
+    time.Sleep(2 * time.Millisecond)
+    updated := &model.User{ID: user.ID, Name: "Johnny", Age: 30, CreatedAt: time.Unix(1, 0)}
+    if err := repo.Update(ctx, updated); err != nil {
+        t.Fatalf("Update() error = %v", err)
+    }
+    assertUpdated("Update()", updated)
+
+    time.Sleep(2 * time.Millisecond)
+    patched, err := repo.Patch(ctx, user.ID, model.UserPatch{Age: intPtr(31)})
+    if err != nil {
+        t.Fatalf("Patch() error = %v", err)
+    }
+    assertUpdated("Patch()", patched)
+
+    // Пустой патч не меняет updated_at
+    unchanged, err := repo.Patch(ctx, user.ID, model.UserPatch{})
+    if err != nil {
+        t.Fatalf("Patch() error = %v", err)
+    }
+    if !unchanged.UpdatedAt.Equal(last) {
+        t.Errorf("empty Patch() updated_at = %v, want %v", unchanged.UpdatedAt, last)
+    }
+
+    time.Sleep(2 * time.Millisecond)
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    page, err := repo.GetAll(ctx, model.UserQuery{IncludeDeleted: true})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    deleted := page.Users[0]
+    assertUpdated("Delete()", &deleted)
+    if deleted.DeletedAt == nil || !deleted.DeletedAt.Equal(deleted.UpdatedAt) {
+        t.Errorf("Delete() deleted_at = %v, want updated_at %v", deleted.DeletedAt, deleted.UpdatedAt)
+    }
+
+    time.Sleep(2 * time.Millisecond)
+    restored, err := repo.Restore(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("Restore() error = %v", err)
+    }
+    assertUpdated("Restore()", restored)
+
+    got, err := repo.GetByID(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if *got != *restored {
+        t.Errorf("GetByID() = %+v, want %+v", *got, *restored)
+    }
+}
+
+func testDelete(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+
+    user := mustCreate(t, repo, "John", 30)
+    other := mustCreate(t, repo, "Ann", 25)
+
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("GetByID() after delete error = %v, want ErrUserNotFound", err)
+    }
+    if err := repo.Delete(ctx, user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("second Delete() error = %v, want ErrUserNotFound", err)
+    }
+    if err := repo.Update(ctx, &model.User{ID: user.ID, Name: "John", Age: 30}); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("Update() after delete error = %v, want ErrUserNotFound", err)
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    if page.Total != 1 || len(page.Users) != 1 || page.Users[0] != *other {
+        t.Errorf("GetAll() after delete = %+v, want only %+v", page, *other)
+    }
+
+    // Удаленный id не переиспользуется
+    created := mustCreate(t, repo, "Kate", 20)
+    if created.ID == user.ID {
+        t.Errorf("Create() reused deleted ID %s", user.ID)
+    }
+}
+
+func testSoftDelete(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    user := mustCreate(t, repo, "John", 30)
+    mustCreate(t, repo, "Ann", 25)
+
+    before := time.Now().Add(-time.Second)
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+
+    // Удаленный пользователь недоступен для чтения и изменения
+    age := 31
+    if _, err := repo.Patch(ctx, user.ID, model.UserPatch{Age: &age}); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("Patch() of deleted user error = %v, want ErrUserNotFound", err)
+    }
+    if err := repo.Update(ctx, &model.User{ID: user.ID, Name: "John", Age: 31, Version: 2}); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("conditional Update() of deleted user error = %v, want ErrUserNotFound", err)
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    assertNames(t, page.Users, []string{"Ann"})
+    if page.Total != 1 {
+        t.Errorf("GetAll() total = %d, want 1", page.Total)
+    }
+
+    page, err = repo.GetAll(ctx, model.UserQuery{IncludeDeleted: true})
+    if err != nil {
+        t.Fatalf("GetAll(include deleted) error = %v", err)
+    }
+    assertNames(t, page.Users, []string{"John", "Ann"})
+    if page.Total != 2 {
+        t.Errorf("GetAll(include deleted) total = %d, want 2", page.Total)
+    }
+    deleted := page.Users[0]
+    if deleted.DeletedAt == nil {
+        t.Fatal("deleted user has no DeletedAt")
+    }
+    if deleted.DeletedAt.Before(before) || deleted.DeletedAt.After(time.Now().Add(time.Second)) {
+        t.Errorf("DeletedAt = %v, want about now", deleted.DeletedAt)
+    }
+    if deleted.Version != user.Version+1 {
+        t.Errorf("deleted user version = %d, want %d", deleted.Version, user.Version+1)
+    }
+    if page.Users[1].DeletedAt != nil {
+        t.Errorf("active user DeletedAt = %v, want nil", page.Users[1].DeletedAt)
+    }
+}
+
+func testRestore(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    user := mustCreate(t, repo, "John", 30)
+
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    restored, err := repo.Restore(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("Restore() error = %v", err)
+    }
+    want := model.User{ID: user.ID, Name: "John", Age: 30, Version: 3}
+    if withoutTimestamps(*restored) != want {
+        t.Errorf("Restore() = %+v, want %+v", *restored, want)
+    }
+
+    got, err := repo.GetByID(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("GetByID() after restore error = %v", err)
+    }
+    if withoutTimestamps(*got) != want {
+        t.Errorf("GetByID() after restore = %+v, want %+v", *got, want)
+    }
+
+    // Повторное восстановление ничего не меняет
+    again, err := repo.Restore(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("second Restore() error = %v", err)
+    }
+    if withoutTimestamps(*again) != want {
+        t.Errorf("second Restore() = %+v, want %+v", *again, want)
+    }
+
+    for _, id := range []string{"999999", "abc"} {
+        if _, err := repo.Restore(ctx, id); !errors.Is(err, repository.ErrUserNotFound) {
+            t.Errorf("Restore(%q) error = %v, want ErrUserNotFound", id, err)
+        }
+    }
+}
+
+func testPurge(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    user := mustCreate(t, repo, "John", 30)
+
+    if err := repo.Purge(ctx, user.ID); !errors.Is(err, repository.ErrUserNotDeleted) {
+        t.Fatalf("Purge() of active user error = %v, want ErrUserNotDeleted", err)
+    }
+
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if err := repo.Purge(ctx, user.ID); err != nil {
+        t.Fatalf("Purge() error = %v", err)
+    }
+    if _, err := repo.Restore(ctx, user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("Restore() after purge error = %v, want ErrUserNotFound", err)
+    }
+    if err := repo.Purge(ctx, user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("second Purge() error = %v, want ErrUserNotFound", err)
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{IncludeDeleted: true})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    if page.Total != 0 {
+        t.Errorf("GetAll(include deleted) after purge total = %d, want 0", page.Total)
+    }
+}
+
+func testPurgeDeleted(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    deleted := mustCreate(t, repo, "John", 30)
+    mustCreate(t, repo, "Ann", 25)
+    if err := repo.Delete(ctx, deleted.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+
+    // Удален позже границы - остается
+    purged, err := repo.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+    if err != nil {
+        t.Fatalf("PurgeDeleted() error = %v", err)
+    }
+    if purged != 0 {
+        t.Errorf("PurgeDeleted() purged %d users, want 0", purged)
+    }
+    if _, err := repo.Restore(ctx, deleted.ID); err != nil {
+        t.Fatalf("Restore() error = %v", err)
+    }
+    if err := repo.Delete(ctx, deleted.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+
+    purged, err = repo.PurgeDeleted(ctx, time.Now().Add(time.Hour))
+    if err != nil {
+        t.Fatalf("PurgeDeleted() error = %v", err)
+    }
+    if purged != 1 {
+        t.Errorf("PurgeDeleted() purged %d users, want 1", purged)
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{IncludeDeleted: true})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    assertNames(t, page.Users, []string{"Ann"})
+}
+
+func testNotFound(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    mustCreate(t, repo, "John", 30)
+
+    // Несуществующие, нечисловые и выходящие за диапазон id неотличимы от отсутствующих
+    for _, id := range []string{"999999", "0", "-1", "abc", "", "1.5", "99999999999999999999"} {
+        t.Run(fmt.Sprintf("id=%q", id), func(t *testing.T) {
+            if _, err := repo.GetByID(ctx, id); !errors.Is(err, repository.ErrUserNotFound) {
+                t.Errorf("GetByID() error = %v, want ErrUserNotFound", err)
+            }
+            if err := repo.Update(ctx, &model.User{ID: id, Name: "John", Age: 30}); !errors.Is(err, repository.ErrUserNotFound) {
+                t.Errorf("Update() error = %v, want ErrUserNotFound", err)
+            }
+            if err := repo.Delete(ctx, id); !errors.Is(err, repository.ErrUserNotFound) {
+                t.Errorf("Delete() error = %v, want ErrUserNotFound", err)
+            }
+        })
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    if page.Total != 1 {
+        t.Errorf("GetAll() total = %d, want 1: Update() must not create users", page.Total)
+    }
+
+    // Пустое хранилище возвращает пустой список, а не nil
+    empty, err := repo.GetAll(ctx, model.UserQuery{NamePrefix: "Nobody"})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    if empty.Users == nil || len(empty.Users) != 0 || empty.Total != 0 || empty.NextCursor != "" {
+        t.Errorf("GetAll() with no matches = %+v, want empty page", empty)
+    }
+}
+
+func testOrdering(t *testing.T, repo repository.UserRepository) {
+    seed(t, repo)
+
+    tests := []struct {
+        sort string
+        want []string
+    }{
+        {"", []string{"Kate", "Bob", "Ann", "Bill", "Ben"}},
+        {model.SortByID, []string{"Kate", "Bob", "Ann", "Bill", "Ben"}},
+        {model.SortByIDDesc, []string{"Ben", "Bill", "Ann", "Bob", "Kate"}},
+        {model.SortByName, []string{"Ann", "Ben", "Bill", "Bob", "Kate"}},
+        {model.SortByNameDesc, []string{"Kate", "Bob", "Bill", "Ben", "Ann"}},
+        // При равном возрасте порядок определяется id в том же направлении
+        {model.SortByAge, []string{"Ben", "Bob", "Ann", "Bill", "Kate"}},
+        {model.SortByAgeDesc, []string{"Kate", "Bill", "Ann", "Bob", "Ben"}},
+    }
+
+    for _, tt := range tests {
+        t.Run("sort="+tt.sort, func(t *testing.T) {
+            page, err := repo.GetAll(context.Background(), model.UserQuery{Sort: tt.sort})
+            if err != nil {
+                t.Fatalf("GetAll() error = %v", err)
+            }
+            assertNames(t, page.Users, tt.want)
+        })
+    }
+}
+
+func testPagination(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    seed(t, repo)
+
+    for _, sort := range []string{model.SortByID, model.SortByNameDesc, model.SortByAge, model.SortByAgeDesc} {
+        t.Run("sort="+sort, func(t *testing.T) {
+            full, err := repo.GetAll(ctx, model.UserQuery{Sort: sort})
+            if err != nil {
+                t.Fatalf("GetAll() error = %v", err)
+            }
+
+            var names []string
+            query := model.UserQuery{Limit: 2, Sort: sort}
+            for pages := 0; ; pages++ {
+                if pages > len(full.Users) {
+                    t.Fatal("pagination does not terminate")
+                }
+                page, err := repo.GetAll(ctx, query)
+                if err != nil {
+                    t.Fatalf("GetAll() error = %v", err)
+                }
+                if page.Total != len(full.Users) {
+                    t.Errorf("GetAll() total = %d, want %d", page.Total, len(full.Users))
+                }
+                if len(page.Users) > query.Limit {
+                    t.Errorf("GetAll() returned %d users, limit is %d", len(page.Users), query.Limit)
+                }
+                for _, u := range page.Users {
+                    names = append(names, u.Name)
+                }
+                if page.NextCursor == "" {
+                    break
+                }
+                query.Cursor = page.NextCursor
+            }
+
+            // Постраничный обход совпадает с выборкой одной страницей
+            assertNames(t, full.Users, names)
+        })
+    }
+
+    // Записи, добавленные во время обхода, не сдвигают уже выданные страницы
+    page, err := repo.GetAll(ctx, model.UserQuery{Limit: 2})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    mustCreate(t, repo, "Zed", 50)
+    next, err := repo.GetAll(ctx, model.UserQuery{Limit: 2, Cursor: page.NextCursor})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    assertNames(t, next.Users, []string{"Ann", "Bill"})
+}
+
+func testFilters(t *testing.T, repo repository.UserRepository) {
+    seed(t, repo)
+    mustCreate(t, repo, "B%_x", 33)
+
+    tests := []struct {
+        name  string
+        query model.UserQuery
+        want  []string
+    }{
+        {"name prefix", model.UserQuery{NamePrefix: "B"}, []string{"Bob", "Bill", "Ben", "B%_x"}},
+        {"name prefix is case sensitive", model.UserQuery{NamePrefix: "b"}, []string{}},
+        {"name prefix escapes wildcards", model.UserQuery{NamePrefix: "B%"}, []string{"B%_x"}},
+        {"name prefix with underscore", model.UserQuery{NamePrefix: "B_"}, []string{}},
+        {"min age", model.UserQuery{MinAge: intPtr(30)}, []string{"Kate", "Ann", "Bill", "B%_x"}},
+        {"max age", model.UserQuery{MaxAge: intPtr(25)}, []string{"Bob", "Ben"}},
+        {"age range", model.UserQuery{MinAge: intPtr(25), MaxAge: intPtr(30)}, []string{"Bob", "Ann", "Bill"}},
+        {"combined", model.UserQuery{NamePrefix: "B", MinAge: intPtr(20), Sort: model.SortByAgeDesc}, []string{"B%_x", "Bill", "Bob"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            page, err := repo.GetAll(context.Background(), tt.query)
+            if err != nil {
+                t.Fatalf("GetAll() error = %v", err)
+            }
+            if page.Total != len(tt.want) {
+                t.Errorf("GetAll() total = %d, want %d", page.Total, len(tt.want))
+            }
+            assertNames(t, page.Users, tt.want)
+        })
+    }
+}
+
+func testInvalidQuery(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    seed(t, repo)
+
+    page, err := repo.GetAll(ctx, model.UserQuery{Limit: 2, Sort: model.SortByName})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+
+    tests := []struct {
+        name    string
+        query   model.UserQuery
+        wantErr error
+    }{
+        {"garbage cursor", model.UserQuery{Cursor: "garbage"}, repository.ErrInvalidCursor},
+        {"cursor from another sort", model.UserQuery{Cursor: page.NextCursor, Sort: model.SortByAge}, repository.ErrInvalidCursor},
+        {"unknown sort", model.UserQuery{Sort: "email"}, model.ErrInvalidQuery},
+        {"limit too large", model.UserQuery{Limit: model.MaxUserListLimit + 1}, model.ErrInvalidQuery},
+        {"inverted age range", model.UserQuery{MinAge: intPtr(40), MaxAge: intPtr(20)}, model.ErrInvalidQuery},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := repo.GetAll(ctx, tt.query); !errors.Is(err, tt.wantErr) {
+                t.Errorf("GetAll() error = %v, want %v", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func testExport(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    seed(t, repo)
+    if err := repo.Delete(ctx, "2"); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+
+    export := func(query model.UserQuery) []model.User {
+        t.Helper()
+        var users []model.User
+        if err := repo.Export(ctx, query, func(u model.User) error {
+            users = append(users, u)
+            return nil
+        }); err != nil {
+            t.Fatalf("Export() error = %v", err)
+        }
+        return users
+    }
+
+    // Limit не ограничивает выгрузку, фильтры и сортировка как у списка
+    assertNames(t, export(model.UserQuery{Limit: 1}), []string{"Kate", "Ann", "Bill", "Ben"})
+    assertNames(t, export(model.UserQuery{Sort: model.SortByAgeDesc, MinAge: intPtr(20)}), []string{"Kate", "Bill", "Ann"})
+    assertNames(t, export(model.UserQuery{NamePrefix: "B", IncludeDeleted: true}), []string{"Bob", "Bill", "Ben"})
+    assertNames(t, export(model.UserQuery{NamePrefix: "Z"}), []string{})
+
+    // Ошибка fn прерывает выгрузку
+    stop := errors.New("stop")
+    calls := 0
+    err := repo.Export(ctx, model.UserQuery{}, func(model.User) error {
+        calls++
+        return stop
+    })
+    if !errors.Is(err, stop) || calls != 1 {
+        t.Errorf("Export() error = %v after %d calls, want stop after 1", err, calls)
+    }
+
+    if err := repo.Export(ctx, model.UserQuery{Sort: "height"}, func(model.User) error { return nil }); !errors.Is(err, model.ErrInvalidQuery) {
+        t.Errorf("Export() error = %v, want ErrInvalidQuery", err)
+    }
+}
+
+func testSearch(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    for _, u := range []*model.User{
+        {Name: "Alexander Smith", Age: 30},
+        {Name: "Anna Lee", Age: 25},
+        {Name: "Ann Taylor", Age: 41},
+        {Name: "Mary Jones", Age: 35, Email: "mjones@example.com"},
+        {Name: "Tom & Jerry", Age: 20},
+        {Name: "Annabel", Age: 50},
+    } {
+        if err := repo.Create(ctx, u); err != nil {
+            t.Fatalf("Create(%s) error = %v", u.Name, err)
+        }
+    }
+    // Удаленные пользователи не находятся
+    if err := repo.Delete(ctx, "6"); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+
+    search := func(q string, limit int) []model.SearchResult {
+        t.Helper()
+        results, err := repo.Search(ctx, model.SearchQuery{Q: q, Limit: limit})
+        if err != nil {
+            t.Fatalf("Search(%q) error = %v", q, err)
+        }
+        return results
+    }
+    users := func(results []model.SearchResult) []model.User {
+        users := make([]model.User, len(results))
+        for i, r := range results {
+            users[i] = r.User
+        }
+        return users
+    }
+
+    // Префикс слова, точное слово похоже на запрос больше
+    results := search("ann", 0)
+    assertNames(t, users(results), []string{"Ann Taylor", "Anna Lee"})
+    if t.Failed() {
+        return
+    }
+    if results[0].Score < results[1].Score {
+        t.Errorf("Search() scores = %v, %v, want descending", results[0].Score, results[1].Score)
+    }
+    if got := results[1].Highlights["name"]; got != "<mark>Ann</mark>a Lee" {
+        t.Errorf("Search() name highlight = %q", got)
+    }
+    assertNames(t, users(search("ann", 1)), []string{"Ann Taylor"})
+
+    // Все слова запроса должны совпасть
+    assertNames(t, users(search("anna LEE", 0)), []string{"Anna Lee"})
+
+    // Опечатка находится по похожести имени
+    assertNames(t, users(search("Alexandr", 0)), []string{"Alexander Smith"})
+
+    // Поиск по email
+    results = search("mjones", 0)
+    assertNames(t, users(results), []string{"Mary Jones"})
+    if t.Failed() {
+        return
+    }
+    if want := map[string]string{"email": "<mark>mjones</mark>@example.com"}; !reflect.DeepEqual(results[0].Highlights, want) {
+        t.Errorf("Search() highlights = %v, want %v", results[0].Highlights, want)
+    }
+    if results[0].User.Email != "mjones@example.com" || results[0].User.Version != 1 {
+        t.Errorf("Search() user = %+v", results[0].User)
+    }
+
+    // Подсвечиваются только начала слов, по которым найден пользователь
+    results = search("jones", 0)
+    assertNames(t, users(results), []string{"Mary Jones"})
+    if t.Failed() {
+        return
+    }
+    if want := map[string]string{"name": "Mary <mark>Jones</mark>"}; !reflect.DeepEqual(results[0].Highlights, want) {
+        t.Errorf("Search() highlights = %v, want %v", results[0].Highlights, want)
+    }
+
+    // Домен email - отдельные слова
+    results = search("example", 0)
+    assertNames(t, users(results), []string{"Mary Jones"})
+    if t.Failed() {
+        return
+    }
+    if want := map[string]string{"email": "mjones@<mark>example</mark>.com"}; !reflect.DeepEqual(results[0].Highlights, want) {
+        t.Errorf("Search() highlights = %v, want %v", results[0].Highlights, want)
+    }
+
+    // Текст подсветки экранирован как HTML
+    results = search("tom", 0)
+    assertNames(t, users(results), []string{"Tom & Jerry"})
+    if t.Failed() {
+        return
+    }
+    if got := results[0].Highlights["name"]; got != "<mark>Tom</mark> &amp; Jerry" {
+        t.Errorf("Search() name highlight = %q", got)
+    }
+
+    assertNames(t, users(search("zzz", 0)), []string{})
+    if _, err := repo.Search(ctx, model.SearchQuery{Q: "  "}); !errors.Is(err, model.ErrInvalidQuery) {
+        t.Errorf("Search() with empty query error = %v, want ErrInvalidQuery", err)
+    }
+    if _, err := repo.Search(ctx, model.SearchQuery{Q: "ann", Limit: model.MaxSearchLimit + 1}); !errors.Is(err, model.ErrInvalidQuery) {
+        t.Errorf("Search() with large limit error = %v, want ErrInvalidQuery", err)
+    }
+}
+
+func testConcurrentCreate(t *testing.T, repo repository.UserRepository) {
+    const workers = 20
+    ctx := context.Background()
+
+    var wg sync.WaitGroup
+    ids := make([]string, workers)
+    errs := make([]error, workers)
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            user := &model.User{Name: fmt.Sprintf("User %02d", i), Age: 20 + i}
+            errs[i] = repo.Create(ctx, user)
+            ids[i] = user.ID
+        }(i)
+    }
+    wg.Wait()
+
+    seen := make(map[string]bool, workers)
+    for i, err := range errs {
+        if err != nil {
+            t.Fatalf("concurrent Create() error = %v", err)
+        }
+        if seen[ids[i]] {
+            t.Errorf("concurrent Create() assigned duplicate ID %s", ids[i])
+        }
+        seen[ids[i]] = true
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{Limit: model.MaxUserListLimit})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    if page.Total != workers || len(page.Users) != workers {
+        t.Errorf("GetAll() returned %d of %d users, want %d", len(page.Users), page.Total, workers)
+    }
+}
+
+func testConcurrentUpdateDelete(t *testing.T, repo repository.UserRepository) {
+    const workers = 10
+    ctx := context.Background()
+    user := mustCreate(t, repo, "John", 30)
+
+    var wg sync.WaitGroup
+    var mu sync.Mutex
+    deleted := 0
+    for i := 0; i < workers; i++ {
+        wg.Add(2)
+        go func(i int) {
+            defer wg.Done()
+            err := repo.Update(ctx, &model.User{ID: user.ID, Name: "John", Age: 30 + i})
+            if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+                t.Errorf("concurrent Update() error = %v", err)
+            }
+        }(i)
+        go func() {
+            defer wg.Done()
+            err := repo.Delete(ctx, user.ID)
+            if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+                t.Errorf("concurrent Delete() error = %v", err)
+                return
+            }
+            if err == nil {
+                mu.Lock()
+                deleted++
+                mu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+
+    // Удаление успешно ровно один раз, после него запись недоступна
+    if deleted != 1 {
+        t.Errorf("concurrent Delete() succeeded %d times, want 1", deleted)
+    }
+    if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+        t.Errorf("GetByID() after concurrent delete error = %v, want ErrUserNotFound", err)
+    }
+}
+
+func testConcurrentConditionalUpdate(t *testing.T, repo repository.UserRepository) {
+    const workers = 10
+    ctx := context.Background()
+    user := mustCreate(t, repo, "John", 30)
+
+    var wg sync.WaitGroup
+    errs := make([]error, workers)
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            errs[i] = repo.Update(ctx, &model.User{ID: user.ID, Name: "John", Age: 30 + i, Version: user.Version})
+        }(i)
+    }
+    wg.Wait()
+
+    // Из клиентов, прочитавших одну версию, изменение применяет только один
+    succeeded := 0
+    for _, err := range errs {
+        switch {
+        case err == nil:
+            succeeded++
+        case !errors.Is(err, repository.ErrVersionMismatch):
+            t.Errorf("concurrent Update() error = %v, want nil or ErrVersionMismatch", err)
+        }
+    }
+    if succeeded != 1 {
+        t.Errorf("concurrent conditional Update() succeeded %d times, want 1", succeeded)
+    }
+
+    got, err := repo.GetByID(ctx, user.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if got.Version != user.Version+1 {
+        t.Errorf("GetByID() version = %d, want %d", got.Version, user.Version+1)
+    }
+}
+
+func testCanceledContext(t *testing.T, repo repository.UserRepository) {
+    user := mustCreate(t, repo, "John", 30)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    if _, err := repo.GetAll(ctx, model.UserQuery{}); !errors.Is(err, context.Canceled) {
+        t.Errorf("GetAll() error = %v, want context.Canceled", err)
+    }
+    if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, context.Canceled) {
+        t.Errorf("GetByID() error = %v, want context.Canceled", err)
+    }
+    if err := repo.Create(ctx, &model.User{Name: "Ann", Age: 25}); !errors.Is(err, context.Canceled) {
+        t.Errorf("Create() error = %v, want context.Canceled", err)
+    }
+    if err := repo.Delete(ctx, user.ID); !errors.Is(err, context.Canceled) {
+        t.Errorf("Delete() error = %v, want context.Canceled", err)
+    }
+
+    // Отмененные операции ничего не изменили
+    page, err := repo.GetAll(context.Background(), model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    assertNames(t, page.Users, []string{"John"})
+}
+
+func testCreateMany(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+
+    // Больше, чем помещается в один INSERT
+    users := make([]*model.User, 1203)
+    for i := range users {
+        users[i] = &model.User{Name: fmt.Sprintf("User %04d", i), Age: i % 100}
+    }
+    if err := repo.CreateMany(ctx, users); err != nil {
+        t.Fatalf("CreateMany() error = %v", err)
+    }
+
+    for _, i := range []int{0, 499, 500, 1202} {
+        got, err := repo.GetByID(ctx, users[i].ID)
+        if err != nil {
+            t.Fatalf("GetByID(%s) error = %v", users[i].ID, err)
+        }
+        if got.Name != users[i].Name || got.Version != 1 || users[i].Version != 1 {
+            t.Errorf("user %d = %+v, stored %+v", i, *users[i], *got)
+        }
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{Limit: 1})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    if page.Total != len(users) {
+        t.Errorf("GetAll() total = %d, want %d", page.Total, len(users))
+    }
+    events, err := repo.AuditEvents(ctx, model.AuditQuery{UserID: users[700].ID})
+    if err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    if len(events.Events) != 1 || events.Events[0].Action != model.AuditActionCreate {
+        t.Errorf("AuditEvents(%s) = %+v, want one create event", users[700].ID, events.Events)
+    }
+}
+
+func testBatchAtomic(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    john := mustCreate(t, repo, "John", 30)
+    ann := mustCreate(t, repo, "Ann", 25)
+
+    ops := []model.BatchOperation{
+        {Op: model.BatchOpCreate, User: &model.User{Name: "Kate", Age: 41}},
+        {Op: model.BatchOpUpdate, ID: john.ID, Version: john.Version, User: &model.User{Name: "Johnny", Age: 31}},
+        {Op: model.BatchOpDelete, ID: ann.ID},
+        {Op: model.BatchOpCreate, User: &model.User{Name: "Bob", Age: 25}},
+    }
+    results, err := repo.Batch(ctx, ops, true)
+    if err != nil {
+        t.Fatalf("Batch() error = %v", err)
+    }
+    for i, result := range results {
+        if result.Err != nil {
+            t.Fatalf("operation %d error = %v", i, result.Err)
+        }
+    }
+    if u := results[0].User; u == nil || u.ID == "" || u.Version != 1 {
+        t.Errorf("create result = %+v, want new user", u)
+    }
+    if u := results[1].User; u == nil || u.Name != "Johnny" || u.Version != john.Version+1 {
+        t.Errorf("update result = %+v, want Johnny with next version", u)
+    }
+    page, err := repo.GetAll(ctx, model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    assertNames(t, page.Users, []string{"Johnny", "Kate", "Bob"})
+
+    // Ошибка в середине пакета отменяет и предыдущие, и последующие операции
+    ops = []model.BatchOperation{
+        {Op: model.BatchOpCreate, User: &model.User{Name: "Bill", Age: 30}},
+        {Op: model.BatchOpUpdate, ID: john.ID, Version: john.Version, User: &model.User{Name: "John", Age: 30}},
+        {Op: model.BatchOpDelete, ID: results[0].User.ID},
+    }
+    before, err := repo.AuditEvents(ctx, model.AuditQuery{})
+    if err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    results, err = repo.Batch(ctx, ops, true)
+    if err != nil {
+        t.Fatalf("Batch() error = %v", err)
+    }
+    wantErrs := []error{model.ErrBatchAborted, repository.ErrVersionMismatch, model.ErrBatchAborted}
+    for i, want := range wantErrs {
+        if !errors.Is(results[i].Err, want) {
+            t.Errorf("operation %d error = %v, want %v", i, results[i].Err, want)
+        }
+    }
+    page, err = repo.GetAll(ctx, model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    assertNames(t, page.Users, []string{"Johnny", "Kate", "Bob"})
+    after, err := repo.AuditEvents(ctx, model.AuditQuery{})
+    if err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    if len(after.Events) != len(before.Events) {
+        t.Errorf("aborted batch recorded %d audit events", len(after.Events)-len(before.Events))
+    }
+}
+
+func testBatchBestEffort(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    john := mustCreate(t, repo, "John", 30)
+
+    ops := []model.BatchOperation{
+        {Op: model.BatchOpCreate, User: &model.User{Name: "Kate", Age: 41}},
+        {Op: model.BatchOpCreate, User: &model.User{Name: "Bob", Age: 25}},
+        {Op: model.BatchOpUpdate, ID: "999999", User: &model.User{Name: "Nobody", Age: 1}},
+        {Op: model.BatchOpUpdate, ID: john.ID, Version: john.Version + 1, User: &model.User{Name: "Johnny", Age: 31}},
+        {Op: model.BatchOpDelete, ID: john.ID},
+        {Op: model.BatchOpDelete, ID: john.ID},
+        {Op: model.BatchOpCreate, User: &model.User{Name: "Ann", Age: 25}},
+    }
+    results, err := repo.Batch(ctx, ops, false)
+    if err != nil {
+        t.Fatalf("Batch() error = %v", err)
+    }
+    wantErrs := []error{nil, nil, repository.ErrUserNotFound, repository.ErrVersionMismatch, nil, repository.ErrUserNotFound, nil}
+    if len(results) != len(wantErrs) {
+        t.Fatalf("Batch() returned %d results, want %d", len(results), len(wantErrs))
+    }
+    for i, want := range wantErrs {
+        if !errors.Is(results[i].Err, want) {
+            t.Errorf("operation %d error = %v, want %v", i, results[i].Err, want)
+        }
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    assertNames(t, page.Users, []string{"Kate", "Bob", "Ann"})
+}
+
+func testAudit(t *testing.T, repo repository.UserRepository) {
+    ctx := actor.NewContext(requestid.NewContext(context.Background(), "req-1"), "alice")
+
+    user := &model.User{Name: "John", Age: 30}
+    if err := repo.Create(ctx, user); err != nil {
+        t.Fatalf("Create() error = %v", err)
+    }
+    if err := repo.Update(ctx, &model.User{ID: user.ID, Name: "Johnny", Age: 30}); err != nil {
+        t.Fatalf("Update() error = %v", err)
+    }
+    if _, err := repo.Patch(ctx, user.ID, model.UserPatch{Age: intPtr(31)}); err != nil {
+        t.Fatalf("Patch() error = %v", err)
+    }
+    // Неудачные и пустые изменения в журнал не попадают
+    if err := repo.Update(ctx, &model.User{ID: user.ID, Name: "John", Age: 30, Version: 1}); !errors.Is(err, repository.ErrVersionMismatch) {
+        t.Fatalf("Update() error = %v, want ErrVersionMismatch", err)
+    }
+    if _, err := repo.Patch(ctx, user.ID, model.UserPatch{}); err != nil {
+        t.Fatalf("Patch() error = %v", err)
+    }
+    if _, err := repo.Restore(ctx, user.ID); err != nil {
+        t.Fatalf("Restore() error = %v", err)
+    }
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if _, err := repo.Restore(ctx, user.ID); err != nil {
+        t.Fatalf("Restore() error = %v", err)
+    }
+    if err := repo.Delete(ctx, user.ID); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if err := repo.Purge(actor.NewContext(context.Background(), actor.System), user.ID); err != nil {
+        t.Fatalf("Purge() error = %v", err)
+    }
+    other := mustCreate(t, repo, "Ann", 25)
+
+    // История доступна и после окончательного удаления пользователя
+    page, err := repo.AuditEvents(context.Background(), model.AuditQuery{UserID: user.ID})
+    if err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    wantActions := []string{
+        model.AuditActionCreate,
+        model.AuditActionUpdate,
+        model.AuditActionUpdate,
+        model.AuditActionDelete,
+        model.AuditActionRestore,
+        model.AuditActionDelete,
+        model.AuditActionPurge,
+    }
+    if len(page.Events) != len(wantActions) {
+        t.Fatalf("AuditEvents() returned %d events, want %d: %+v", len(page.Events), len(wantActions), page.Events)
+    }
+    for i, e := range page.Events {
+        if e.Action != wantActions[i] {
+            t.Errorf("event %d action = %q, want %q", i, e.Action, wantActions[i])
+        }
+        if e.UserID != user.ID {
+            t.Errorf("event %d user_id = %q, want %q", i, e.UserID, user.ID)
+        }
+        if e.CreatedAt.IsZero() {
+            t.Errorf("event %d created_at is zero", i)
+        }
+    }
+
+    create, update, patch, purge := page.Events[0], page.Events[1], page.Events[2], page.Events[6]
+    if create.Actor != "alice" || create.RequestID != "req-1" {
+        t.Errorf("create actor = %q, request_id = %q, want alice, req-1", create.Actor, create.RequestID)
+    }
+    if purge.Actor != actor.System || purge.RequestID != "" {
+        t.Errorf("purge actor = %q, request_id = %q, want %q and empty", purge.Actor, purge.RequestID, actor.System)
+    }
+
+    assertAuditFields(t, "create before", create.Before, nil)
+    assertAuditFields(t, "create after", create.After, map[string]interface{}{"name": "John", "age": 30.0, "email": nil, "deleted_at": nil})
+    // При изменении сохраняются только измененные поля
+    assertAuditFields(t, "update before", update.Before, map[string]interface{}{"name": "John"})
+    assertAuditFields(t, "update after", update.After, map[string]interface{}{"name": "Johnny"})
+    assertAuditFields(t, "patch before", patch.Before, map[string]interface{}{"age": 30.0})
+    assertAuditFields(t, "patch after", patch.After, map[string]interface{}{"age": 31.0})
+    assertAuditFields(t, "purge after", purge.After, nil)
+    if fields := decodeAuditFields(t, purge.Before); fields["name"] != "Johnny" || fields["deleted_at"] == nil {
+        t.Errorf("purge before = %s, want full snapshot of deleted user", purge.Before)
+    }
+
+    page, err = repo.AuditEvents(context.Background(), model.AuditQuery{UserID: other.ID})
+    if err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    if len(page.Events) != 1 || page.Events[0].Actor != actor.Anonymous {
+        t.Errorf("AuditEvents(%s) = %+v, want one event by %q", other.ID, page.Events, actor.Anonymous)
+    }
+}
+
+func testAuditQuery(t *testing.T, repo repository.UserRepository) {
+    ctx := context.Background()
+    seed(t, repo)
+
+    // Постраничный обход возвращает все события ровно один раз и по порядку
+    var (
+        ids    []string
+        cursor string
+    )
+    for pages := 0; ; pages++ {
+        if pages > 5 {
+            t.Fatal("pagination did not terminate")
+        }
+        page, err := repo.AuditEvents(ctx, model.AuditQuery{Limit: 2, Cursor: cursor})
+        if err != nil {
+            t.Fatalf("AuditEvents() error = %v", err)
+        }
+        for _, e := range page.Events {
+            ids = append(ids, e.ID)
+        }
+        if page.NextCursor == "" {
+            break
+        }
+        cursor = page.NextCursor
+    }
+    if len(ids) != 5 {
+        t.Fatalf("paginated AuditEvents() returned %d events, want 5", len(ids))
+    }
+    for i := 1; i < len(ids); i++ {
+        prev, _ := strconv.ParseInt(ids[i-1], 10, 64)
+        cur, _ := strconv.ParseInt(ids[i], 10, 64)
+        if cur <= prev {
+            t.Errorf("event ids are not increasing: %v", ids)
+            break
+        }
+    }
+
+    page, err := repo.AuditEvents(ctx, model.AuditQuery{})
+    if err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    last := page.Events[len(page.Events)-1]
+
+    // since включает события, записанные ровно в этот момент
+    since := last.CreatedAt
+    if page, err = repo.AuditEvents(ctx, model.AuditQuery{Since: &since}); err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    if len(page.Events) == 0 || page.Events[len(page.Events)-1].ID != last.ID {
+        t.Errorf("AuditEvents(since=%s) = %+v, want to end with event %s", since, page.Events, last.ID)
+    }
+    since = last.CreatedAt.Add(time.Second)
+    if page, err = repo.AuditEvents(ctx, model.AuditQuery{Since: &since}); err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    if len(page.Events) != 0 {
+        t.Errorf("AuditEvents(since=%s) returned %d events, want 0", since, len(page.Events))
+    }
+
+    if _, err := repo.AuditEvents(ctx, model.AuditQuery{Cursor: "abc"}); !errors.Is(err, repository.ErrInvalidCursor) {
+        t.Errorf("AuditEvents(cursor=abc) error = %v, want ErrInvalidCursor", err)
+    }
+    if _, err := repo.AuditEvents(ctx, model.AuditQuery{Limit: model.MaxAuditListLimit + 1}); !errors.Is(err, model.ErrInvalidQuery) {
+        t.Errorf("AuditEvents(limit) error = %v, want ErrInvalidQuery", err)
+    }
+}
+
+// assertAuditFields сравнивает снимок полей события с ожидаемым. nil означает отсутствие снимка.
+func assertAuditFields(t *testing.T, name string, raw json.RawMessage, want map[string]interface{}) {
+    t.Helper()
+
+    if want == nil {
+        if raw != nil {
+            t.Errorf("%s = %s, want none", name, raw)
+        }
+        return
+    }
+    got := decodeAuditFields(t, raw)
+    if len(got) != len(want) {
+        t.Errorf("%s = %s, want %v", name, raw, want)
+        return
+    }
+    for key, value := range want {
+        if got[key] != value {
+            t.Errorf("%s = %s, want %v", name, raw, want)
+            return
+        }
+    }
+}
+
+func decodeAuditFields(t *testing.T, raw json.RawMessage) map[string]interface{} {
+    t.Helper()
+
+    var fields map[string]interface{}
+    if err := json.Unmarshal(raw, &fields); err != nil {
+        t.Fatalf("failed to decode audit fields %q: %v", raw, err)
+    }
+    return fields
+}
+
+// seed создает пользователей в фиксированном порядке. Имена начинаются с
+// заглавной латинской буквы, чтобы порядок не зависел от правил сортировки БД.
+func seed(t *testing.T, repo repository.UserRepository) {
+    t.Helper()
+
+    mustCreate(t, repo, "Kate", 41)
+    mustCreate(t, repo, "Bob", 25)
+    mustCreate(t, repo, "Ann", 30)
+    mustCreate(t, repo, "Bill", 30)
+    mustCreate(t, repo, "Ben", 19)
+}
+
+func mustCreate(t *testing.T, repo repository.UserRepository, name string, age int) *model.User {
+    t.Helper()
+
+    user := &model.User{Name: name, Age: age}
+    if err := repo.Create(context.Background(), user); err != nil {
+        t.Fatalf("Create(%s) error = %v", name, err)
+    }
+    if _, err := strconv.ParseInt(user.ID, 10, 64); err != nil {
+        t.Fatalf("Create(%s) assigned non-numeric ID %q", name, user.ID)
+    }
+    return user
+}
+
+func assertNames(t *testing.T, users []model.User, want []string) {
+    t.Helper()
+
+    got := make([]string, len(users))
+    for i, u := range users {
+        got[i] = u.Name
+    }
+    if len(got) != len(want) {
+        t.Errorf("got users %v, want %v", got, want)
+        return
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("got users %v, want %v", got, want)
+            return
+        }
+    }
+}
+
+// withoutTimestamps обнуляет created_at и updated_at для сравнения с ожидаемым пользователем
+func withoutTimestamps(u model.User) model.User {
+    u.CreatedAt, u.UpdatedAt = time.Time{}, time.Time{}
+    return u
+}
+
+func intPtr(v int) *int {
+    return &v
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+    "context"
+    "html"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode"
+
+    "go-crud-example/internal/model"
+)
+
+// Поиск пользователей. PostgreSQL ищет по tsvector и триграммам pg_trgm, остальные
+// хранилища - перебором с теми же правилами: каждое слово запроса должно быть
+// началом слова в имени или email, либо имя должно быть похоже на запрос.
+
+// similarityThreshold - минимальная триграммная похожесть имени на запрос,
+// как pg_trgm.similarity_threshold по умолчанию
+const similarityThreshold = 0.3
+
+// textMatchScore - вклад полного совпадения слов в релевантность. Близок к
+// значению ts_rank для одного совпадения, чтобы порядок не отличался от PostgreSQL.
+const textMatchScore = 0.1
+
+// searchTerms разбивает строку на слова из букв и цифр в нижнем регистре
+func searchTerms(s string) []string {
+    return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+}
+
+// prefixTSQuery строит tsquery, в котором каждое слово ищется как префикс
+func prefixTSQuery(terms []string) string {
+    parts := make([]string, len(terms))
+    for i, term := range terms {
+        parts[i] = term + ":*"
+    }
+    return strings.Join(parts, " & ")
+}
+
+// trigrams возвращает множество триграмм строки по правилам pg_trgm: каждое
+// слово дополняется двумя пробелами в начале и одним в конце
+func trigrams(s string) map[string]struct{} {
+    set := make(map[string]struct{})
+    for _, word := range searchTerms(s) {
+        runes := []rune("  " + word + " ")
+        for i := 0; i+3 <= len(runes); i++ {
+            set[string(runes[i:i+3])] = struct{}{}
+        }
+    }
+    return set
+}
+
+// similarity - доля общих триграмм, как similarity() в pg_trgm
+func similarity(a, b string) float64 {
+    ta, tb := trigrams(a), trigrams(b)
+    if len(ta) == 0 || len(tb) == 0 {
+        return 0
+    }
+
+    common := 0
+    for t := range ta {
+        if _, ok := tb[t]; ok {
+            common++
+        }
+    }
+    return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// matchesAllTerms сообщает, что каждое слово запроса - начало какого-то слова текста
+func matchesAllTerms(text string, terms []string) bool {
+    words := searchTerms(text)
+    for _, term := range terms {
+        found := false
+        for _, word := range words {
+            if strings.HasPrefix(word, term) {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    return true
+}
+
+// highlight оборачивает в <mark> начала слов текста, совпавшие со словами
+// запроса без учета регистра, - по тому же правилу, по которому ищет запрос.
+// Возвращает false, если совпадений нет.
+func highlight(text string, terms []string) (string, bool) {
+    runes := []rune(text)
+    isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+    marked := make([]bool, len(runes))
+    found := false
+    for start := 0; start < len(runes); {
+        if !isWord(runes[start]) {
+            start++
+            continue
+        }
+        end := start
+        for end < len(runes) && isWord(runes[end]) {
+            end++
+        }
+        word := make([]rune, end-start)
+        for i, r := range runes[start:end] {
+            word[i] = unicode.ToLower(r)
+        }
+        for _, term := range terms {
+            t := []rune(term)
+            if len(t) <= len(word) && string(word[:len(t)]) == term {
+                for j := start; j < start+len(t); j++ {
+                    marked[j] = true
+                }
+                found = true
+            }
+        }
+        start = end
+    }
+    if !found {
+        return "", false
+    }
+
+    var b strings.Builder
+    for i := 0; i < len(runes); {
+        j := i
+        for j < len(runes) && marked[j] == marked[i] {
+            j++
+        }
+        segment := html.EscapeString(string(runes[i:j]))
+        if marked[i] {
+            segment = "<mark>" + segment + "</mark>"
+        }
+        b.WriteString(segment)
+        i = j
+    }
+    return b.String(), true
+}
+
+// newSearchResult заполняет подсветку совпавших полей пользователя
+func newSearchResult(u model.User, score float64, terms []string) model.SearchResult {
+    result := model.SearchResult{User: u, Score: score}
+    fields := map[string]string{"name": u.Name, "email": u.Email}
+    for field, value := range fields {
+        if h, ok := highlight(value, terms); ok {
+            if result.Highlights == nil {
+                result.Highlights = make(map[string]string)
+            }
+            result.Highlights[field] = h
+        }
+    }
+    return result
+}
+
+// naiveSearch ищет перебором всех пользователей, которых передает export.
+// В памяти хранится не больше 2*limit лучших результатов.
+func naiveSearch(ctx context.Context, query model.SearchQuery, export func(ctx context.Context, query model.UserQuery, fn func(model.User) error) error) ([]model.SearchResult, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+    terms := searchTerms(query.Q)
+    results := []model.SearchResult{}
+    if len(terms) == 0 {
+        return results, nil
+    }
+
+    err := export(ctx, model.UserQuery{}, func(u model.User) error {
+        score := similarity(u.Name, query.Q)
+        matched := matchesAllTerms(u.Name+" "+u.Email, terms)
+        if !matched && score < similarityThreshold {
+            return nil
+        }
+        if matched {
+            score += textMatchScore
+        }
+
+        results = append(results, newSearchResult(u, score, terms))
+        if len(results) >= 2*query.Limit {
+            results = rankSearchResults(results, query.Limit)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return rankSearchResults(results, query.Limit), nil
+}
+
+// rankSearchResults упорядочивает результаты по убыванию релевантности, при
+// равенстве - по id, и оставляет первые limit
+func rankSearchResults(results []model.SearchResult, limit int) []model.SearchResult {
+    sort.Slice(results, func(i, j int) bool {
+        if results[i].Score != results[j].Score {
+            return results[i].Score > results[j].Score
+        }
+        a, _ := strconv.ParseInt(results[i].User.ID, 10, 64)
+        b, _ := strconv.ParseInt(results[j].User.ID, 10, 64)
+        return a < b
+    })
+    return results[:min(len(results), limit)]
+}
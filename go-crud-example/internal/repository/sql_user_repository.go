@@ -0,0 +1,701 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "go-crud-example/internal/model"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// maxInsertRows ограничивает число строк в одном INSERT: PostgreSQL и SQLite
+// ограничивают число параметров запроса
+const maxInsertRows = 500
+
+// userEmailIndex - уникальный индекс по LOWER(email), его нарушение означает ErrEmailTaken
+const userEmailIndex = "idx_users_email"
+
+// errBatchRollback откатывает транзакцию атомарного пакета, ошибки операций
+// к этому моменту уже записаны в результаты
+var errBatchRollback = errors.New("batch rolled back")
+
+// sqlUserRepository содержит общую реализацию UserRepository для SQL хранилищ.
+// Запросы совместимы с PostgreSQL и SQLite, различается перевод ошибок драйвера.
+type sqlUserRepository struct {
+    db             *sql.DB
+    translateError func(ctx context.Context, err error) error
+    // lockClause дописывается к SELECT, блокирующему строку в транзакции
+    lockClause string
+}
+
+func (r *sqlUserRepository) GetAll(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+    cursor, err := decodeUserCursor(query.Cursor, query.Sort)
+    if err != nil {
+        return nil, err
+    }
+
+    var total int
+    countQuery, countArgs := buildUserCountQuery(query)
+    if err := r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+
+    listQuery, listArgs := buildUserListQuery(query, cursor)
+    rows, err := r.db.QueryContext(ctx, listQuery, listArgs...)
+    if err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+    defer rows.Close()
+
+    users := []model.User{}
+    for rows.Next() {
+        u, err := scanUser(rows)
+        if err != nil {
+            return nil, err
+        }
+        users = append(users, *u)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+
+    return newUserPage(users, query, total)
+}
+
+// Export читает пользователей прямо из курсора БД, не накапливая их в памяти
+func (r *sqlUserRepository) Export(ctx context.Context, query model.UserQuery, fn func(model.User) error) error {
+    if err := query.Normalize(); err != nil {
+        return err
+    }
+
+    exportQuery, args := buildUserExportQuery(query)
+    rows, err := r.db.QueryContext(ctx, exportQuery, args...)
+    if err != nil {
+        return r.translateError(ctx, err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        u, err := scanUser(rows)
+        if err != nil {
+            return r.translateError(ctx, err)
+        }
+        if err := fn(*u); err != nil {
+            return err
+        }
+    }
+    return r.translateError(ctx, rows.Err())
+}
+
+// Search ищет перебором выгрузки. PostgresUserRepository переопределяет его
+// полнотекстовым поиском.
+func (r *sqlUserRepository) Search(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+    return naiveSearch(ctx, query, r.Export)
+}
+
+func (r *sqlUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
+    if !isValidUserID(id) {
+        return nil, ErrUserNotFound
+    }
+
+    row := r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND deleted_at IS NULL", id)
+    u, err := scanUser(row)
+    if err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+    return u, nil
+}
+
+// Изменения пользователей и записи журнала аудита выполняются в одной
+// транзакции: событие сохраняется тогда и только тогда, когда сохранено изменение.
+
+func (r *sqlUserRepository) Create(ctx context.Context, user *model.User) error {
+    return r.withTx(ctx, func(tx *sql.Tx) error {
+        return r.insertUsers(ctx, tx, []*model.User{user})
+    })
+}
+
+func (r *sqlUserRepository) CreateMany(ctx context.Context, users []*model.User) error {
+    if len(users) == 0 {
+        return nil
+    }
+    return r.withTx(ctx, func(tx *sql.Tx) error {
+        return r.insertUsers(ctx, tx, users)
+    })
+}
+
+func (r *sqlUserRepository) Update(ctx context.Context, user *model.User) error {
+    if !isValidUserID(user.ID) {
+        return ErrUserNotFound
+    }
+
+    return r.withTx(ctx, func(tx *sql.Tx) error {
+        return r.updateUser(ctx, tx, user)
+    })
+}
+
+func (r *sqlUserRepository) Patch(ctx context.Context, id string, patch model.UserPatch) (*model.User, error) {
+    if !isValidUserID(id) {
+        return nil, ErrUserNotFound
+    }
+    if patch.IsEmpty() {
+        return r.GetByID(ctx, id)
+    }
+
+    var updated *model.User
+    err := r.withTx(ctx, func(tx *sql.Tx) error {
+        current, err := r.selectForUpdate(ctx, tx, id, false)
+        if err != nil {
+            return err
+        }
+        if patch.Version > 0 && patch.Version != current.Version {
+            return ErrVersionMismatch
+        }
+
+        query, args := buildUserPatchQuery(id, patch, currentTime())
+        if updated, err = scanUser(tx.QueryRowContext(ctx, query, args...)); err != nil {
+            return err
+        }
+        return r.audit(ctx, tx, model.AuditActionUpdate, current, updated)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return updated, nil
+}
+
+// Delete помечает пользователя удаленным. Запись остается в таблице до Purge.
+func (r *sqlUserRepository) Delete(ctx context.Context, id string) error {
+    if !isValidUserID(id) {
+        return ErrUserNotFound
+    }
+
+    return r.withTx(ctx, func(tx *sql.Tx) error {
+        return r.deleteUser(ctx, tx, id)
+    })
+}
+
+func (r *sqlUserRepository) Restore(ctx context.Context, id string) (*model.User, error) {
+    if !isValidUserID(id) {
+        return nil, ErrUserNotFound
+    }
+
+    var restored *model.User
+    err := r.withTx(ctx, func(tx *sql.Tx) error {
+        current, err := r.selectForUpdate(ctx, tx, id, true)
+        if err != nil {
+            return err
+        }
+        if current.DeletedAt == nil {
+            // Пользователь не удален, восстанавливать нечего
+            restored = current
+            return nil
+        }
+
+        if restored, err = scanUser(tx.QueryRowContext(
+            ctx,
+            "UPDATE users SET deleted_at = NULL, version = version + 1, updated_at = $1 WHERE id = $2 RETURNING "+userColumns,
+            currentTime(),
+            id,
+        )); err != nil {
+            return err
+        }
+        return r.audit(ctx, tx, model.AuditActionRestore, current, restored)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return restored, nil
+}
+
+func (r *sqlUserRepository) Purge(ctx context.Context, id string) error {
+    if !isValidUserID(id) {
+        return ErrUserNotFound
+    }
+
+    return r.withTx(ctx, func(tx *sql.Tx) error {
+        current, err := r.selectForUpdate(ctx, tx, id, true)
+        if err != nil {
+            return err
+        }
+        if current.DeletedAt == nil {
+            return ErrUserNotDeleted
+        }
+
+        if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id); err != nil {
+            return err
+        }
+        return r.audit(ctx, tx, model.AuditActionPurge, current, nil)
+    })
+}
+
+func (r *sqlUserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+    var purged int64
+    err := r.withTx(ctx, func(tx *sql.Tx) error {
+        rows, err := tx.QueryContext(
+            ctx,
+            "DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING "+userColumns,
+            before.UTC(),
+        )
+        if err != nil {
+            return err
+        }
+        users, err := scanUsers(rows)
+        if err != nil {
+            return err
+        }
+
+        for _, u := range users {
+            if err := r.audit(ctx, tx, model.AuditActionPurge, u, nil); err != nil {
+                return err
+            }
+        }
+        purged = int64(len(users))
+        return nil
+    })
+    if err != nil {
+        return 0, err
+    }
+    return purged, nil
+}
+
+func (r *sqlUserRepository) AuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+    after, err := decodeAuditCursor(query.Cursor)
+    if err != nil {
+        return nil, err
+    }
+
+    b := &userQueryBuilder{}
+    if query.UserID != "" {
+        if !isValidUserID(query.UserID) {
+            return &model.AuditPage{Events: []model.AuditEvent{}}, nil
+        }
+        b.where("user_id = " + b.arg(query.UserID))
+    }
+    if query.Since != nil {
+        b.where("created_at >= " + b.arg(query.Since.UTC()))
+    }
+    if after > 0 {
+        b.where("id > " + b.arg(after))
+    }
+
+    rows, err := r.db.QueryContext(
+        ctx,
+        "SELECT "+auditColumns+" FROM audit_events"+b.whereClause()+" ORDER BY id LIMIT "+b.arg(query.Limit+1),
+        b.args...,
+    )
+    if err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+    defer rows.Close()
+
+    events := []model.AuditEvent{}
+    for rows.Next() {
+        event, err := scanAuditEvent(rows)
+        if err != nil {
+            return nil, err
+        }
+        events = append(events, *event)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, r.translateError(ctx, err)
+    }
+
+    return newAuditPage(events, query.Limit), nil
+}
+
+// Batch выполняет операции по порядку в одной транзакции. Каждая операция
+// выполняется в точке сохранения, чтобы ее ошибка откатывала только ее саму.
+func (r *sqlUserRepository) Batch(ctx context.Context, ops []model.BatchOperation, atomic bool) ([]model.BatchResult, error) {
+    results := make([]model.BatchResult, len(ops))
+    err := r.withTx(ctx, func(tx *sql.Tx) error {
+        for start := 0; start < len(ops); {
+            // Идущие подряд создания вставляются одним запросом. Если вставка не
+            // удалась, операции повторяются по одной, чтобы найти ошибочную.
+            end := start + 1
+            for ops[start].Op == model.BatchOpCreate && end < len(ops) && ops[end].Op == model.BatchOpCreate && end-start < maxInsertRows {
+                end++
+            }
+            failed, err := r.applyBatch(ctx, tx, ops[start:end], results[start:end])
+            if err != nil {
+                return err
+            }
+            if failed && end-start > 1 {
+                for i := start; i < end; i++ {
+                    if _, err := r.applyBatch(ctx, tx, ops[i:i+1], results[i:i+1]); err != nil {
+                        return err
+                    }
+                }
+            }
+            if atomic && batchFailed(results[start:end]) {
+                // Ошибка withTx откатывает транзакцию, результаты уже заполнены
+                abortBatch(results)
+                return errBatchRollback
+            }
+            start = end
+        }
+        return nil
+    })
+    if err != nil && !errors.Is(err, errBatchRollback) {
+        return nil, err
+    }
+    return results, nil
+}
+
+// applyBatch выполняет операции в точке сохранения и записывает их результаты.
+// failed сообщает об ошибке операций, err - об ошибке самой транзакции.
+func (r *sqlUserRepository) applyBatch(ctx context.Context, tx *sql.Tx, ops []model.BatchOperation, results []model.BatchResult) (failed bool, err error) {
+    if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_operation"); err != nil {
+        return false, err
+    }
+
+    opErr := r.applyBatchOperations(ctx, tx, ops, results)
+    if opErr != nil {
+        if ctx.Err() != nil {
+            return false, opErr
+        }
+        if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_operation"); err != nil {
+            return false, err
+        }
+        opErr = r.translateError(ctx, opErr)
+        for i := range results {
+            results[i] = model.BatchResult{Err: opErr}
+        }
+    }
+
+    if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_operation"); err != nil {
+        return false, err
+    }
+    return opErr != nil, nil
+}
+
+// applyBatchOperations выполняет либо одну операцию, либо несколько созданий подряд
+func (r *sqlUserRepository) applyBatchOperations(ctx context.Context, tx *sql.Tx, ops []model.BatchOperation, results []model.BatchResult) error {
+    op := ops[0]
+    switch op.Op {
+    case model.BatchOpCreate:
+        users := make([]*model.User, len(ops))
+        for i := range ops {
+            u := *ops[i].User
+            users[i] = &u
+        }
+        if err := r.insertUsers(ctx, tx, users); err != nil {
+            return err
+        }
+        for i, u := range users {
+            results[i] = model.BatchResult{User: u}
+        }
+    case model.BatchOpUpdate:
+        if !isValidUserID(op.ID) {
+            return ErrUserNotFound
+        }
+        u := *op.User
+        u.ID, u.Version, u.DeletedAt = op.ID, op.Version, nil
+        if err := r.updateUser(ctx, tx, &u); err != nil {
+            return err
+        }
+        results[0] = model.BatchResult{User: &u}
+    case model.BatchOpDelete:
+        if !isValidUserID(op.ID) {
+            return ErrUserNotFound
+        }
+        if err := r.deleteUser(ctx, tx, op.ID); err != nil {
+            return err
+        }
+        results[0] = model.BatchResult{}
+    default:
+        return fmt.Errorf("%w: unknown operation %q", model.ErrInvalidBatch, op.Op)
+    }
+    return nil
+}
+
+// insertUsers создает пользователей многострочными INSERT и заполняет их
+// значениями, присвоенными хранилищем
+func (r *sqlUserRepository) insertUsers(ctx context.Context, tx *sql.Tx, users []*model.User) error {
+    createdAt := currentTime()
+    for len(users) > 0 {
+        chunk := users[:min(len(users), maxInsertRows)]
+        users = users[len(chunk):]
+
+        b := &userQueryBuilder{}
+        values := make([]string, len(chunk))
+        for i, u := range chunk {
+            values[i] = "(" + strings.Join([]string{
+                b.arg(u.Name),
+                b.arg(u.Age),
+                b.arg(nullString(u.Email)),
+                b.arg(createdAt),
+                b.arg(createdAt),
+            }, ", ") + ")"
+        }
+        rows, err := tx.QueryContext(
+            ctx,
+            "INSERT INTO users (name, age, email, created_at, updated_at) VALUES "+strings.Join(values, ", ")+" RETURNING "+userColumns,
+            b.args...,
+        )
+        if err != nil {
+            return err
+        }
+        created, err := scanUsers(rows)
+        if err != nil {
+            return err
+        }
+        if len(created) != len(chunk) {
+            return fmt.Errorf("insert returned %d rows, want %d", len(created), len(chunk))
+        }
+
+        // Порядок строк RETURNING не гарантирован, но id выдаются в порядке VALUES
+        sort.Slice(created, func(i, j int) bool {
+            a, _ := strconv.ParseInt(created[i].ID, 10, 64)
+            b, _ := strconv.ParseInt(created[j].ID, 10, 64)
+            return a < b
+        })
+        events := make([]model.AuditEvent, len(created))
+        for i, u := range created {
+            if events[i], err = newAuditEvent(ctx, model.AuditActionCreate, nil, u); err != nil {
+                return err
+            }
+        }
+        if err := r.insertAuditEvents(ctx, tx, events); err != nil {
+            return err
+        }
+
+        for i, u := range chunk {
+            *u = *created[i]
+        }
+    }
+    return nil
+}
+
+// updateUser заменяет данные пользователя, проверяя ожидаемую версию, и
+// заполняет user сохраненными значениями
+func (r *sqlUserRepository) updateUser(ctx context.Context, tx *sql.Tx, user *model.User) error {
+    current, err := r.selectForUpdate(ctx, tx, user.ID, false)
+    if err != nil {
+        return err
+    }
+    if user.Version > 0 && user.Version != current.Version {
+        return ErrVersionMismatch
+    }
+
+    updated, err := scanUser(tx.QueryRowContext(
+        ctx,
+        "UPDATE users SET name = $1, age = $2, email = $3, version = version + 1, updated_at = $4 WHERE id = $5 RETURNING "+userColumns,
+        user.Name,
+        user.Age,
+        nullString(user.Email),
+        currentTime(),
+        user.ID,
+    ))
+    if err != nil {
+        return err
+    }
+    if err := r.audit(ctx, tx, model.AuditActionUpdate, current, updated); err != nil {
+        return err
+    }
+
+    *user = *updated
+    return nil
+}
+
+func (r *sqlUserRepository) deleteUser(ctx context.Context, tx *sql.Tx, id string) error {
+    current, err := r.selectForUpdate(ctx, tx, id, false)
+    if err != nil {
+        return err
+    }
+
+    deleted, err := scanUser(tx.QueryRowContext(
+        ctx,
+        "UPDATE users SET deleted_at = $1, version = version + 1, updated_at = $1 WHERE id = $2 RETURNING "+userColumns,
+        currentTime(),
+        id,
+    ))
+    if err != nil {
+        return err
+    }
+    return r.audit(ctx, tx, model.AuditActionDelete, current, deleted)
+}
+
+// withTx выполняет fn в транзакции. Ошибки драйвера переводятся в доменные.
+func (r *sqlUserRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return r.translateError(ctx, err)
+    }
+    defer tx.Rollback()
+
+    if err := fn(tx); err != nil {
+        return r.translateError(ctx, err)
+    }
+    return r.translateError(ctx, tx.Commit())
+}
+
+// selectForUpdate читает пользователя внутри транзакции и блокирует строку до
+// ее завершения. SQLite не поддерживает FOR UPDATE: там транзакция сразу берет
+// блокировку на запись (_txlock=immediate), что дает тот же эффект.
+func (r *sqlUserRepository) selectForUpdate(ctx context.Context, tx *sql.Tx, id string, includeDeleted bool) (*model.User, error) {
+    query := "SELECT " + userColumns + " FROM users WHERE id = $1"
+    if !includeDeleted {
+        query += " AND deleted_at IS NULL"
+    }
+    return scanUser(tx.QueryRowContext(ctx, query+r.lockClause, id))
+}
+
+// audit записывает событие журнала аудита в транзакции изменения
+func (r *sqlUserRepository) audit(ctx context.Context, tx *sql.Tx, action string, from, to *model.User) error {
+    event, err := newAuditEvent(ctx, action, from, to)
+    if err != nil {
+        return err
+    }
+    return r.insertAuditEvents(ctx, tx, []model.AuditEvent{event})
+}
+
+// insertAuditEvents записывает события одним многострочным INSERT
+func (r *sqlUserRepository) insertAuditEvents(ctx context.Context, tx *sql.Tx, events []model.AuditEvent) error {
+    b := &userQueryBuilder{}
+    values := make([]string, len(events))
+    for i, e := range events {
+        values[i] = "(" + strings.Join([]string{
+            b.arg(e.UserID),
+            b.arg(e.Action),
+            b.arg(e.Actor),
+            b.arg(e.RequestID),
+            b.arg(nullJSON(e.Before)),
+            b.arg(nullJSON(e.After)),
+            b.arg(e.CreatedAt),
+        }, ", ") + ")"
+    }
+
+    _, err := tx.ExecContext(
+        ctx,
+        "INSERT INTO audit_events (user_id, action, actor, request_id, before, after, created_at) VALUES "+strings.Join(values, ", "),
+        b.args...,
+    )
+    return err
+}
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+// scanUser читает строку с колонками userColumns, за которыми следуют
+// дополнительные колонки extra
+func scanUser(row rowScanner, extra ...interface{}) (*model.User, error) {
+    var (
+        u         model.User
+        email     sql.NullString
+        deletedAt sql.NullTime
+    )
+    dest := []interface{}{&u.ID, &u.Name, &u.Age, &email, &u.Version, &deletedAt, &u.CreatedAt, &u.UpdatedAt}
+    if err := row.Scan(append(dest, extra...)...); err != nil {
+        return nil, err
+    }
+    u.Email = email.String
+    u.CreatedAt = u.CreatedAt.UTC()
+    u.UpdatedAt = u.UpdatedAt.UTC()
+    if deletedAt.Valid {
+        t := deletedAt.Time.UTC()
+        u.DeletedAt = &t
+    }
+    return &u, nil
+}
+
+// scanUsers читает все строки и закрывает rows
+func scanUsers(rows *sql.Rows) ([]*model.User, error) {
+    defer rows.Close()
+
+    var users []*model.User
+    for rows.Next() {
+        u, err := scanUser(rows)
+        if err != nil {
+            return nil, err
+        }
+        users = append(users, u)
+    }
+    return users, rows.Err()
+}
+
+// batchFailed сообщает, что хотя бы одна операция завершилась ошибкой
+func batchFailed(results []model.BatchResult) bool {
+    for _, result := range results {
+        if result.Err != nil {
+            return true
+        }
+    }
+    return false
+}
+
+// abortBatch отменяет результаты успешных операций атомарного пакета
+func abortBatch(results []model.BatchResult) {
+    for i := range results {
+        if results[i].Err == nil {
+            results[i] = model.BatchResult{Err: model.ErrBatchAborted}
+        }
+    }
+}
+
+// auditColumns - колонки audit_events в порядке, ожидаемом scanAuditEvent
+const auditColumns = "id, user_id, action, actor, request_id, before, after, created_at"
+
+// scanAuditEvent читает строку с колонками auditColumns
+func scanAuditEvent(row rowScanner) (*model.AuditEvent, error) {
+    var (
+        e             model.AuditEvent
+        id, userID    int64
+        before, after sql.NullString
+    )
+    if err := row.Scan(&id, &userID, &e.Action, &e.Actor, &e.RequestID, &before, &after, &e.CreatedAt); err != nil {
+        return nil, err
+    }
+    e.ID = strconv.FormatInt(id, 10)
+    e.UserID = strconv.FormatInt(userID, 10)
+    e.CreatedAt = e.CreatedAt.UTC()
+    if before.Valid {
+        e.Before = json.RawMessage(before.String)
+    }
+    if after.Valid {
+        e.After = json.RawMessage(after.String)
+    }
+    return &e, nil
+}
+
+// nullJSON передает пустой JSON как NULL
+func nullJSON(data json.RawMessage) interface{} {
+    if data == nil {
+        return nil
+    }
+    return string(data)
+}
+
+// nullString передает пустую строку как NULL: пустой email не участвует в
+// уникальном индексе
+func nullString(s string) interface{} {
+    if s == "" {
+        return nil
+    }
+    return s
+}
+
+// currentTime - момент изменения с точностью до микросекунд, как хранит PostgreSQL
+func currentTime() time.Time {
+    return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// isValidUserID проверяет, что id может быть первичным ключом users.
+// Иначе PostgreSQL вернет ошибку приведения типа вместо "не найдено".
+func isValidUserID(id string) bool {
+    n, err := strconv.ParseInt(id, 10, 32)
+    return err == nil && n > 0
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "strings"
+
+    "go-crud-example/internal/model"
+    "modernc.org/sqlite"
+    sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteUserRepository хранит пользователей в SQLite (чистый Go драйвер
+// modernc.org/sqlite). Предназначен для edge-развертываний и демо на одном узле.
+type SQLiteUserRepository struct {
+    *sqlUserRepository
+}
+
+func NewSQLiteUserRepository(db *sql.DB) UserRepository {
+    return &SQLiteUserRepository{
+        sqlUserRepository: &sqlUserRepository{db: db, translateError: translateSQLiteError},
+    }
+}
+
+// translateSQLiteError переводит ошибки SQLite в доменные ошибки
+func translateSQLiteError(ctx context.Context, err error) error {
+    if err == nil {
+        return nil
+    }
+    if errors.Is(err, sql.ErrNoRows) {
+        return ErrUserNotFound
+    }
+
+    var sqliteErr *sqlite.Error
+    if !errors.As(err, &sqliteErr) {
+        return err
+    }
+
+    switch sqliteErr.Code() {
+    case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+        // SQLite не сообщает имя ограничения отдельно, только в тексте ошибки
+        if strings.Contains(sqliteErr.Error(), userEmailIndex) {
+            return fmt.Errorf("%w: %w", ErrEmailTaken, err)
+        }
+        return fmt.Errorf("%w: %w", ErrUserConflict, err)
+    case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+        return fmt.Errorf("%w: %w", ErrUserConflict, err)
+    case sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK:
+        return fmt.Errorf("%w: %w", model.ErrInvalidUser, err)
+    case sqlite3.SQLITE_INTERRUPT:
+        // Запрос прерван драйвером из-за отмены контекста
+        if ctxErr := ctx.Err(); ctxErr != nil {
+            return fmt.Errorf("%w: %w", ctxErr, err)
+        }
+    }
+    return err
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+    "fmt"
+    "go-crud-example/internal/model"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// userColumns - колонки users в порядке, ожидаемом scanUser
+const userColumns = "id, name, age, email, version, deleted_at, created_at, updated_at"
+
+// Колонки, по которым разрешена сортировка. Имена колонок подставляются в SQL
+// только из этой таблицы, пользовательские значения идут через плейсхолдеры.
+var userSortColumns = map[string]string{
+    model.SortByID:   "id",
+    model.SortByName: "name",
+    model.SortByAge:  "age",
+}
+
+type userQueryBuilder struct {
+    conditions []string
+    args       []interface{}
+}
+
+// arg добавляет значение в список аргументов и возвращает его плейсхолдер
+func (b *userQueryBuilder) arg(value interface{}) string {
+    b.args = append(b.args, value)
+    return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *userQueryBuilder) where(condition string) {
+    b.conditions = append(b.conditions, condition)
+}
+
+func (b *userQueryBuilder) whereClause() string {
+    if len(b.conditions) == 0 {
+        return ""
+    }
+    return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+func (b *userQueryBuilder) applyFilters(q model.UserQuery) {
+    if !q.IncludeDeleted {
+        b.where("deleted_at IS NULL")
+    }
+    if q.NamePrefix != "" {
+        b.where("name LIKE " + b.arg(escapeLike(q.NamePrefix)+"%") + ` ESCAPE '\'`)
+    }
+    if q.MinAge != nil {
+        b.where("age >= " + b.arg(*q.MinAge))
+    }
+    if q.MaxAge != nil {
+        b.where("age <= " + b.arg(*q.MaxAge))
+    }
+}
+
+func buildUserCountQuery(q model.UserQuery) (string, []interface{}) {
+    b := &userQueryBuilder{}
+    b.applyFilters(q)
+    return "SELECT COUNT(*) FROM users" + b.whereClause(), b.args
+}
+
+// buildUserListQuery строит запрос страницы с keyset-пагинацией: вместо OFFSET
+// выбираются записи строго после позиции курсора. id используется как
+// дополнительный ключ сортировки, чтобы порядок был однозначным.
+func buildUserListQuery(q model.UserQuery, cursor *userCursor) (string, []interface{}) {
+    b := &userQueryBuilder{}
+    b.applyFilters(q)
+
+    field, desc := q.SortField()
+    column := userSortColumns[field]
+    op := ">"
+    if desc {
+        op = "<"
+    }
+
+    if cursor != nil {
+        if column == "id" {
+            b.where(fmt.Sprintf("id %s %s", op, b.arg(cursor.ID)))
+        } else {
+            b.where(fmt.Sprintf("(%s, id) %s (%s, %s)", column, op, b.arg(cursor.value(field)), b.arg(cursor.ID)))
+        }
+    }
+
+    query := fmt.Sprintf(
+        "SELECT %s FROM users%s ORDER BY %s LIMIT %s",
+        userColumns,
+        b.whereClause(),
+        userOrderBy(q),
+        b.arg(q.Limit+1),
+    )
+    return query, b.args
+}
+
+// buildUserExportQuery строит запрос всех пользователей, подходящих под фильтры,
+// в порядке сортировки списка, но без пагинации
+func buildUserExportQuery(q model.UserQuery) (string, []interface{}) {
+    b := &userQueryBuilder{}
+    b.applyFilters(q)
+    return fmt.Sprintf("SELECT %s FROM users%s ORDER BY %s", userColumns, b.whereClause(), userOrderBy(q)), b.args
+}
+
+// userOrderBy возвращает ORDER BY по полю сортировки с id как дополнительным ключом
+func userOrderBy(q model.UserQuery) string {
+    field, desc := q.SortField()
+    column := userSortColumns[field]
+    dir := "ASC"
+    if desc {
+        dir = "DESC"
+    }
+
+    order := fmt.Sprintf("%s %s", column, dir)
+    if column != "id" {
+        order += fmt.Sprintf(", id %s", dir)
+    }
+    return order
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+    return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// buildUserPatchQuery строит UPDATE только по изменяемым колонкам
+func buildUserPatchQuery(id string, patch model.UserPatch, updatedAt time.Time) (string, []interface{}) {
+    b := &userQueryBuilder{}
+    set := []string{"version = version + 1", "updated_at = " + b.arg(updatedAt)}
+    if patch.Name != nil {
+        set = append(set, "name = "+b.arg(*patch.Name))
+    }
+    if patch.Age != nil {
+        set = append(set, "age = "+b.arg(*patch.Age))
+    }
+    if patch.Email != nil {
+        set = append(set, "email = "+b.arg(nullString(*patch.Email)))
+    }
+
+    b.where("id = " + b.arg(id))
+    b.where("deleted_at IS NULL")
+    if patch.Version > 0 {
+        b.where("version = " + b.arg(patch.Version))
+    }
+
+    return fmt.Sprintf(
+        "UPDATE users SET %s%s RETURNING %s",
+        strings.Join(set, ", "),
+        b.whereClause(),
+        userColumns,
+    ), b.args
+}
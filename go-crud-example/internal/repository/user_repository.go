@@ -1,98 +1,54 @@
 package repository
 
 import (
+    "context"
     "database/sql"
     "go-crud-example/internal/model"
+    "time"
 )
 
 type UserRepository interface {
-    GetAll() ([]model.User, error)
-    GetByID(id string) (*model.User, error)
-    Create(user *model.User) error
-    Update(user *model.User) error
-    Delete(id string) error
+    GetAll(ctx context.Context, query model.UserQuery) (*model.UserPage, error)
+    // Export вызывает fn для каждого пользователя, подходящего под фильтры query, в
+    // порядке ее сортировки. Limit и Cursor не учитываются. Ошибка fn прерывает выгрузку.
+    Export(ctx context.Context, query model.UserQuery, fn func(model.User) error) error
+    // Search ищет неудаленных пользователей по имени и email и возвращает не больше
+    // query.Limit результатов в порядке убывания релевантности
+    Search(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error)
+    GetByID(ctx context.Context, id string) (*model.User, error)
+    Create(ctx context.Context, user *model.User) error
+    // CreateMany создает пользователей многострочной вставкой в одной транзакции
+    // и проставляет им ID и версии
+    CreateMany(ctx context.Context, users []*model.User) error
+    Update(ctx context.Context, user *model.User) error
+    // Patch изменяет только заданные в патче поля и возвращает обновленного пользователя
+    Patch(ctx context.Context, id string, patch model.UserPatch) (*model.User, error)
+    // Delete помечает пользователя удаленным, он перестает быть виден остальным методам
+    Delete(ctx context.Context, id string) error
+    // Restore снимает пометку об удалении. Для неудаленного пользователя ничего не меняет.
+    Restore(ctx context.Context, id string) (*model.User, error)
+    // Purge окончательно удаляет ранее удаленного пользователя
+    Purge(ctx context.Context, id string) error
+    // PurgeDeleted окончательно удаляет пользователей, удаленных раньше before
+    PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+    // AuditEvents возвращает события журнала аудита в порядке их записи
+    AuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error)
+    // Batch выполняет операции по порядку и возвращает результат каждой. В атомарном
+    // режиме ошибка одной операции отменяет весь пакет, остальные получают
+    // model.ErrBatchAborted. Ошибка метода означает, что пакет не применен.
+    Batch(ctx context.Context, ops []model.BatchOperation, atomic bool) ([]model.BatchResult, error)
 }
 
 type PostgresUserRepository struct {
-    db *sql.DB
+    *sqlUserRepository
 }
 
 func NewUserRepository(db *sql.DB) UserRepository {
-    return &PostgresUserRepository{db: db}
-}
-
-func (r *PostgresUserRepository) GetAll() ([]model.User, error) {
-    rows, err := r.db.Query("SELECT id, name, age FROM users")
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    users := []model.User{}
-    for rows.Next() {
-        var u model.User
-        if err := rows.Scan(&u.ID, &u.Name, &u.Age); err != nil {
-            return nil, err
-        }
-        users = append(users, u)
-    }
-
-    return users, nil
-}
-
-func (r *PostgresUserRepository) GetByID(id string) (*model.User, error) {
-    var u model.User
-    err := r.db.QueryRow("SELECT id, name, age FROM users WHERE id = $1", id).
-        Scan(&u.ID, &u.Name, &u.Age)
-    if err != nil {
-        return nil, err
-    }
-    return &u, nil
-}
-
-func (r *PostgresUserRepository) Create(user *model.User) error {
-    return r.db.QueryRow(
-        "INSERT INTO users (name, age) VALUES ($1, $2) RETURNING id",
-        user.Name,
-        user.Age,
-    ).Scan(&user.ID)
-}
-
-func (r *PostgresUserRepository) Update(user *model.User) error {
-    result, err := r.db.Exec(
-        "UPDATE users SET name = $1, age = $2 WHERE id = $3",
-        user.Name,
-        user.Age,
-        user.ID,
-    )
-    if err != nil {
-        return err
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-    if rowsAffected == 0 {
-        return sql.ErrNoRows
-    }
-
-    return nil
-}
-
-func (r *PostgresUserRepository) Delete(id string) error {
-    result, err := r.db.Exec("DELETE FROM users WHERE id = $1", id)
-    if err != nil {
-        return err
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-    if rowsAffected == 0 {
-        return sql.ErrNoRows
+    return &PostgresUserRepository{
+        sqlUserRepository: &sqlUserRepository{
+            db:             db,
+            translateError: translatePostgresError,
+            lockClause:     " FOR UPDATE",
+        },
     }
-
-    return nil
 }
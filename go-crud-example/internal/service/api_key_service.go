@@ -0,0 +1,152 @@
+package service
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "time"
+
+    "go-crud-example/internal/authz"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/pkg/actor"
+)
+
+// apiKeySecretBytes - случайная часть ключа, 256 бит. Такой ключ не подобрать
+// перебором, поэтому для хранения достаточно SHA-256 без соли.
+const apiKeySecretBytes = 32
+
+type APIKeyService interface {
+    // CreateAPIKey выпускает ключ. Открытый ключ есть только в результате.
+    CreateAPIKey(ctx context.Context, req model.APIKeyRequest) (*model.CreatedAPIKey, error)
+    ListAPIKeys(ctx context.Context) ([]model.APIKey, error)
+    RevokeAPIKey(ctx context.Context, id string) (*model.APIKey, error)
+    // AuthenticateAPIKey возвращает действующий ключ. Неизвестный, отозванный
+    // и истекший ключ - ErrInvalidAPIKey.
+    AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKey, error)
+}
+
+type apiKeyService struct {
+    repo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+    return &apiKeyService{repo: repo}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, req model.APIKeyRequest) (*model.CreatedAPIKey, error) {
+    if err := req.Validate(time.Now()); err != nil {
+        return nil, err
+    }
+    for _, scope := range req.Scopes {
+        if !authz.IsAction(scope) {
+            return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyRequest, scope)
+        }
+    }
+
+    secret := make([]byte, apiKeySecretBytes)
+    if _, err := rand.Read(secret); err != nil {
+        return nil, fmt.Errorf("failed to generate api key: %w", err)
+    }
+    plain := model.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+    key := model.APIKey{
+        Name:      req.Name,
+        Prefix:    plain[:model.APIKeyDisplayLength],
+        Scopes:    req.Scopes,
+        CreatedBy: actor.FromContext(ctx),
+        ExpiresAt: req.ExpiresAt,
+    }
+    if key.ExpiresAt != nil {
+        expiresAt := key.ExpiresAt.UTC()
+        key.ExpiresAt = &expiresAt
+    }
+    if err := s.repo.Create(ctx, &key, hashAPIKey(plain)); err != nil {
+        return nil, err
+    }
+    return &model.CreatedAPIKey{APIKey: key, Key: plain}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+    return s.repo.List(ctx)
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+    return s.repo.Revoke(ctx, id)
+}
+
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+    found, err := s.repo.GetByHash(ctx, hashAPIKey(key))
+    if errors.Is(err, ErrAPIKeyNotFound) {
+        return nil, ErrInvalidAPIKey
+    }
+    if err != nil {
+        return nil, err
+    }
+    if !found.Active(time.Now()) {
+        return nil, ErrInvalidAPIKey
+    }
+    return found, nil
+}
+
+func hashAPIKey(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    return hex.EncodeToString(sum[:])
+}
+
+// authorizedAPIKeyService разрешает управление ключами только с правом
+// api_keys:manage. Ключ выдается только с действиями, которые разрешены
+// самому актору: иначе право api_keys:manage позволяло бы выпустить ключ
+// с любыми правами. По той же причине ключ, выпущенный по API-ключу, должен
+// истечь не позже него. Аутентификация ключом выполняется до проверки прав.
+type authorizedAPIKeyService struct {
+    next   APIKeyService
+    policy *authz.Policy
+}
+
+// NewAuthorizedAPIKeyService оборачивает сервис ключей проверкой прав
+func NewAuthorizedAPIKeyService(next APIKeyService, policy *authz.Policy) APIKeyService {
+    return &authorizedAPIKeyService{next: next, policy: policy}
+}
+
+func (s *authorizedAPIKeyService) CreateAPIKey(ctx context.Context, req model.APIKeyRequest) (*model.CreatedAPIKey, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionAPIKeys, ""); err != nil {
+        return nil, err
+    }
+    for _, scope := range req.Scopes {
+        // Неизвестные действия отклоняет проверка запроса
+        if !authz.IsAction(scope) {
+            continue
+        }
+        if err := s.policy.Authorize(ctx, scope, ""); err != nil {
+            return nil, fmt.Errorf("%w: scope %s exceeds the caller's rights", ErrForbidden, scope)
+        }
+    }
+    if expiresAt, ok := actor.ExpiryFromContext(ctx); ok && (req.ExpiresAt == nil || req.ExpiresAt.After(expiresAt)) {
+        return nil, fmt.Errorf("%w: key must expire no later than the caller's key (%s)",
+            ErrForbidden, expiresAt.UTC().Format(time.RFC3339))
+    }
+    return s.next.CreateAPIKey(ctx, req)
+}
+
+func (s *authorizedAPIKeyService) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionAPIKeys, ""); err != nil {
+        return nil, err
+    }
+    return s.next.ListAPIKeys(ctx)
+}
+
+func (s *authorizedAPIKeyService) RevokeAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionAPIKeys, ""); err != nil {
+        return nil, err
+    }
+    return s.next.RevokeAPIKey(ctx, id)
+}
+
+func (s *authorizedAPIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+    return s.next.AuthenticateAPIKey(ctx, key)
+}
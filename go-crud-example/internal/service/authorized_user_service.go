@@ -0,0 +1,137 @@
+package service
+
+import (
+    "context"
+    "io"
+
+    "go-crud-example/internal/authz"
+    "go-crud-example/internal/model"
+)
+
+// authorizedUserService проверяет права актора из контекста по политике и
+// только затем передает вызов next
+type authorizedUserService struct {
+    next   UserService
+    policy *authz.Policy
+}
+
+// NewAuthorizedUserService оборачивает сервис проверкой прав. Отказ возвращает
+// ошибку, оборачивающую ErrForbidden.
+func NewAuthorizedUserService(next UserService, policy *authz.Policy) UserService {
+    return &authorizedUserService{next: next, policy: policy}
+}
+
+func (s *authorizedUserService) GetUsers(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
+    if err := s.authorizeQuery(ctx, authz.ActionList, query); err != nil {
+        return nil, err
+    }
+    return s.next.GetUsers(ctx, query)
+}
+
+func (s *authorizedUserService) GetUser(ctx context.Context, id string) (*model.User, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionRead, id); err != nil {
+        return nil, err
+    }
+    return s.next.GetUser(ctx, id)
+}
+
+func (s *authorizedUserService) ExportUsers(ctx context.Context, query model.UserQuery, fn func(model.User) error) error {
+    if err := s.authorizeQuery(ctx, authz.ActionExport, query); err != nil {
+        return err
+    }
+    return s.next.ExportUsers(ctx, query, fn)
+}
+
+func (s *authorizedUserService) SearchUsers(ctx context.Context, query model.SearchQuery) (*model.SearchResults, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionSearch, ""); err != nil {
+        return nil, err
+    }
+    return s.next.SearchUsers(ctx, query)
+}
+
+func (s *authorizedUserService) CreateUser(ctx context.Context, user *model.User) error {
+    if err := s.policy.Authorize(ctx, authz.ActionCreate, ""); err != nil {
+        return err
+    }
+    return s.next.CreateUser(ctx, user)
+}
+
+func (s *authorizedUserService) UpdateUser(ctx context.Context, user *model.User) error {
+    if err := s.policy.Authorize(ctx, authz.ActionUpdate, user.ID); err != nil {
+        return err
+    }
+    return s.next.UpdateUser(ctx, user)
+}
+
+func (s *authorizedUserService) PatchUser(ctx context.Context, id string, version int64, patch PatchFunc) (*model.User, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionUpdate, id); err != nil {
+        return nil, err
+    }
+    return s.next.PatchUser(ctx, id, version, patch)
+}
+
+func (s *authorizedUserService) DeleteUser(ctx context.Context, id string) error {
+    if err := s.policy.Authorize(ctx, authz.ActionDelete, id); err != nil {
+        return err
+    }
+    return s.next.DeleteUser(ctx, id)
+}
+
+func (s *authorizedUserService) RestoreUser(ctx context.Context, id string) (*model.User, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionRestore, id); err != nil {
+        return nil, err
+    }
+    return s.next.RestoreUser(ctx, id)
+}
+
+func (s *authorizedUserService) PurgeUser(ctx context.Context, id string) error {
+    if err := s.policy.Authorize(ctx, authz.ActionPurge, id); err != nil {
+        return err
+    }
+    return s.next.PurgeUser(ctx, id)
+}
+
+func (s *authorizedUserService) ImportUsers(ctx context.Context, r io.Reader, opts model.ImportOptions) (*model.ImportReport, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionImport, ""); err != nil {
+        return nil, err
+    }
+    return s.next.ImportUsers(ctx, r, opts)
+}
+
+// BatchUsers требует прав на каждую операцию пакета. Пакет, в котором хотя бы
+// одна операция запрещена, не выполняется целиком.
+func (s *authorizedUserService) BatchUsers(ctx context.Context, batch model.BatchRequest) ([]model.BatchResult, error) {
+    for _, op := range batch.Operations {
+        action := authz.ActionCreate
+        switch op.Op {
+        case model.BatchOpUpdate:
+            action = authz.ActionUpdate
+        case model.BatchOpDelete:
+            action = authz.ActionDelete
+        }
+        if err := s.policy.Authorize(ctx, action, op.ID); err != nil {
+            return nil, err
+        }
+    }
+    return s.next.BatchUsers(ctx, batch)
+}
+
+// GetAuditEvents для журнала одного пользователя учитывает правила self
+func (s *authorizedUserService) GetAuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+    if err := s.policy.Authorize(ctx, authz.ActionAudit, query.UserID); err != nil {
+        return nil, err
+    }
+    return s.next.GetAuditEvents(ctx, query)
+}
+
+// authorizeQuery дополнительно требует прав на удаленных пользователей, если
+// запрос их включает
+func (s *authorizedUserService) authorizeQuery(ctx context.Context, action string, query model.UserQuery) error {
+    if err := s.policy.Authorize(ctx, action, ""); err != nil {
+        return err
+    }
+    if query.IncludeDeleted {
+        return s.policy.Authorize(ctx, authz.ActionReadDeleted, "")
+    }
+    return nil
+}
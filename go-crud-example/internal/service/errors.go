@@ -1,8 +1,19 @@
 package service
 
-import "errors"
+import "go-crud-example/internal/model"
 
 var (
-    ErrUserNotFound = errors.New("user not found")
-    ErrInvalidUser = errors.New("invalid user")
-)
\ No newline at end of file
+    ErrUserNotFound         = model.ErrUserNotFound
+    ErrUserConflict         = model.ErrUserConflict
+    ErrEmailTaken           = model.ErrEmailTaken
+    ErrInvalidUser          = model.ErrInvalidUser
+    ErrVersionMismatch      = model.ErrVersionMismatch
+    ErrUserNotDeleted       = model.ErrUserNotDeleted
+    ErrForbidden            = model.ErrForbidden
+    ErrInvalidBatch         = model.ErrInvalidBatch
+    ErrBatchAborted         = model.ErrBatchAborted
+    ErrInvalidImport        = model.ErrInvalidImport
+    ErrAPIKeyNotFound       = model.ErrAPIKeyNotFound
+    ErrInvalidAPIKeyRequest = model.ErrInvalidAPIKeyRequest
+    ErrInvalidAPIKey        = model.ErrInvalidAPIKey
+)
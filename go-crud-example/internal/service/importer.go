@@ -0,0 +1,247 @@
+package service
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "strconv"
+    "strings"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/logger"
+)
+
+// maxImportLineSize ограничивает длину строки NDJSON, чтобы одна строка не
+// занимала память без ограничений
+const maxImportLineSize = 1 << 20
+
+// importRow - одна строка файла импорта. err - ошибка разбора строки.
+type importRow struct {
+    line int
+    user model.User
+    err  error
+}
+
+// userDecoder читает строки файла импорта по одной. По окончании файла
+// возвращает io.EOF, другие ошибки означают, что файл дальше читать нельзя.
+type userDecoder interface {
+    next() (importRow, error)
+}
+
+func (s *userService) ImportUsers(ctx context.Context, r io.Reader, opts model.ImportOptions) (*model.ImportReport, error) {
+    if err := opts.Normalize(); err != nil {
+        return nil, err
+    }
+
+    decoder, err := newUserDecoder(r, opts.Format)
+    if err != nil {
+        return nil, err
+    }
+
+    report := &model.ImportReport{DryRun: opts.DryRun, Errors: []model.ImportError{}}
+    batch := make([]importRow, 0, opts.BatchSize)
+    for {
+        row, err := decoder.next()
+        if errors.Is(err, io.EOF) {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        report.Total++
+
+        if row.err == nil {
+            row.err = row.user.Validate()
+        }
+        if row.err != nil {
+            addImportError(ctx, report, row.line, row.err)
+            continue
+        }
+
+        batch = append(batch, row)
+        if len(batch) == opts.BatchSize {
+            if err := s.importBatch(ctx, batch, opts.DryRun, report); err != nil {
+                return nil, err
+            }
+            batch = batch[:0]
+        }
+    }
+
+    if err := s.importBatch(ctx, batch, opts.DryRun, report); err != nil {
+        return nil, err
+    }
+    report.SortErrors()
+    return report, nil
+}
+
+// importBatch создает проверенных пользователей одной вставкой. Если вставка
+// отклонена из-за данных, пользователи создаются по одному, чтобы найти
+// ошибочные строки.
+func (s *userService) importBatch(ctx context.Context, batch []importRow, dryRun bool, report *model.ImportReport) error {
+    if len(batch) == 0 {
+        return nil
+    }
+    if dryRun {
+        report.Imported += len(batch)
+        return nil
+    }
+
+    users := make([]*model.User, len(batch))
+    for i := range batch {
+        users[i] = &batch[i].user
+    }
+    err := s.repo.CreateMany(ctx, users)
+    if err == nil {
+        report.Imported += len(batch)
+        return nil
+    }
+    if !isImportRowError(err) {
+        return fmt.Errorf("failed to import users: %w", err)
+    }
+
+    for _, row := range batch {
+        if err := s.repo.Create(ctx, &row.user); err != nil {
+            if !isImportRowError(err) {
+                return fmt.Errorf("failed to import users: %w", err)
+            }
+            addImportError(ctx, report, row.line, err)
+            continue
+        }
+        report.Imported++
+    }
+    return nil
+}
+
+// addImportError записывает ошибку строки в отчет, а ошибку, текст которой
+// в отчет не попал, - в лог
+func addImportError(ctx context.Context, report *model.ImportReport, line int, err error) {
+    if !report.AddError(line, err) {
+        logger.FromContext(ctx, slog.Default()).Error("failed to import row", slog.Int("line", line), slog.Any("error", err))
+    }
+}
+
+// isImportRowError сообщает, что ошибка вызвана данными строки, а не хранилищем
+func isImportRowError(err error) bool {
+    return errors.Is(err, ErrUserConflict) || errors.Is(err, ErrInvalidUser)
+}
+
+func newUserDecoder(r io.Reader, format string) (userDecoder, error) {
+    if format == model.ImportFormatCSV {
+        return newCSVUserDecoder(r)
+    }
+
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+    return &ndjsonUserDecoder{scanner: scanner}, nil
+}
+
+// csvUserDecoder читает CSV с заголовком. Обязательны колонки name и age,
+// email необязательна, остальные (например id и version из выгрузки) игнорируются.
+type csvUserDecoder struct {
+    reader *csv.Reader
+    fields int
+    name   int
+    age    int
+    email  int
+}
+
+func newCSVUserDecoder(r io.Reader) (*csvUserDecoder, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if errors.Is(err, io.EOF) {
+        return nil, fmt.Errorf("%w: CSV header is missing", model.ErrInvalidImport)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", model.ErrInvalidImport, err)
+    }
+
+    d := &csvUserDecoder{reader: reader, fields: len(header), name: -1, age: -1, email: -1}
+    for i, column := range header {
+        switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))) {
+        case "name":
+            d.name = i
+        case "age":
+            d.age = i
+        case "email":
+            d.email = i
+        }
+    }
+    if d.name < 0 || d.age < 0 {
+        return nil, fmt.Errorf("%w: CSV header must contain name and age columns", model.ErrInvalidImport)
+    }
+    return d, nil
+}
+
+func (d *csvUserDecoder) next() (importRow, error) {
+    record, err := d.reader.Read()
+    var parseErr *csv.ParseError
+    if errors.As(err, &parseErr) {
+        // Ошибка в кавычках одной записи не мешает читать следующие
+        return importRow{line: parseErr.StartLine, err: &model.ImportFieldError{Message: parseErr.Err.Error()}}, nil
+    }
+    if err != nil {
+        return importRow{}, err
+    }
+
+    line, _ := d.reader.FieldPos(0)
+    if len(record) != d.fields {
+        return importRow{line: line, err: &model.ImportFieldError{Message: fmt.Sprintf("expected %d fields, got %d", d.fields, len(record))}}, nil
+    }
+
+    row := importRow{line: line, user: model.User{Name: record[d.name]}}
+    if d.email >= 0 {
+        row.user.Email = strings.TrimSpace(record[d.email])
+    }
+    age, err := strconv.Atoi(strings.TrimSpace(record[d.age]))
+    if err != nil {
+        row.err = &model.ImportFieldError{Field: "age", Message: "must be an integer"}
+        return row, nil
+    }
+    row.user.Age = age
+    return row, nil
+}
+
+// ndjsonUserDecoder читает по одному JSON объекту пользователя на строку.
+// Пустые строки пропускаются, поля, которые задает хранилище, игнорируются.
+type ndjsonUserDecoder struct {
+    scanner *bufio.Scanner
+    line    int
+}
+
+func (d *ndjsonUserDecoder) next() (importRow, error) {
+    for d.scanner.Scan() {
+        d.line++
+        data := bytes.TrimSpace(d.scanner.Bytes())
+        if len(data) == 0 {
+            continue
+        }
+
+        var u model.User
+        decoder := json.NewDecoder(bytes.NewReader(data))
+        decoder.DisallowUnknownFields()
+        if err := decoder.Decode(&u); err != nil {
+            return importRow{line: d.line, err: &model.ImportFieldError{Message: fmt.Sprintf("invalid JSON: %v", err)}}, nil
+        }
+        if decoder.More() {
+            return importRow{line: d.line, err: &model.ImportFieldError{Message: "invalid JSON: unexpected data after object"}}, nil
+        }
+        return importRow{line: d.line, user: model.User{Name: u.Name, Age: u.Age, Email: u.Email}}, nil
+    }
+
+    if err := d.scanner.Err(); err != nil {
+        if errors.Is(err, bufio.ErrTooLong) {
+            return importRow{}, fmt.Errorf("%w: line %d is longer than %d bytes", model.ErrInvalidImport, d.line+1, maxImportLineSize)
+        }
+        return importRow{}, err
+    }
+    return importRow{}, io.EOF
+}
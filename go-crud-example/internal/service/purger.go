@@ -0,0 +1,63 @@
+package service
+
+import (
+    "context"
+    "log/slog"
+    "time"
+
+    "go-crud-example/internal/repository"
+    "go-crud-example/pkg/actor"
+    "go-crud-example/pkg/metrics"
+)
+
+// Purger периодически окончательно удаляет пользователей, мягко удаленных
+// раньше, чем retention назад
+type Purger struct {
+    repo      repository.UserRepository
+    retention time.Duration
+    interval  time.Duration
+    logger    *slog.Logger
+}
+
+func NewPurger(repo repository.UserRepository, retention, interval time.Duration, logger *slog.Logger) *Purger {
+    return &Purger{
+        repo:      repo,
+        retention: retention,
+        interval:  interval,
+        logger:    logger,
+    }
+}
+
+// Run выполняет очистку сразу и затем каждые interval до отмены ctx
+func (p *Purger) Run(ctx context.Context) {
+    ticker := time.NewTicker(p.interval)
+    defer ticker.Stop()
+
+    for {
+        if _, err := p.PurgeOnce(ctx); err != nil && ctx.Err() == nil {
+            p.logger.Error("failed to purge deleted users", slog.Any("error", err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// PurgeOnce удаляет пользователей, срок хранения которых истек. В журнале
+// аудита удаление записывается от имени actor.System.
+func (p *Purger) PurgeOnce(ctx context.Context) (int64, error) {
+    ctx = actor.NewContext(ctx, actor.System)
+    purged, err := p.repo.PurgeDeleted(ctx, time.Now().Add(-p.retention))
+    if err != nil {
+        return 0, err
+    }
+
+    if purged > 0 {
+        metrics.UsersPurgedTotal.Add(float64(purged))
+        p.logger.Info("purged deleted users", slog.Int64("count", purged), slog.Duration("retention", p.retention))
+    }
+    return purged, nil
+}
@@ -1,19 +1,47 @@
 package service
 
 import (
-    "database/sql"
+    "bytes"
+    "context"
+    "encoding/json"
     "errors"
     "fmt"
     "go-crud-example/internal/model"
+    "io"
     "go-crud-example/internal/repository"
 )
 
+// maxPatchAttempts - сколько раз PatchUser без ожидаемой версии повторяет
+// чтение и применение патча, если запись изменили параллельно
+const maxPatchAttempts = 3
+
+// PatchFunc применяет патч к JSON-представлению пользователя
+type PatchFunc func(doc []byte) ([]byte, error)
+
 type UserService interface {
-    GetUsers() ([]model.User, error)
-    GetUser(id string) (*model.User, error)
-    CreateUser(user *model.User) error
-    UpdateUser(user *model.User) error
-    DeleteUser(id string) error
+    GetUsers(ctx context.Context, query model.UserQuery) (*model.UserPage, error)
+    GetUser(ctx context.Context, id string) (*model.User, error)
+    // ExportUsers передает fn всех пользователей, подходящих под фильтры query,
+    // не загружая их в память целиком
+    ExportUsers(ctx context.Context, query model.UserQuery, fn func(model.User) error) error
+    // SearchUsers ищет пользователей по имени и email, лучшие совпадения первыми
+    SearchUsers(ctx context.Context, query model.SearchQuery) (*model.SearchResults, error)
+    CreateUser(ctx context.Context, user *model.User) error
+    UpdateUser(ctx context.Context, user *model.User) error
+    // PatchUser применяет patch к текущему пользователю и сохраняет изменившиеся поля.
+    // version - ожидаемая версия пользователя, 0 - без проверки.
+    PatchUser(ctx context.Context, id string, version int64, patch PatchFunc) (*model.User, error)
+    DeleteUser(ctx context.Context, id string) error
+    RestoreUser(ctx context.Context, id string) (*model.User, error)
+    PurgeUser(ctx context.Context, id string) error
+    // ImportUsers создает пользователей из CSV или NDJSON. Ошибки отдельных строк
+    // возвращаются в отчете, ошибка метода означает, что импорт прерван.
+    ImportUsers(ctx context.Context, r io.Reader, opts model.ImportOptions) (*model.ImportReport, error)
+    // BatchUsers выполняет пакет операций. Ошибки отдельных операций возвращаются
+    // в результатах, ошибка метода означает, что пакет не выполнялся.
+    BatchUsers(ctx context.Context, batch model.BatchRequest) ([]model.BatchResult, error)
+    // GetAuditEvents возвращает журнал изменений пользователей
+    GetAuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error)
 }
 
 type userService struct {
@@ -26,62 +54,198 @@ func NewUserService(repo repository.UserRepository) UserService {
     }
 }
 
-func (s *userService) GetUsers() ([]model.User, error) {
-    users, err := s.repo.GetAll()
+func (s *userService) GetUsers(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+
+    page, err := s.repo.GetAll(ctx, query)
     if err != nil {
         return nil, fmt.Errorf("failed to get users: %w", err)
     }
-    return users, nil
+    return page, nil
+}
+
+func (s *userService) ExportUsers(ctx context.Context, query model.UserQuery, fn func(model.User) error) error {
+    if err := query.Normalize(); err != nil {
+        return err
+    }
+
+    if err := s.repo.Export(ctx, query, fn); err != nil {
+        return fmt.Errorf("failed to export users: %w", err)
+    }
+    return nil
 }
 
-func (s *userService) GetUser(id string) (*model.User, error) {
-    user, err := s.repo.GetByID(id)
-    if err == sql.ErrNoRows {
-        return nil, fmt.Errorf("user not found: %w", err)
+func (s *userService) SearchUsers(ctx context.Context, query model.SearchQuery) (*model.SearchResults, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
     }
+
+    results, err := s.repo.Search(ctx, query)
+    if err != nil {
+        return nil, fmt.Errorf("failed to search users: %w", err)
+    }
+    return &model.SearchResults{Results: results}, nil
+}
+
+func (s *userService) GetUser(ctx context.Context, id string) (*model.User, error) {
+    user, err := s.repo.GetByID(ctx, id)
     if err != nil {
         return nil, fmt.Errorf("failed to get user: %w", err)
     }
     return user, nil
 }
 
-func (s *userService) CreateUser(user *model.User) error {
+func (s *userService) CreateUser(ctx context.Context, user *model.User) error {
     if err := user.Validate(); err != nil {
-        return fmt.Errorf("validation error: %w", err)
+        return err
     }
 
-    if err := s.repo.Create(user); err != nil {
+    if err := s.repo.Create(ctx, user); err != nil {
         return fmt.Errorf("failed to create user: %w", err)
     }
     return nil
 }
 
-func (s *userService) UpdateUser(user *model.User) error {
+func (s *userService) UpdateUser(ctx context.Context, user *model.User) error {
     if err := user.Validate(); err != nil {
-        return fmt.Errorf("validation error: %w", err)
+        return err
     }
 
-    if err := s.repo.Update(user); err != nil {
-        if err == sql.ErrNoRows {
-            return fmt.Errorf("user not found: %w", err)
-        }
+    if err := s.repo.Update(ctx, user); err != nil {
         return fmt.Errorf("failed to update user: %w", err)
     }
     return nil
 }
 
-func (s *userService) DeleteUser(id string) error {
-    if err := s.repo.Delete(id); err != nil {
-        if err == sql.ErrNoRows {
-            return fmt.Errorf("user not found: %w", err)
+func (s *userService) PatchUser(ctx context.Context, id string, version int64, patch PatchFunc) (*model.User, error) {
+    for attempt := 1; ; attempt++ {
+        user, err := s.patchUser(ctx, id, version, patch)
+        // Клиент не требовал конкретной версии: применяем патч к свежим данным
+        if errors.Is(err, ErrVersionMismatch) && version == 0 && attempt < maxPatchAttempts {
+            continue
         }
+        return user, err
+    }
+}
+
+func (s *userService) patchUser(ctx context.Context, id string, version int64, patch PatchFunc) (*model.User, error) {
+    current, err := s.repo.GetByID(ctx, id)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get user: %w", err)
+    }
+    if version > 0 && current.Version != version {
+        return nil, ErrVersionMismatch
+    }
+
+    doc, err := json.Marshal(current)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode user: %w", err)
+    }
+    patchedDoc, err := patch(doc)
+    if err != nil {
+        return nil, err
+    }
+
+    var patched model.User
+    decoder := json.NewDecoder(bytes.NewReader(patchedDoc))
+    decoder.DisallowUnknownFields()
+    if err := decoder.Decode(&patched); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidUser, err)
+    }
+    if patched.ID != current.ID || patched.Version != current.Version ||
+        !patched.CreatedAt.Equal(current.CreatedAt) || !patched.UpdatedAt.Equal(current.UpdatedAt) {
+        return nil, fmt.Errorf("%w: id, version, created_at and updated_at are read-only", ErrInvalidUser)
+    }
+    if err := patched.Validate(); err != nil {
+        return nil, err
+    }
+
+    changes := model.DiffUsers(current, &patched)
+    if changes.IsEmpty() {
+        return current, nil
+    }
+    changes.Version = current.Version
+
+    user, err := s.repo.Patch(ctx, id, changes)
+    if err != nil {
+        return nil, fmt.Errorf("failed to patch user: %w", err)
+    }
+    return user, nil
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id string) error {
+    if err := s.repo.Delete(ctx, id); err != nil {
         return fmt.Errorf("failed to delete user: %w", err)
     }
     return nil
 }
 
-// Определение пользовательских ошибок
-var (
-    ErrEmptyName  = errors.New("name cannot be empty")
-    ErrInvalidAge = errors.New("age must be positive")
-)
+func (s *userService) RestoreUser(ctx context.Context, id string) (*model.User, error) {
+    user, err := s.repo.Restore(ctx, id)
+    if err != nil {
+        return nil, fmt.Errorf("failed to restore user: %w", err)
+    }
+    return user, nil
+}
+
+func (s *userService) PurgeUser(ctx context.Context, id string) error {
+    if err := s.repo.Purge(ctx, id); err != nil {
+        return fmt.Errorf("failed to purge user: %w", err)
+    }
+    return nil
+}
+
+func (s *userService) BatchUsers(ctx context.Context, batch model.BatchRequest) ([]model.BatchResult, error) {
+    if err := batch.Normalize(); err != nil {
+        return nil, err
+    }
+
+    // Некорректные операции не доходят до репозитория
+    results := make([]model.BatchResult, len(batch.Operations))
+    valid := make([]model.BatchOperation, 0, len(batch.Operations))
+    index := make([]int, 0, len(batch.Operations))
+    for i := range batch.Operations {
+        if err := batch.Operations[i].Validate(); err != nil {
+            results[i].Err = err
+            continue
+        }
+        valid = append(valid, batch.Operations[i])
+        index = append(index, i)
+    }
+
+    atomic := batch.Mode == model.BatchModeAtomic
+    if atomic && len(valid) < len(batch.Operations) {
+        for i := range results {
+            if results[i].Err == nil {
+                results[i].Err = ErrBatchAborted
+            }
+        }
+        return results, nil
+    }
+    if len(valid) == 0 {
+        return results, nil
+    }
+
+    applied, err := s.repo.Batch(ctx, valid, atomic)
+    if err != nil {
+        return nil, fmt.Errorf("failed to apply batch: %w", err)
+    }
+    for j, i := range index {
+        results[i] = applied[j]
+    }
+    return results, nil
+}
+
+func (s *userService) GetAuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+
+    page, err := s.repo.AuditEvents(ctx, query)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get audit events: %w", err)
+    }
+    return page, nil
+}
@@ -0,0 +1,71 @@
+// Package actor передает через контекст идентификатор того, кто выполняет
+// операцию, его роли и scopes. Используется журналом аудита и проверкой прав.
+package actor
+
+import (
+    "context"
+    "time"
+)
+
+const (
+    // Anonymous - актор запросов без аутентификации
+    Anonymous = "anonymous"
+    // System - актор фоновых задач приложения
+    System = "system"
+)
+
+type (
+    ctxKey    struct{}
+    rolesKey  struct{}
+    scopesKey struct{}
+    expiryKey struct{}
+)
+
+func NewContext(ctx context.Context, actor string) context.Context {
+    return context.WithValue(ctx, ctxKey{}, actor)
+}
+
+// FromContext возвращает актора или Anonymous, если он не задан
+func FromContext(ctx context.Context) string {
+    if actor, ok := ctx.Value(ctxKey{}).(string); ok && actor != "" {
+        return actor
+    }
+    return Anonymous
+}
+
+// WithRoles сохраняет роли актора
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+    return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// RolesFromContext возвращает роли актора или nil, если они не заданы
+func RolesFromContext(ctx context.Context) []string {
+    roles, _ := ctx.Value(rolesKey{}).([]string)
+    return roles
+}
+
+// WithScopes ограничивает актора перечисленными действиями независимо от
+// ролей. Используется для API-ключей.
+func WithScopes(ctx context.Context, scopes ...string) context.Context {
+    return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// ScopesFromContext возвращает scopes актора. ok == false, если актор ими не
+// ограничен и права определяются ролями.
+func ScopesFromContext(ctx context.Context) (scopes []string, ok bool) {
+    scopes, ok = ctx.Value(scopesKey{}).([]string)
+    return scopes, ok
+}
+
+// WithExpiry сохраняет срок действия учетных данных актора, например
+// API-ключа, по которому выполняется запрос
+func WithExpiry(ctx context.Context, expiresAt time.Time) context.Context {
+    return context.WithValue(ctx, expiryKey{}, expiresAt)
+}
+
+// ExpiryFromContext возвращает срок действия учетных данных актора. ok == false,
+// если срок не задан.
+func ExpiryFromContext(ctx context.Context) (expiresAt time.Time, ok bool) {
+    expiresAt, ok = ctx.Value(expiryKey{}).(time.Time)
+    return expiresAt, ok
+}
@@ -3,32 +3,135 @@ package config
 import (
     "fmt"
     "github.com/joho/godotenv"
+    "go-crud-example/pkg/ratelimit"
     "log"
     "os"
+    "strconv"
+    "strings"
+    "time"
 )
 
 type Config struct {
-    Server   ServerConfig
-    Database DatabaseConfig
+    Server      ServerConfig
+    Database    DatabaseConfig
+    Purge       PurgeConfig
+    Log         LogConfig
+    Auth        AuthConfig
+    RateLimit   RateLimitConfig
+    Idempotency IdempotencyConfig
 }
 
 type ServerConfig struct {
-    Port string
+    Port        string
+    MetricsPort string
+    // Максимальное время обработки одного запроса
+    RequestTimeout time.Duration
+    // Время на завершение текущих запросов при остановке
+    ShutdownTimeout time.Duration
+    // Пауза между переводом readiness в 503 и остановкой серверов
+    ShutdownDelay time.Duration
 }
 
+// Поддерживаемые хранилища пользователей
+const (
+    StorageDriverPostgres = "postgres"
+    StorageDriverSQLite   = "sqlite"
+    StorageDriverMemory   = "memory"
+)
+
 type DatabaseConfig struct {
+    // Хранилище пользователей: postgres, sqlite или memory
+    Driver   string
     Host     string
     Port     string
     User     string
     Password string
     DBName   string
     SSLMode  string
+    // Путь к файлу базы для драйвера sqlite
+    SQLitePath string
+    // Применять миграции при старте сервера
+    AutoMigrate bool
+}
+
+// PurgeConfig управляет окончательным удалением мягко удаленных пользователей
+type PurgeConfig struct {
+    // Сколько хранить удаленных пользователей, 0 отключает очистку
+    Retention time.Duration
+    // Как часто запускать очистку
+    Interval time.Duration
+}
+
+// AuthConfig настраивает проверку JWT и API-ключей. Аутентификация включена,
+// если задан JWTSecret или JWKS либо включены API-ключи.
+type AuthConfig struct {
+    // Общий секрет для токенов HS256
+    JWTSecret string
+    // Путь к файлу или URL набора ключей JWKS для RS256 и ES256
+    JWKS string
+    // Как долго кэшировать JWKS
+    JWKSRefresh time.Duration
+    // Ожидаемые iss и aud, пустые не проверяются
+    Issuer   string
+    Audience string
+    // Допустимое расхождение часов при проверке exp и nbf
+    Leeway time.Duration
+    // Claim с ролями, вложенный задается через точку
+    RolesClaim string
+    // Принимать API-ключи из X-API-Key и Authorization: ApiKey
+    APIKeys bool
+    // Пути, открытые без токена. Путь с "/" на конце задает префикс.
+    Exempt []string
+    // JSON-файл политики доступа, пустой - встроенная политика
+    PolicyFile string
+}
+
+// Enabled сообщает, что запросы требуют токен или API-ключ
+func (a AuthConfig) Enabled() bool {
+    return a.JWTSecret != "" || a.JWKS != "" || a.APIKeys
+}
+
+// JWTEnabled сообщает, что принимаются JWT
+func (a AuthConfig) JWTEnabled() bool {
+    return a.JWTSecret != "" || a.JWKS != ""
+}
+
+// RateLimitConfig задает лимиты запросов на клиента
+type RateLimitConfig struct {
+    // Общий лимит на маршруты без собственного, нулевой их не ограничивает
+    Default ratelimit.Limit
+    // Лимиты отдельных маршрутов по ключу "METHOD /шаблон"
+    Routes map[string]ratelimit.Limit
+    // Пути без ограничений. Путь с "/" на конце задает префикс.
+    Exempt []string
+}
+
+// Enabled сообщает, что задан хотя бы один лимит
+func (c RateLimitConfig) Enabled() bool {
+    return !c.Default.IsZero() || len(c.Routes) > 0
+}
+
+// IdempotencyConfig настраивает повтор ответов по заголовку Idempotency-Key
+type IdempotencyConfig struct {
+    // Сколько хранить ответ, 0 отключает поддержку заголовка
+    TTL time.Duration
+    // Сколько ключ остается занятым незавершенным запросом
+    LockTimeout time.Duration
+    // Маршруты "METHOD /шаблон", для которых учитывается заголовок
+    Routes []string
+}
+
+type LogConfig struct {
+    // debug, info, warn или error
+    Level string
+    // json или text
+    Format string
 }
 
 func LoadConfig() (*Config, error) {
     envPaths := []string{
         "../.env",
-        "./.env", 
+        "./.env",
         "/app/.env",
     }
 
@@ -43,18 +146,145 @@ func LoadConfig() (*Config, error) {
         }
     }
 
+    requestTimeout, err := getEnvDuration("SERVER_REQUEST_TIMEOUT", 5*time.Second)
+    if err != nil {
+        return nil, err
+    }
+
+    shutdownTimeout, err := getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 15*time.Second)
+    if err != nil {
+        return nil, err
+    }
+
+    shutdownDelay, err := getEnvDuration("SERVER_SHUTDOWN_DELAY", 5*time.Second)
+    if err != nil {
+        return nil, err
+    }
+
+    autoMigrate, err := getEnvBool("DB_AUTO_MIGRATE", true)
+    if err != nil {
+        return nil, err
+    }
+
+    purgeRetention, err := getEnvDuration("PURGE_RETENTION", 30*24*time.Hour)
+    if err != nil {
+        return nil, err
+    }
+
+    purgeInterval, err := getEnvDuration("PURGE_INTERVAL", time.Hour)
+    if err != nil {
+        return nil, err
+    }
+
+    jwksRefresh, err := getEnvDuration("JWT_JWKS_REFRESH", time.Hour)
+    if err != nil {
+        return nil, err
+    }
+
+    jwtLeeway, err := getEnvDuration("JWT_LEEWAY", 30*time.Second)
+    if err != nil {
+        return nil, err
+    }
+
+    apiKeys, err := getEnvBool("API_KEYS_ENABLED", false)
+    if err != nil {
+        return nil, err
+    }
+
+    var rateLimitDefault ratelimit.Limit
+    if value := getEnv("RATE_LIMIT_DEFAULT", ""); value != "" {
+        if rateLimitDefault, err = ratelimit.ParseLimit(value); err != nil {
+            return nil, fmt.Errorf("invalid RATE_LIMIT_DEFAULT: %w", err)
+        }
+    }
+
+    rateLimitRoutes, err := getEnvRouteLimits("RATE_LIMIT_ROUTES")
+    if err != nil {
+        return nil, err
+    }
+
+    idempotencyTTL, err := getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+    if err != nil {
+        return nil, err
+    }
+
+    idempotencyLockTimeout, err := getEnvDuration("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute)
+    if err != nil {
+        return nil, err
+    }
+
     config := &Config{
         Server: ServerConfig{
-            Port: getEnv("SERVER_PORT", "8000"),
+            Port:            getEnv("SERVER_PORT", "8000"),
+            MetricsPort:     getEnv("METRICS_PORT", "9090"),
+            RequestTimeout:  requestTimeout,
+            ShutdownTimeout: shutdownTimeout,
+            ShutdownDelay:   shutdownDelay,
         },
         Database: DatabaseConfig{
-            Host:     getEnv("DB_HOST", "localhost"),
-            Port:     getEnv("DB_PORT", "5432"),
-            User:     getEnv("DB_USER", "postgres"),
-            Password: getEnv("DB_PASSWORD", "postgres"),
-            DBName:   getEnv("DB_NAME", "users"),
-            SSLMode:  getEnv("DB_SSLMODE", "disable"),
+            Driver:      getEnv("STORAGE_DRIVER", StorageDriverPostgres),
+            Host:        getEnv("DB_HOST", "localhost"),
+            Port:        getEnv("DB_PORT", "5432"),
+            User:        getEnv("DB_USER", "postgres"),
+            Password:    getEnv("DB_PASSWORD", "postgres"),
+            DBName:      getEnv("DB_NAME", "users"),
+            SSLMode:     getEnv("DB_SSLMODE", "disable"),
+            SQLitePath:  getEnv("SQLITE_PATH", "users.db"),
+            AutoMigrate: autoMigrate,
+        },
+        Purge: PurgeConfig{
+            Retention: purgeRetention,
+            Interval:  purgeInterval,
+        },
+        Log: LogConfig{
+            Level:  getEnv("LOG_LEVEL", "info"),
+            Format: getEnv("LOG_FORMAT", "json"),
+        },
+        Auth: AuthConfig{
+            JWTSecret:   getEnv("JWT_SECRET", ""),
+            JWKS:        getEnv("JWT_JWKS", ""),
+            JWKSRefresh: jwksRefresh,
+            Issuer:      getEnv("JWT_ISSUER", ""),
+            Audience:    getEnv("JWT_AUDIENCE", ""),
+            Leeway:      jwtLeeway,
+            RolesClaim:  getEnv("JWT_ROLES_CLAIM", "roles"),
+            APIKeys:     apiKeys,
+            Exempt:      getEnvList("AUTH_EXEMPT", []string{"/health", "/ready", "/swagger/"}),
+            PolicyFile:  getEnv("AUTHZ_POLICY_FILE", ""),
+        },
+        RateLimit: RateLimitConfig{
+            Default: rateLimitDefault,
+            Routes:  rateLimitRoutes,
+            Exempt:  getEnvList("RATE_LIMIT_EXEMPT", []string{"/health", "/ready", "/metrics", "/swagger/"}),
         },
+        Idempotency: IdempotencyConfig{
+            TTL:         idempotencyTTL,
+            LockTimeout: idempotencyLockTimeout,
+            Routes:      getEnvList("IDEMPOTENCY_ROUTES", []string{"POST /users"}),
+        },
+    }
+
+    switch config.Database.Driver {
+    case StorageDriverPostgres, StorageDriverSQLite, StorageDriverMemory:
+    default:
+        return nil, fmt.Errorf("unsupported STORAGE_DRIVER %q", config.Database.Driver)
+    }
+
+    if config.Purge.Retention > 0 && config.Purge.Interval <= 0 {
+        return nil, fmt.Errorf("PURGE_INTERVAL must be positive when PURGE_RETENTION is set")
+    }
+
+    if config.Auth.PolicyFile != "" && !config.Auth.Enabled() {
+        return nil, fmt.Errorf("AUTHZ_POLICY_FILE requires JWT_SECRET, JWT_JWKS or API_KEYS_ENABLED")
+    }
+
+    if config.Auth.JWKS != "" && config.Auth.JWKSRefresh <= 0 {
+        return nil, fmt.Errorf("JWT_JWKS_REFRESH must be positive")
+    }
+
+    // Незавершенный запрос не должен освободить ключ раньше, чем его прервет таймаут
+    if config.Idempotency.TTL > 0 && config.Idempotency.LockTimeout <= config.Server.RequestTimeout {
+        return nil, fmt.Errorf("IDEMPOTENCY_LOCK_TIMEOUT must be greater than SERVER_REQUEST_TIMEOUT")
     }
 
     return config, nil
@@ -67,9 +297,82 @@ func (d *DatabaseConfig) GetDSN() string {
     )
 }
 
+// GetSQLiteDSN возвращает DSN для modernc.org/sqlite. WAL и busy_timeout
+// позволяют читать параллельно с записью, case_sensitive_like делает LIKE
+// чувствительным к регистру, как в PostgreSQL. _txlock=immediate заставляет
+// транзакции сразу брать блокировку на запись, заменяя SELECT ... FOR UPDATE.
+func (d *DatabaseConfig) GetSQLiteDSN() string {
+    return fmt.Sprintf(
+        "file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=case_sensitive_like(1)&_txlock=immediate",
+        d.SQLitePath,
+    )
+}
+
 func getEnv(key, defaultValue string) string {
     if value, exists := os.LookupEnv(key); exists {
         return value
     }
     return defaultValue
 }
+
+// getEnvList читает список через запятую. Пустое значение дает пустой список.
+func getEnvList(key string, defaultValue []string) []string {
+    value, exists := os.LookupEnv(key)
+    if !exists {
+        return defaultValue
+    }
+
+    var list []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            list = append(list, item)
+        }
+    }
+    return list
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+    value, exists := os.LookupEnv(key)
+    if !exists || value == "" {
+        return defaultValue, nil
+    }
+
+    duration, err := time.ParseDuration(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid duration in %s: %w", key, err)
+    }
+    return duration, nil
+}
+
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+    value, exists := os.LookupEnv(key)
+    if !exists || value == "" {
+        return defaultValue, nil
+    }
+
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, fmt.Errorf("invalid boolean in %s: %w", key, err)
+    }
+    return b, nil
+}
+
+// getEnvRouteLimits читает лимиты маршрутов через запятую, например
+// "POST /users=10/m,GET /users/export=2/m". Метод приводится к верхнему регистру.
+func getEnvRouteLimits(key string) (map[string]ratelimit.Limit, error) {
+    limits := make(map[string]ratelimit.Limit)
+    for _, entry := range getEnvList(key, nil) {
+        route, value, found := strings.Cut(entry, "=")
+        method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+        path = strings.TrimSpace(path)
+        if !found || !hasPath || method == "" || !strings.HasPrefix(path, "/") {
+            return nil, fmt.Errorf("invalid %s entry %q, want METHOD /path=<requests>/<period>", key, entry)
+        }
+        limit, err := ratelimit.ParseLimit(value)
+        if err != nil {
+            return nil, fmt.Errorf("invalid %s entry %q: %w", key, entry, err)
+        }
+        limits[strings.ToUpper(method)+" "+path] = limit
+    }
+    return limits, nil
+}
@@ -0,0 +1,161 @@
+// Package idempotency хранит ответы на запросы с заголовком Idempotency-Key,
+// чтобы повтор запроса получил исходный ответ, а не выполнил его еще раз.
+// MemoryStore подходит для одного экземпляра сервиса, для нескольких нужна
+// реализация на общем хранилище.
+package idempotency
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// Response - сохраненный ответ на запрос
+type Response struct {
+    Status int
+    Header http.Header
+    Body   []byte
+}
+
+// ErrNotReserved возвращает Complete, если ключ уже не занят этим запросом:
+// блокировка истекла и ключ освобожден или занят другим запросом
+var ErrNotReserved = errors.New("idempotency key is no longer reserved")
+
+// Record - состояние ключа
+type Record struct {
+    // Fingerprint - хэш запроса, впервые пришедшего с этим ключом
+    Fingerprint string
+    // Response - nil, пока первый запрос еще выполняется
+    Response *Response
+    // Token отличает занятие ключа от следующих после истечения блокировки.
+    // Заполняется только в записи, которую вернул успешный Reserve.
+    Token string
+}
+
+// Store хранит ключи. Reserve должен быть атомарным: из одновременных запросов
+// с одним ключом занять его может только один, в том числе в разных экземплярах
+// сервиса.
+type Store interface {
+    // Reserve занимает key до lockedUntil, если ключ свободен или его срок
+    // истек к моменту now, и возвращает true и запись с Token занятия. Иначе
+    // возвращает текущую запись ключа.
+    Reserve(ctx context.Context, key, fingerprint string, now, lockedUntil time.Time) (*Record, bool, error)
+    // Complete сохраняет ответ и продлевает хранение ключа до expiresAt, если
+    // ключ все еще занят с token, иначе возвращает ErrNotReserved
+    Complete(ctx context.Context, key, token string, response Response, expiresAt time.Time) error
+    // Release освобождает ключ, занятый с token, без ответа, чтобы запрос можно
+    // было повторить. Ключ, занятый заново или с сохраненным ответом, не меняется.
+    Release(ctx context.Context, key, token string) error
+    // DeleteExpired удаляет ключи, срок которых истек к моменту now
+    DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type entry struct {
+    record    Record
+    expiresAt time.Time
+}
+
+// MemoryStore хранит ключи в памяти процесса
+type MemoryStore struct {
+    mu      sync.Mutex
+    entries map[string]*entry
+}
+
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{entries: make(map[string]*entry)}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key, fingerprint string, now, lockedUntil time.Time) (*Record, bool, error) {
+    token, err := NewToken()
+    if err != nil {
+        return nil, false, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+        record := e.record
+        record.Token = ""
+        return &record, false, nil
+    }
+    record := Record{Fingerprint: fingerprint, Token: token}
+    s.entries[key] = &entry{record: record, expiresAt: lockedUntil}
+    return &record, true, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key, token string, response Response, expiresAt time.Time) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    e, ok := s.entries[key]
+    if !ok || e.record.Token != token || e.record.Response != nil {
+        return ErrNotReserved
+    }
+    e.record.Response = &response
+    e.expiresAt = expiresAt
+    return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key, token string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if e, ok := s.entries[key]; ok && e.record.Token == token && e.record.Response == nil {
+        delete(s.entries, key)
+    }
+    return nil
+}
+
+func (s *MemoryStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var deleted int64
+    for key, e := range s.entries {
+        if !now.Before(e.expiresAt) {
+            delete(s.entries, key)
+            deleted++
+        }
+    }
+    return deleted, nil
+}
+
+// NewToken создает случайный Token занятия ключа
+func NewToken() (string, error) {
+    token := make([]byte, 16)
+    if _, err := rand.Read(token); err != nil {
+        return "", fmt.Errorf("failed to generate idempotency token: %w", err)
+    }
+    return hex.EncodeToString(token), nil
+}
+
+// Cleanup возвращает фоновую задачу, удаляющую истекшие ключи каждые interval
+func Cleanup(store Store, interval time.Duration, logger *slog.Logger) func(ctx context.Context) {
+    return func(ctx context.Context) {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+
+            deleted, err := store.DeleteExpired(ctx, time.Now())
+            if err != nil && ctx.Err() == nil {
+                logger.Error("failed to delete expired idempotency keys", slog.Any("error", err))
+            }
+            if deleted > 0 {
+                logger.Debug("deleted expired idempotency keys", slog.Int64("count", deleted))
+            }
+        }
+    }
+}
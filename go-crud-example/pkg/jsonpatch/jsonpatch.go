@@ -0,0 +1,318 @@
+// Package jsonpatch применяет к JSON документам JSON Merge Patch (RFC 7396)
+// и JSON Patch (RFC 6902).
+package jsonpatch
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// Media types патчей
+const (
+    MergePatchType = "application/merge-patch+json"
+    JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+    // ErrInvalidPatch - патч некорректен или не может быть применен к документу
+    ErrInvalidPatch = errors.New("invalid patch")
+    // ErrTestFailed - операция test JSON Patch не совпала с документом
+    ErrTestFailed = errors.New("patch test failed")
+)
+
+// MergePatch применяет JSON Merge Patch к документу
+func MergePatch(doc, patch []byte) ([]byte, error) {
+    var target, p interface{}
+    if err := json.Unmarshal(doc, &target); err != nil {
+        return nil, fmt.Errorf("invalid document: %w", err)
+    }
+    if err := json.Unmarshal(patch, &p); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+    }
+    return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+    p, ok := patch.(map[string]interface{})
+    if !ok {
+        return patch
+    }
+    t, ok := target.(map[string]interface{})
+    if !ok {
+        t = make(map[string]interface{})
+    }
+    for key, value := range p {
+        if value == nil {
+            delete(t, key)
+            continue
+        }
+        t[key] = merge(t[key], value)
+    }
+    return t
+}
+
+type operation struct {
+    Op    string          `json:"op"`
+    Path  *string         `json:"path"`
+    From  *string         `json:"from"`
+    Value json.RawMessage `json:"value"`
+}
+
+// Apply применяет JSON Patch к документу. Операции выполняются по порядку,
+// при ошибке любой из них документ не изменяется.
+func Apply(doc, patch []byte) ([]byte, error) {
+    var target interface{}
+    if err := json.Unmarshal(doc, &target); err != nil {
+        return nil, fmt.Errorf("invalid document: %w", err)
+    }
+
+    var ops []operation
+    if err := json.Unmarshal(patch, &ops); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+    }
+
+    for i, op := range ops {
+        var err error
+        if target, err = op.apply(target); err != nil {
+            return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+        }
+    }
+    return json.Marshal(target)
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+    if op.Path == nil {
+        return nil, fmt.Errorf("%w: missing path", ErrInvalidPatch)
+    }
+    path, err := parsePointer(*op.Path)
+    if err != nil {
+        return nil, err
+    }
+
+    switch op.Op {
+    case "add", "replace", "test":
+        value, err := op.value()
+        if err != nil {
+            return nil, err
+        }
+        switch op.Op {
+        case "add":
+            return add(doc, path, value)
+        case "replace":
+            return replace(doc, path, value)
+        }
+        current, err := get(doc, path)
+        if err != nil {
+            return nil, err
+        }
+        if !reflect.DeepEqual(current, value) {
+            return nil, fmt.Errorf("%w: value at %q differs", ErrTestFailed, *op.Path)
+        }
+        return doc, nil
+    case "remove":
+        return remove(doc, path)
+    case "move", "copy":
+        if op.From == nil {
+            return nil, fmt.Errorf("%w: missing from", ErrInvalidPatch)
+        }
+        from, err := parsePointer(*op.From)
+        if err != nil {
+            return nil, err
+        }
+        value, err := get(doc, from)
+        if err != nil {
+            return nil, err
+        }
+        if op.Op == "copy" {
+            return add(doc, path, deepCopy(value))
+        }
+        if isPrefix(from, path) && len(from) < len(path) {
+            return nil, fmt.Errorf("%w: cannot move %q into its child", ErrInvalidPatch, *op.From)
+        }
+        if doc, err = remove(doc, from); err != nil {
+            return nil, err
+        }
+        return add(doc, path, value)
+    default:
+        return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+    }
+}
+
+func (op operation) value() (interface{}, error) {
+    if op.Value == nil {
+        return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+    }
+    var value interface{}
+    if err := json.Unmarshal(op.Value, &value); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+    }
+    return value, nil
+}
+
+// parsePointer разбирает JSON Pointer (RFC 6901) на токены
+func parsePointer(pointer string) ([]string, error) {
+    if pointer == "" {
+        return nil, nil
+    }
+    if pointer[0] != '/' {
+        return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalidPatch, pointer)
+    }
+
+    tokens := strings.Split(pointer[1:], "/")
+    for i, token := range tokens {
+        tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+    }
+    return tokens, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+    node := doc
+    for _, token := range path {
+        switch container := node.(type) {
+        case map[string]interface{}:
+            value, ok := container[token]
+            if !ok {
+                return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, token)
+            }
+            node = value
+        case []interface{}:
+            i, err := index(token, len(container)-1)
+            if err != nil {
+                return nil, err
+            }
+            node = container[i]
+        default:
+            return nil, fmt.Errorf("%w: cannot reference %q in a scalar value", ErrInvalidPatch, token)
+        }
+    }
+    return node, nil
+}
+
+// update находит контейнер, содержащий последний токен пути, заменяет его
+// результатом fn и возвращает измененный документ
+func update(doc interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+    if len(path) == 1 {
+        return fn(doc, path[0])
+    }
+
+    child, err := get(doc, path[:1])
+    if err != nil {
+        return nil, err
+    }
+    child, err = update(child, path[1:], fn)
+    if err != nil {
+        return nil, err
+    }
+
+    switch container := doc.(type) {
+    case map[string]interface{}:
+        container[path[0]] = child
+    case []interface{}:
+        i, _ := index(path[0], len(container)-1)
+        container[i] = child
+    }
+    return doc, nil
+}
+
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+    if len(path) == 0 {
+        return value, nil
+    }
+    return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+        switch c := container.(type) {
+        case map[string]interface{}:
+            c[token] = value
+            return c, nil
+        case []interface{}:
+            i := len(c)
+            if token != "-" {
+                var err error
+                if i, err = index(token, len(c)); err != nil {
+                    return nil, err
+                }
+            }
+            c = append(c, nil)
+            copy(c[i+1:], c[i:])
+            c[i] = value
+            return c, nil
+        default:
+            return nil, fmt.Errorf("%w: cannot add %q to a scalar value", ErrInvalidPatch, token)
+        }
+    })
+}
+
+func remove(doc interface{}, path []string) (interface{}, error) {
+    if len(path) == 0 {
+        return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+    }
+    return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+        if _, err := get(container, []string{token}); err != nil {
+            return nil, err
+        }
+        switch c := container.(type) {
+        case map[string]interface{}:
+            delete(c, token)
+            return c, nil
+        default:
+            items := c.([]interface{})
+            i, _ := index(token, len(items)-1)
+            return append(items[:i], items[i+1:]...), nil
+        }
+    })
+}
+
+func replace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+    if len(path) == 0 {
+        return value, nil
+    }
+    return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+        if _, err := get(container, []string{token}); err != nil {
+            return nil, err
+        }
+        switch c := container.(type) {
+        case map[string]interface{}:
+            c[token] = value
+            return c, nil
+        default:
+            items := c.([]interface{})
+            i, _ := index(token, len(items)-1)
+            items[i] = value
+            return items, nil
+        }
+    })
+}
+
+// index разбирает индекс массива: без ведущих нулей и не больше last
+func index(token string, last int) (int, error) {
+    if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+        return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+    }
+    i, err := strconv.Atoi(token)
+    if err != nil || i > last {
+        return 0, fmt.Errorf("%w: array index %q out of range", ErrInvalidPatch, token)
+    }
+    return i, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+    if len(prefix) > len(path) {
+        return false
+    }
+    for i := range prefix {
+        if prefix[i] != path[i] {
+            return false
+        }
+    }
+    return true
+}
+
+func deepCopy(value interface{}) interface{} {
+    data, _ := json.Marshal(value)
+    var result interface{}
+    _ = json.Unmarshal(data, &result)
+    return result
+}
@@ -0,0 +1,335 @@
+package jwt
+
+import (
+    "context"
+    "crypto/ecdh"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rsa"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "math/big"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// maxJWKSSize ограничивает размер загружаемого набора ключей
+const maxJWKSSize = 1 << 20
+
+// minReloadInterval - как часто набор может перечитываться после предыдущей
+// попытки, в том числе неудачной или вызванной токеном с неизвестным kid.
+// Защищает источник ключей от перебора kid, а API - от повторных запросов
+// к недоступному источнику.
+const minReloadInterval = 30 * time.Second
+
+// JWKS - набор открытых ключей (RFC 7517) из файла или по URL. Ключи
+// кэшируются на refresh и перечитываются раньше, если пришел токен с
+// неизвестным kid, что позволяет сменить ключи подписи без перезапуска.
+// Устаревший набор перечитывается в фоне, а до окончания загрузки и при
+// ошибке используются ранее загруженные ключи.
+type JWKS struct {
+    source  string
+    refresh time.Duration
+    client  *http.Client
+
+    mu       sync.Mutex
+    keys     map[string]jwk
+    loadedAt time.Time
+    // triedAt и lastErr - время и ошибка последней попытки загрузки
+    triedAt time.Time
+    lastErr error
+    // loading закрывается по окончании текущей загрузки, nil - загрузки нет
+    loading chan struct{}
+}
+
+// jwk - ключ из набора с ограничениями на его использование
+type jwk struct {
+    alg string
+    key interface{}
+}
+
+// NewJWKS создает набор ключей. source - путь к файлу или URL http(s).
+// Ключи загружаются при первой проверке токена.
+func NewJWKS(source string, refresh time.Duration) *JWKS {
+    return &JWKS{
+        source:  source,
+        refresh: refresh,
+        client:  &http.Client{Timeout: 10 * time.Second},
+    }
+}
+
+// Load загружает набор ключей. Позволяет проверить источник при старте.
+func (s *JWKS) Load(ctx context.Context) error {
+    s.mu.Lock()
+    done := s.startLoad()
+    s.mu.Unlock()
+
+    if err := wait(ctx, done); err != nil {
+        return err
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.lastErr
+}
+
+func (s *JWKS) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if (s.keys == nil || time.Since(s.loadedAt) >= s.refresh) && s.canReload() {
+        done := s.startLoad()
+        // Без загруженных ключей проверять нечем, остается дождаться загрузки
+        if s.keys == nil {
+            if err := s.waitUnlocked(ctx, done); err != nil {
+                return nil, err
+            }
+        }
+    }
+    if s.keys == nil {
+        return nil, s.lastErr
+    }
+
+    key, ok := s.find(kid, alg)
+    // Новый kid мог появиться после ротации ключей. Токен без kid такой
+    // загрузки не ждет: его может проверить следующий набор, например секрет
+    if !ok && kid != "" && s.canReload() {
+        if err := s.waitUnlocked(ctx, s.startLoad()); err != nil {
+            return nil, err
+        }
+        key, ok = s.find(kid, alg)
+    }
+    if !ok {
+        return nil, ErrUnknownKey
+    }
+    return key, nil
+}
+
+// find ищет ключ по kid и алгоритму. Токен без kid принимается, только если
+// в наборе ровно один ключ.
+func (s *JWKS) find(kid, alg string) (interface{}, bool) {
+    k, ok := s.keys[kid]
+    if kid == "" && !ok && len(s.keys) == 1 {
+        for _, only := range s.keys {
+            k, ok = only, true
+        }
+    }
+    if !ok || k.alg != alg {
+        return nil, false
+    }
+    return k.key, true
+}
+
+// canReload сообщает, что можно начать загрузку или присоединиться к текущей.
+// Вызывается под s.mu.
+func (s *JWKS) canReload() bool {
+    return s.loading != nil || time.Since(s.triedAt) >= min(s.refresh, minReloadInterval)
+}
+
+// startLoad запускает загрузку набора в фоне, если она еще не идет, и
+// возвращает канал, который закроется по ее окончании. Одновременные запросы
+// ждут одну загрузку. Вызывается под s.mu.
+func (s *JWKS) startLoad() chan struct{} {
+    if s.loading != nil {
+        return s.loading
+    }
+    done := make(chan struct{})
+    s.loading = done
+    s.triedAt = time.Now()
+
+    go func() {
+        // Загрузка общая для всех ожидающих, поэтому не зависит от контекста
+        // запроса; время ограничено таймаутом клиента
+        keys, err := s.load(context.Background())
+
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        if err == nil {
+            s.keys = keys
+            s.loadedAt = time.Now()
+        }
+        s.lastErr = err
+        s.loading = nil
+        close(done)
+    }()
+    return done
+}
+
+// waitUnlocked ждет окончания загрузки, отпустив s.mu. Вызывается под s.mu.
+func (s *JWKS) waitUnlocked(ctx context.Context, done chan struct{}) error {
+    s.mu.Unlock()
+    defer s.mu.Lock()
+    return wait(ctx, done)
+}
+
+func wait(ctx context.Context, done chan struct{}) error {
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// load читает и разбирает набор ключей
+func (s *JWKS) load(ctx context.Context) (map[string]jwk, error) {
+    data, err := s.read(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to load JWKS from %s: %w", s.source, err)
+    }
+    keys, err := parseJWKS(data)
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse JWKS from %s: %w", s.source, err)
+    }
+    return keys, nil
+}
+
+func (s *JWKS) read(ctx context.Context) ([]byte, error) {
+    if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+        return os.ReadFile(s.source)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("unexpected status %s", resp.Status)
+    }
+    data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+    if err != nil {
+        return nil, err
+    }
+    if len(data) > maxJWKSSize {
+        return nil, errors.New("key set is too large")
+    }
+    return data, nil
+}
+
+// rawJWK - ключ в формате JSON. Поддерживаются RSA, EC P-256 и oct.
+type rawJWK struct {
+    Kty string `json:"kty"`
+    Kid string `json:"kid"`
+    Use string `json:"use"`
+    Alg string `json:"alg"`
+    N   string `json:"n"`
+    E   string `json:"e"`
+    Crv string `json:"crv"`
+    X   string `json:"x"`
+    Y   string `json:"y"`
+    K   string `json:"k"`
+}
+
+// parseJWKS разбирает набор ключей. Ключи шифрования и неподдерживаемых
+// типов пропускаются, некорректный ключ поддерживаемого типа - ошибка.
+func parseJWKS(data []byte) (map[string]jwk, error) {
+    var set struct {
+        Keys []rawJWK `json:"keys"`
+    }
+    if err := json.Unmarshal(data, &set); err != nil {
+        return nil, err
+    }
+
+    keys := make(map[string]jwk, len(set.Keys))
+    for i, raw := range set.Keys {
+        if raw.Use != "" && raw.Use != "sig" {
+            continue
+        }
+
+        var (
+            key interface{}
+            alg string
+            err error
+        )
+        switch raw.Kty {
+        case "RSA":
+            key, alg, err = parseRSAKey(raw)
+        case "EC":
+            // ES256 использует только P-256
+            if raw.Crv != "P-256" {
+                continue
+            }
+            key, alg, err = parseECKey(raw)
+        case "oct":
+            key, alg, err = parseOctKey(raw)
+        default:
+            continue
+        }
+        if err != nil {
+            return nil, fmt.Errorf("key %d (kid %q): %w", i, raw.Kid, err)
+        }
+        // Ключ, предназначенный для другого алгоритма, не используется
+        if raw.Alg != "" && raw.Alg != alg {
+            continue
+        }
+        if _, exists := keys[raw.Kid]; exists {
+            return nil, fmt.Errorf("duplicate kid %q", raw.Kid)
+        }
+        keys[raw.Kid] = jwk{alg: alg, key: key}
+    }
+    return keys, nil
+}
+
+func parseRSAKey(raw rawJWK) (interface{}, string, error) {
+    n, err := decodeBigInt(raw.N)
+    if err != nil {
+        return nil, "", fmt.Errorf("invalid n: %w", err)
+    }
+    e, err := decodeBigInt(raw.E)
+    if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+        return nil, "", errors.New("invalid e")
+    }
+    if n.BitLen() < 2048 {
+        return nil, "", errors.New("RSA key must be at least 2048 bits")
+    }
+    return &rsa.PublicKey{N: n, E: int(e.Int64())}, RS256, nil
+}
+
+func parseECKey(raw rawJWK) (interface{}, string, error) {
+    x, errX := base64.RawURLEncoding.DecodeString(raw.X)
+    y, errY := base64.RawURLEncoding.DecodeString(raw.Y)
+    if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+        return nil, "", errors.New("invalid coordinates")
+    }
+
+    // ecdh проверяет, что точка лежит на кривой
+    point := append(append([]byte{4}, x...), y...)
+    if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+        return nil, "", errors.New("point is not on the curve")
+    }
+    return &ecdsa.PublicKey{
+        Curve: elliptic.P256(),
+        X:     new(big.Int).SetBytes(x),
+        Y:     new(big.Int).SetBytes(y),
+    }, ES256, nil
+}
+
+func parseOctKey(raw rawJWK) (interface{}, string, error) {
+    k, err := base64.RawURLEncoding.DecodeString(raw.K)
+    if err != nil || len(k) == 0 {
+        return nil, "", errors.New("invalid k")
+    }
+    return k, HS256, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+    data, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    if len(data) == 0 {
+        return nil, errors.New("empty value")
+    }
+    return new(big.Int).SetBytes(data), nil
+}
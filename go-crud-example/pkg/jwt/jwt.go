@@ -0,0 +1,314 @@
+// Package jwt проверяет JSON Web Token (RFC 7519) с подписью HS256, RS256 или
+// ES256. Ключи проверки берутся из KeySet: общий секрет или набор JWKS.
+package jwt
+
+import (
+    "context"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/hmac"
+    "crypto/rsa"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "math/big"
+    "strings"
+    "time"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+    HS256 = "HS256"
+    RS256 = "RS256"
+    ES256 = "ES256"
+)
+
+// ErrInvalidToken - общая ошибка непринятого токена. Конкретные причины
+// оборачивают ее, их текст можно показывать клиенту.
+var ErrInvalidToken = errors.New("invalid token")
+
+var (
+    ErrMalformed            = fmt.Errorf("%w: malformed token", ErrInvalidToken)
+    ErrUnsupportedAlgorithm = fmt.Errorf("%w: unsupported signing algorithm", ErrInvalidToken)
+    ErrUnknownKey           = fmt.Errorf("%w: unknown signing key", ErrInvalidToken)
+    ErrSignature            = fmt.Errorf("%w: signature is invalid", ErrInvalidToken)
+    ErrExpired              = fmt.Errorf("%w: token is expired", ErrInvalidToken)
+    ErrNotValidYet          = fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+    ErrInvalidClaims        = fmt.Errorf("%w: invalid claims", ErrInvalidToken)
+)
+
+// KeySet находит ключ проверки подписи по kid и alg из заголовка токена.
+// Ключ - []byte для HS256, *rsa.PublicKey для RS256 и *ecdsa.PublicKey для ES256.
+type KeySet interface {
+    Key(ctx context.Context, kid, alg string) (interface{}, error)
+}
+
+// Secret - KeySet с одним общим секретом для HS256
+type Secret []byte
+
+func (s Secret) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+    if alg != HS256 {
+        return nil, ErrUnknownKey
+    }
+    return []byte(s), nil
+}
+
+// KeySets ищет ключ в наборах по порядку, например сначала в JWKS, где ключ
+// выбирается по kid и алгоритму, затем в секрете HS256, который подходит к
+// любому токену HS256. Поэтому наборы с выбором ключа идут первыми.
+type KeySets []KeySet
+
+func (s KeySets) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+    for _, keys := range s {
+        key, err := keys.Key(ctx, kid, alg)
+        if !errors.Is(err, ErrUnknownKey) {
+            return key, err
+        }
+    }
+    return nil, ErrUnknownKey
+}
+
+// Claims - зарегистрированные claims токена. Roles берутся из claim, заданного
+// Options.RolesClaim. Raw содержит все claims, включая нестандартные.
+type Claims struct {
+    Subject   string                 `json:"sub"`
+    Issuer    string                 `json:"iss"`
+    Audience  Audience               `json:"aud"`
+    ExpiresAt *NumericDate           `json:"exp"`
+    NotBefore *NumericDate           `json:"nbf"`
+    IssuedAt  *NumericDate           `json:"iat"`
+    Roles     []string               `json:"-"`
+    Raw       map[string]interface{} `json:"-"`
+}
+
+// Audience - claim aud, который по RFC 7519 может быть строкой или массивом строк
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+    var single string
+    if err := json.Unmarshal(data, &single); err == nil {
+        *a = Audience{single}
+        return nil
+    }
+    var list []string
+    if err := json.Unmarshal(data, &list); err != nil {
+        return errors.New("aud must be a string or an array of strings")
+    }
+    *a = list
+    return nil
+}
+
+func (a Audience) Contains(audience string) bool {
+    for _, v := range a {
+        if v == audience {
+            return true
+        }
+    }
+    return false
+}
+
+// NumericDate - время в секундах от начала эпохи, возможно дробное
+type NumericDate struct {
+    time.Time
+}
+
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+    var seconds float64
+    if err := json.Unmarshal(data, &seconds); err != nil {
+        return errors.New("date must be a number of seconds")
+    }
+    whole, frac := math.Modf(seconds)
+    d.Time = time.Unix(int64(whole), int64(frac*1e9)).UTC()
+    return nil
+}
+
+// Options - требования к claims проверяемых токенов
+type Options struct {
+    // Issuer - ожидаемый iss, пустой не проверяется
+    Issuer string
+    // Audience - значение, которое должно быть в aud, пустое не проверяется
+    Audience string
+    // Leeway - допустимое расхождение часов при проверке exp и nbf
+    Leeway time.Duration
+    // RolesClaim - claim со списком ролей или одной ролью. Вложенный claim
+    // задается через точку, например realm_access.roles. По умолчанию roles.
+    RolesClaim string
+}
+
+// Verifier проверяет подпись и claims токенов
+type Verifier struct {
+    keys KeySet
+    opts Options
+}
+
+func NewVerifier(keys KeySet, opts Options) *Verifier {
+    return &Verifier{keys: keys, opts: opts}
+}
+
+type header struct {
+    Alg string `json:"alg"`
+    Kid string `json:"kid"`
+}
+
+// Verify возвращает claims токена, если подпись верна, токен действует и
+// claims соответствуют Options. Токен без exp или sub не принимается.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 {
+        return nil, ErrMalformed
+    }
+
+    var h header
+    if err := decodeSegment(parts[0], &h); err != nil {
+        return nil, err
+    }
+    signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, ErrMalformed
+    }
+
+    switch h.Alg {
+    case HS256, RS256, ES256:
+    default:
+        return nil, fmt.Errorf("%w %q", ErrUnsupportedAlgorithm, h.Alg)
+    }
+    key, err := v.keys.Key(ctx, h.Kid, h.Alg)
+    if err != nil {
+        return nil, err
+    }
+    if err := verifySignature(h.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+        return nil, err
+    }
+
+    var claims Claims
+    if err := decodeSegment(parts[1], &claims); err != nil {
+        return nil, err
+    }
+    if err := decodeSegment(parts[1], &claims.Raw); err != nil {
+        return nil, err
+    }
+    if err := v.validate(&claims, time.Now()); err != nil {
+        return nil, err
+    }
+    if claims.Roles, err = v.roles(claims.Raw); err != nil {
+        return nil, err
+    }
+    return &claims, nil
+}
+
+// roles читает роли из claim Options.RolesClaim. Отсутствующий claim - пустой список.
+func (v *Verifier) roles(raw map[string]interface{}) ([]string, error) {
+    name := v.opts.RolesClaim
+    if name == "" {
+        name = "roles"
+    }
+
+    var value interface{} = raw
+    for _, key := range strings.Split(name, ".") {
+        object, ok := value.(map[string]interface{})
+        if !ok {
+            return nil, nil
+        }
+        if value, ok = object[key]; !ok {
+            return nil, nil
+        }
+    }
+
+    switch value := value.(type) {
+    case string:
+        return []string{value}, nil
+    case []interface{}:
+        roles := make([]string, 0, len(value))
+        for _, item := range value {
+            role, ok := item.(string)
+            if !ok {
+                return nil, fmt.Errorf("%w: %s must contain strings", ErrInvalidClaims, name)
+            }
+            roles = append(roles, role)
+        }
+        return roles, nil
+    default:
+        return nil, fmt.Errorf("%w: %s must be a string or an array of strings", ErrInvalidClaims, name)
+    }
+}
+
+func (v *Verifier) validate(c *Claims, now time.Time) error {
+    if c.ExpiresAt == nil {
+        return fmt.Errorf("%w: exp is required", ErrInvalidClaims)
+    }
+    if !now.Before(c.ExpiresAt.Add(v.opts.Leeway)) {
+        return ErrExpired
+    }
+    if c.NotBefore != nil && now.Add(v.opts.Leeway).Before(c.NotBefore.Time) {
+        return ErrNotValidYet
+    }
+    if c.Subject == "" {
+        return fmt.Errorf("%w: sub is required", ErrInvalidClaims)
+    }
+    if v.opts.Issuer != "" && c.Issuer != v.opts.Issuer {
+        return fmt.Errorf("%w: unexpected issuer", ErrInvalidClaims)
+    }
+    if v.opts.Audience != "" && !c.Audience.Contains(v.opts.Audience) {
+        return fmt.Errorf("%w: unexpected audience", ErrInvalidClaims)
+    }
+    return nil
+}
+
+// verifySignature проверяет подпись. Тип ключа должен соответствовать
+// алгоритму, иначе открытый RSA-ключ можно было бы использовать как секрет HS256.
+func verifySignature(alg string, key interface{}, signed string, signature []byte) error {
+    switch alg {
+    case HS256:
+        secret, ok := key.([]byte)
+        if !ok || len(secret) == 0 {
+            return ErrUnknownKey
+        }
+        mac := hmac.New(sha256.New, secret)
+        mac.Write([]byte(signed))
+        if !hmac.Equal(mac.Sum(nil), signature) {
+            return ErrSignature
+        }
+    case RS256:
+        pub, ok := key.(*rsa.PublicKey)
+        if !ok {
+            return ErrUnknownKey
+        }
+        digest := sha256.Sum256([]byte(signed))
+        if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+            return ErrSignature
+        }
+    case ES256:
+        pub, ok := key.(*ecdsa.PublicKey)
+        if !ok || pub.Curve != elliptic.P256() {
+            return ErrUnknownKey
+        }
+        // Подпись JWS - r и s фиксированной длины подряд (RFC 7518, раздел 3.4)
+        if len(signature) != 64 {
+            return ErrSignature
+        }
+        r := new(big.Int).SetBytes(signature[:32])
+        s := new(big.Int).SetBytes(signature[32:])
+        digest := sha256.Sum256([]byte(signed))
+        if !ecdsa.Verify(pub, digest[:], r, s) {
+            return ErrSignature
+        }
+    default:
+        return ErrUnsupportedAlgorithm
+    }
+    return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+    data, err := base64.RawURLEncoding.DecodeString(segment)
+    if err != nil {
+        return ErrMalformed
+    }
+    if err := json.Unmarshal(data, v); err != nil {
+        return fmt.Errorf("%w: %v", ErrMalformed, err)
+    }
+    return nil
+}
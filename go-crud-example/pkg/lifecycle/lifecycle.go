@@ -0,0 +1,235 @@
+package lifecycle
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log/slog"
+    "net"
+    "net/http"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// requestCancelTimeout - сколько ждать завершения запросов после отмены их
+// контекста, если они не успели завершиться за время остановки
+const requestCancelTimeout = 5 * time.Second
+
+// Manager управляет жизненным циклом HTTP серверов, фоновых задач и ресурсов
+// приложения. При остановке сначала переводит readiness в состояние "не готов",
+// ждет, пока балансировщик перестанет присылать трафик, затем дожидается
+// завершения текущих запросов и фоновых задач и освобождает ресурсы в обратном порядке.
+// Запросы, не завершившиеся за время остановки (например, потоковая выгрузка),
+// отменяются через контекст до освобождения ресурсов, которыми они пользуются.
+type Manager struct {
+    logger          *slog.Logger
+    shutdownTimeout time.Duration
+    readinessDelay  time.Duration
+
+    servers []namedServer
+    tasks   []namedTask
+    closers []namedCloser
+    ready   atomic.Bool
+
+    stopTasks context.CancelFunc
+    tasksDone sync.WaitGroup
+
+    // requestsCtx - базовый контекст запросов всех серверов
+    requestsCtx    context.Context
+    cancelRequests context.CancelFunc
+    requests       sync.WaitGroup
+}
+
+type namedServer struct {
+    name   string
+    server *http.Server
+}
+
+type namedTask struct {
+    name string
+    run  func(ctx context.Context)
+}
+
+type namedCloser struct {
+    name  string
+    close func(ctx context.Context) error
+}
+
+func NewManager(logger *slog.Logger, shutdownTimeout, readinessDelay time.Duration) *Manager {
+    requestsCtx, cancelRequests := context.WithCancel(context.Background())
+    return &Manager{
+        logger:          logger,
+        shutdownTimeout: shutdownTimeout,
+        readinessDelay:  readinessDelay,
+        requestsCtx:     requestsCtx,
+        cancelRequests:  cancelRequests,
+    }
+}
+
+// AddServer регистрирует сервер, который будет запущен в Run. Контекст
+// запросов сервера отменяется при остановке, если они не успели завершиться.
+func (m *Manager) AddServer(name string, server *http.Server) {
+    handler := server.Handler
+    if handler == nil {
+        handler = http.DefaultServeMux
+    }
+    server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        m.requests.Add(1)
+        defer m.requests.Done()
+        handler.ServeHTTP(w, r)
+    })
+    server.BaseContext = func(net.Listener) context.Context {
+        return m.requestsCtx
+    }
+    m.servers = append(m.servers, namedServer{name: name, server: server})
+}
+
+// Go регистрирует фоновую задачу. Задача запускается в Run и должна
+// завершиться после отмены переданного контекста при остановке.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+    m.tasks = append(m.tasks, namedTask{name: name, run: fn})
+}
+
+// OnShutdown регистрирует функцию освобождения ресурса. Функции вызываются
+// после остановки серверов в порядке, обратном регистрации.
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+    m.closers = append(m.closers, namedCloser{name: name, close: fn})
+}
+
+// Ready сообщает, готово ли приложение принимать трафик
+func (m *Manager) Ready() bool {
+    return m.ready.Load()
+}
+
+// ReadinessHandler отвечает 200, пока приложение готово, и 503 после начала остановки
+func (m *Manager) ReadinessHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        status, code := "OK", http.StatusOK
+        if !m.Ready() {
+            status, code = "SHUTTING_DOWN", http.StatusServiceUnavailable
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(code)
+        _ = json.NewEncoder(w).Encode(map[string]string{"status": status})
+    })
+}
+
+// Run запускает все серверы и блокируется до отмены ctx или ошибки одного
+// из серверов, после чего выполняет корректную остановку.
+func (m *Manager) Run(ctx context.Context) error {
+    errCh := make(chan error, len(m.servers))
+
+    for _, s := range m.servers {
+        // Открываем порт синхронно, чтобы ошибка занятого порта не терялась
+        listener, err := net.Listen("tcp", s.server.Addr)
+        if err != nil {
+            return errors.Join(fmt.Errorf("%s server: %w", s.name, err), m.shutdown())
+        }
+
+        m.logger.Info("server started", slog.String("server", s.name), slog.String("addr", listener.Addr().String()))
+        go func(s namedServer) {
+            if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+                errCh <- fmt.Errorf("%s server: %w", s.name, err)
+            }
+        }(s)
+    }
+    m.startTasks()
+    m.ready.Store(true)
+
+    var runErr error
+    select {
+    case <-ctx.Done():
+        m.logger.Info("shutdown signal received")
+    case runErr = <-errCh:
+        m.logger.Error("server failed", slog.Any("error", runErr))
+    }
+
+    return errors.Join(runErr, m.shutdown())
+}
+
+func (m *Manager) startTasks() {
+    ctx, cancel := context.WithCancel(context.Background())
+    m.stopTasks = cancel
+
+    for _, t := range m.tasks {
+        m.tasksDone.Add(1)
+        m.logger.Info("background task started", slog.String("task", t.name))
+        go func(t namedTask) {
+            defer m.tasksDone.Done()
+            t.run(ctx)
+        }(t)
+    }
+}
+
+func (m *Manager) shutdown() error {
+    m.ready.Store(false)
+
+    if m.readinessDelay > 0 && len(m.servers) > 0 {
+        m.logger.Info("readiness disabled, waiting for traffic to drain", slog.Duration("delay", m.readinessDelay))
+        time.Sleep(m.readinessDelay)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+    defer cancel()
+
+    var (
+        wg   sync.WaitGroup
+        mu   sync.Mutex
+        errs []error
+    )
+    for _, s := range m.servers {
+        wg.Add(1)
+        go func(s namedServer) {
+            defer wg.Done()
+            if err := s.server.Shutdown(ctx); err != nil {
+                mu.Lock()
+                errs = append(errs, fmt.Errorf("%s server shutdown: %w", s.name, err))
+                mu.Unlock()
+            }
+        }(s)
+    }
+    wg.Wait()
+
+    // Незавершенные запросы отменяются и должны закончиться до закрытия ресурсов
+    m.cancelRequests()
+    if len(errs) > 0 {
+        done := make(chan struct{})
+        go func() {
+            m.requests.Wait()
+            close(done)
+        }()
+        select {
+        case <-done:
+        case <-time.After(requestCancelTimeout):
+            errs = append(errs, errors.New("requests did not finish after cancellation"))
+        }
+    }
+
+    // Фоновые задачи могут использовать ресурсы, поэтому останавливаются до них
+    if m.stopTasks != nil {
+        m.stopTasks()
+        done := make(chan struct{})
+        go func() {
+            m.tasksDone.Wait()
+            close(done)
+        }()
+        select {
+        case <-done:
+        case <-ctx.Done():
+            errs = append(errs, fmt.Errorf("background tasks: %w", ctx.Err()))
+        }
+    }
+
+    for i := len(m.closers) - 1; i >= 0; i-- {
+        c := m.closers[i]
+        if err := c.close(ctx); err != nil {
+            errs = append(errs, fmt.Errorf("%s close: %w", c.name, err))
+        }
+    }
+
+    m.logger.Info("shutdown complete")
+    return errors.Join(errs...)
+}
@@ -1,10 +1,83 @@
 package logger
 
 import (
-    "log"
+    "context"
+    "fmt"
+    "io"
+    "log/slog"
     "os"
+    "strings"
+    "sync"
+
+    "go-crud-example/pkg/config"
 )
 
-func NewLogger() *log.Logger {
-    return log.New(os.Stdout, "", log.Ldate|log.Ltime)
-}
\ No newline at end of file
+// NewLogger создает структурированный логгер с форматом и уровнем из конфигурации
+func NewLogger(cfg config.LogConfig) (*slog.Logger, error) {
+    return New(os.Stdout, cfg)
+}
+
+func New(w io.Writer, cfg config.LogConfig) (*slog.Logger, error) {
+    var level slog.Level
+    if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+        return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+    }
+
+    opts := &slog.HandlerOptions{Level: level}
+    switch strings.ToLower(cfg.Format) {
+    case "json":
+        return slog.New(slog.NewJSONHandler(w, opts)), nil
+    case "text":
+        return slog.New(slog.NewTextHandler(w, opts)), nil
+    default:
+        return nil, fmt.Errorf("invalid log format %q: expected json or text", cfg.Format)
+    }
+}
+
+type ctxKey struct{}
+
+// scope хранит логгер запроса и атрибуты, которые добавляются по ходу его обработки
+type scope struct {
+    logger *slog.Logger
+    mu     sync.Mutex
+    attrs  []slog.Attr
+}
+
+// NewContext привязывает логгер к контексту запроса
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+    return context.WithValue(ctx, ctxKey{}, &scope{logger: logger})
+}
+
+// FromContext возвращает логгер запроса со всеми добавленными атрибутами.
+// Если логгер к контексту не привязан, возвращается fallback.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+    s, ok := ctx.Value(ctxKey{}).(*scope)
+    if !ok {
+        return fallback
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if len(s.attrs) == 0 {
+        return s.logger
+    }
+
+    args := make([]any, len(s.attrs))
+    for i, attr := range s.attrs {
+        args[i] = attr
+    }
+    return s.logger.With(args...)
+}
+
+// AddAttrs добавляет атрибуты к логгеру запроса, например идентификатор
+// пользователя, известный только обработчику
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+    s, ok := ctx.Value(ctxKey{}).(*scope)
+    if !ok {
+        return
+    }
+
+    s.mu.Lock()
+    s.attrs = append(s.attrs, attrs...)
+    s.mu.Unlock()
+}
@@ -1,7 +1,10 @@
 package metrics
 
 import (
+    "database/sql"
+
     "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/collectors"
     "github.com/prometheus/client_golang/prometheus/promauto"
 )
 
@@ -29,4 +32,25 @@ var (
         },
         []string{"query_type"},
     )
+
+    RateLimitedTotal = promauto.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "http_requests_rate_limited_total",
+            Help: "Total number of HTTP requests rejected by the rate limiter",
+        },
+        []string{"method", "endpoint"},
+    )
+
+    UsersPurgedTotal = promauto.NewCounter(
+        prometheus.CounterOpts{
+            Name: "users_purged_total",
+            Help: "Total number of soft-deleted users permanently removed by the purge job",
+        },
+    )
 )
+
+// RegisterDBStats публикует статистику пула соединений sql.DBStats
+// (открытые, занятые и простаивающие соединения, ожидания) как метрики
+func RegisterDBStats(db *sql.DB, dbName string) error {
+    return prometheus.Register(collectors.NewDBStatsCollector(db, dbName))
+}
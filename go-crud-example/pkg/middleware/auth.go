@@ -0,0 +1,155 @@
+package middleware
+
+import (
+    "context"
+    "errors"
+    "log/slog"
+    "net/http"
+    "strings"
+    "time"
+
+    "go-crud-example/pkg/actor"
+    "go-crud-example/pkg/jwt"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/problem"
+)
+
+// CodeUnauthorized - код ответа 401 для запросов без действительного токена
+const CodeUnauthorized = "unauthorized"
+
+type claimsKey struct{}
+
+// ClaimsFromContext возвращает claims токена, с которым пришел запрос
+func ClaimsFromContext(ctx context.Context) (*jwt.Claims, bool) {
+    claims, ok := ctx.Value(claimsKey{}).(*jwt.Claims)
+    return claims, ok
+}
+
+// ErrInvalidAPIKey возвращает APIKeyFunc для неизвестного, отозванного или
+// истекшего ключа. Остальные ошибки считаются сбоем сервиса.
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKeyFunc проверяет API-ключ и возвращает актора, разрешенные ему действия
+// и срок действия ключа (nil - бессрочный)
+type APIKeyFunc func(ctx context.Context, key string) (subject string, scopes []string, expiresAt *time.Time, err error)
+
+// AuthOptions задает принимаемые способы аутентификации. Должен быть задан
+// хотя бы один из Verifier и APIKey.
+type AuthOptions struct {
+    // Verifier проверяет JWT из Authorization: Bearer, nil отключает JWT
+    Verifier *jwt.Verifier
+    // APIKey проверяет ключи из X-API-Key и Authorization: ApiKey, nil отключает их
+    APIKey APIKeyFunc
+    // Exempt - пути, открытые без аутентификации: путь с "/" на конце задает
+    // префикс (например /swagger/), остальные сравниваются целиком
+    Exempt []string
+}
+
+// AuthMiddleware требует действительный JWT или API-ключ.
+// Для JWT claims передаются дальше через контекст, sub становится актором
+// журнала аудита, роли из токена - ролями актора. Для API-ключа актор -
+// apikey:<id>, права ограничены scopes ключа, срок ключа передается как срок
+// учетных данных актора.
+func AuthMiddleware(opts AuthOptions) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if isExempt(r.URL.Path, opts.Exempt) {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            if key, ok := apiKey(r); ok && opts.APIKey != nil {
+                subject, scopes, expiresAt, err := opts.APIKey(r.Context(), key)
+                if err != nil {
+                    if !errors.Is(err, ErrInvalidAPIKey) {
+                        logger.FromContext(r.Context(), slog.Default()).Error("failed to verify api key", slog.Any("error", err))
+                        problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Failed to verify API key")
+                        return
+                    }
+                    unauthorized(w, r, opts, "", "Invalid API key")
+                    return
+                }
+
+                logger.AddAttrs(r.Context(), slog.String("subject", subject))
+                ctx := actor.NewContext(r.Context(), subject)
+                ctx = actor.WithScopes(ctx, scopes...)
+                if expiresAt != nil {
+                    ctx = actor.WithExpiry(ctx, *expiresAt)
+                }
+                next.ServeHTTP(w, r.WithContext(ctx))
+                return
+            }
+
+            token, ok := bearerToken(r)
+            if !ok || opts.Verifier == nil {
+                unauthorized(w, r, opts, "", "Authentication is required")
+                return
+            }
+            claims, err := opts.Verifier.Verify(r.Context(), token)
+            if err != nil {
+                if !errors.Is(err, jwt.ErrInvalidToken) {
+                    // Ключи проверки недоступны - это проблема сервиса, а не клиента
+                    logger.FromContext(r.Context(), slog.Default()).Error("failed to verify token", slog.Any("error", err))
+                    problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Failed to verify token")
+                    return
+                }
+                unauthorized(w, r, opts, "invalid_token", err.Error())
+                return
+            }
+
+            logger.AddAttrs(r.Context(), slog.String("subject", claims.Subject))
+            ctx := context.WithValue(r.Context(), claimsKey{}, claims)
+            ctx = actor.NewContext(ctx, claims.Subject)
+            ctx = actor.WithRoles(ctx, claims.Roles...)
+            next.ServeHTTP(w, r.WithContext(ctx))
+        })
+    }
+}
+
+func isExempt(path string, exempt []string) bool {
+    for _, e := range exempt {
+        if path == e || (strings.HasSuffix(e, "/") && strings.HasPrefix(path, e)) {
+            return true
+        }
+    }
+    return false
+}
+
+// bearerToken достает токен из заголовка Authorization. Схема не зависит от регистра.
+func bearerToken(r *http.Request) (string, bool) {
+    return authorization(r, "Bearer")
+}
+
+// apiKey достает ключ из X-API-Key или из Authorization: ApiKey
+func apiKey(r *http.Request) (string, bool) {
+    if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+        return key, true
+    }
+    return authorization(r, "ApiKey")
+}
+
+func authorization(r *http.Request, scheme string) (string, bool) {
+    got, credentials, found := strings.Cut(r.Header.Get("Authorization"), " ")
+    credentials = strings.TrimSpace(credentials)
+    if !found || !strings.EqualFold(got, scheme) || credentials == "" {
+        return "", false
+    }
+    return credentials, true
+}
+
+// unauthorized отвечает 401 с заголовком WWW-Authenticate для каждой
+// включенной схемы. Код ошибки Bearer по RFC 6750 указывается, только если
+// передан недействительный токен.
+func unauthorized(w http.ResponseWriter, r *http.Request, opts AuthOptions, code, detail string) {
+    if opts.Verifier != nil {
+        challenge := `Bearer realm="api"`
+        if code != "" {
+            challenge += `, error="` + code + `", error_description="` + strings.ReplaceAll(detail, `"`, `'`) + `"`
+        }
+        w.Header().Add("WWW-Authenticate", challenge)
+    }
+    if opts.APIKey != nil {
+        w.Header().Add("WWW-Authenticate", `ApiKey realm="api"`)
+    }
+    problem.Error(w, r, http.StatusUnauthorized, CodeUnauthorized, detail)
+}
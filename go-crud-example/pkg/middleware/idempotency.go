@@ -0,0 +1,191 @@
+package middleware
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "io"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "go-crud-example/pkg/idempotency"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/problem"
+)
+
+const (
+    // IdempotencyKeyHeader - заголовок с ключом идемпотентности от клиента
+    IdempotencyKeyHeader = "Idempotency-Key"
+    // IdempotentReplayedHeader отмечает ответ, повторенный из хранилища
+    IdempotentReplayedHeader = "Idempotent-Replayed"
+
+    CodeInvalidIdempotencyKey = "invalid_idempotency_key"
+    CodeIdempotencyKeyReused  = "idempotency_key_reused"
+    CodeIdempotencyKeyInUse   = "idempotency_key_in_use"
+    CodeRequestTooLarge       = "request_too_large"
+
+    maxIdempotencyKeyLength = 255
+    // maxIdempotentBodySize ограничивает тело, которое читается целиком для отпечатка
+    maxIdempotentBodySize = 1 << 20
+)
+
+// replayedHeaders - заголовки ответа, которые сохраняются и повторяются вместе с телом
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyOptions задает маршруты с поддержкой Idempotency-Key и сроки хранения
+type IdempotencyOptions struct {
+    Store idempotency.Store
+    // TTL - сколько хранится ответ для повтора
+    TTL time.Duration
+    // LockTimeout - сколько ключ остается занятым незавершенным запросом.
+    // Защищает от вечной блокировки, если экземпляр упал посреди запроса.
+    LockTimeout time.Duration
+    // Routes - маршруты "METHOD /шаблон", например "POST /users"
+    Routes []string
+}
+
+// IdempotencyMiddleware выполняет запрос с заголовком Idempotency-Key один раз.
+// Повтор с тем же ключом и телом получает сохраненный ответ с заголовком
+// Idempotent-Replayed: true, с другим телом - 422, а пока первый запрос еще
+// выполняется - 409. Ответы 5xx и запросы, отмененные клиентом, не
+// сохраняются, такой запрос можно повторить.
+// Ключ действует в пределах клиента, поэтому middleware подключается после
+// AuthMiddleware.
+func IdempotencyMiddleware(opts IdempotencyOptions) func(http.Handler) http.Handler {
+    routes := make(map[string]bool, len(opts.Routes))
+    for _, route := range opts.Routes {
+        routes[route] = true
+    }
+
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            key := r.Header.Get(IdempotencyKeyHeader)
+            if key == "" || !routes[r.Method+" "+routeTemplate(r)] {
+                next.ServeHTTP(w, r)
+                return
+            }
+            if !isValidIdempotencyKey(key) {
+                problem.Error(w, r, http.StatusBadRequest, CodeInvalidIdempotencyKey, "Idempotency-Key must be 1 to 255 printable ASCII characters")
+                return
+            }
+
+            body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+            if err != nil {
+                var tooLarge *http.MaxBytesError
+                if errors.As(err, &tooLarge) {
+                    problem.Error(w, r, http.StatusRequestEntityTooLarge, CodeRequestTooLarge, "Request body is too large")
+                    return
+                }
+                problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Failed to read request body")
+                return
+            }
+            r.Body = io.NopCloser(bytes.NewReader(body))
+
+            log := logger.FromContext(r.Context(), slog.Default())
+            storeKey := clientKey(r) + " " + key
+            fingerprint := requestFingerprint(r, body)
+            now := time.Now()
+
+            record, reserved, err := opts.Store.Reserve(r.Context(), storeKey, fingerprint, now, now.Add(opts.LockTimeout))
+            if err != nil {
+                log.Error("failed to reserve idempotency key", slog.Any("error", err))
+                problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Failed to check idempotency key")
+                return
+            }
+            if !reserved {
+                replay(w, r, record, fingerprint)
+                return
+            }
+
+            // Ключ освобождается при ошибке сервера и при панике обработчика.
+            // Запрос к хранилищу выполняется и после отмены контекста запроса.
+            ctx := context.WithoutCancel(r.Context())
+            completed := false
+            defer func() {
+                if !completed {
+                    if err := opts.Store.Release(ctx, storeKey, record.Token); err != nil {
+                        log.Error("failed to release idempotency key", slog.Any("error", err))
+                    }
+                }
+            }()
+
+            recorder := &recordingResponseWriter{ResponseWriter: NewResponseWriter(w)}
+            next.ServeHTTP(recorder, r)
+
+            // Не сохраняются ошибки сервера и незаконченные ответы: обработчик
+            // ничего не ответил, например потому что клиент отключился
+            if !recorder.wroteHeader || r.Context().Err() != nil || recorder.Status() >= http.StatusInternalServerError {
+                return
+            }
+            response := idempotency.Response{Status: recorder.Status(), Header: make(http.Header), Body: recorder.body.Bytes()}
+            for _, name := range replayedHeaders {
+                if value := w.Header().Get(name); value != "" {
+                    response.Header.Set(name, value)
+                }
+            }
+            // Запрос, выполнявшийся дольше LockTimeout, мог потерять ключ: его
+            // ответ не сохраняется, чтобы не затереть запрос, занявший ключ после
+            if err := opts.Store.Complete(ctx, storeKey, record.Token, response, time.Now().Add(opts.TTL)); err != nil {
+                if errors.Is(err, idempotency.ErrNotReserved) {
+                    log.Warn("idempotency key reservation expired before the response was stored")
+                    return
+                }
+                log.Error("failed to store idempotent response", slog.Any("error", err))
+                return
+            }
+            completed = true
+        })
+    }
+}
+
+// replay отвечает на повтор запроса с уже занятым ключом
+func replay(w http.ResponseWriter, r *http.Request, record *idempotency.Record, fingerprint string) {
+    switch {
+    case record.Fingerprint != fingerprint:
+        problem.Error(w, r, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency-Key was already used with a different request")
+    case record.Response == nil:
+        w.Header().Set("Retry-After", "1")
+        problem.Error(w, r, http.StatusConflict, CodeIdempotencyKeyInUse, "A request with this Idempotency-Key is still in progress")
+    default:
+        for name, values := range record.Response.Header {
+            w.Header()[name] = values
+        }
+        w.Header().Set(IdempotentReplayedHeader, "true")
+        w.WriteHeader(record.Response.Status)
+        _, _ = w.Write(record.Response.Body)
+    }
+}
+
+// requestFingerprint отличает повтор запроса от другого запроса с тем же ключом
+func requestFingerprint(r *http.Request, body []byte) string {
+    h := sha256.New()
+    h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+    h.Write(body)
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+func isValidIdempotencyKey(key string) bool {
+    if len(key) > maxIdempotencyKeyLength {
+        return false
+    }
+    for i := 0; i < len(key); i++ {
+        if key[i] < 0x21 || key[i] > 0x7e {
+            return false
+        }
+    }
+    return true
+}
+
+// recordingResponseWriter копирует тело ответа для сохранения
+type recordingResponseWriter struct {
+    *ResponseWriter
+    body bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+    rw.body.Write(b)
+    return rw.ResponseWriter.Write(b)
+}
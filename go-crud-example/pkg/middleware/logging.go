@@ -1,9 +1,13 @@
 package middleware
 
 import (
-    "log"
+    "log/slog"
     "net/http"
     "time"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/requestid"
 )
 
 type ResponseWriter struct {
@@ -17,6 +21,9 @@ func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
 }
 
 func (rw *ResponseWriter) Status() int {
+    if !rw.wroteHeader {
+        return http.StatusOK
+    }
     return rw.status
 }
 
@@ -30,32 +37,77 @@ func (rw *ResponseWriter) WriteHeader(code int) {
     rw.wroteHeader = true
 }
 
-func LoggingMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+    if !rw.wroteHeader {
+        rw.WriteHeader(http.StatusOK)
+    }
+    return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ResponseWriter
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+    return rw.ResponseWriter
+}
+
+// RequestIDMiddleware берет идентификатор запроса из заголовка X-Request-ID
+// или генерирует новый и возвращает его в ответе
+func RequestIDMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get(requestid.Header)
+        if id == "" || len(id) > 128 {
+            id = requestid.New()
+        }
+
+        w.Header().Set(requestid.Header, id)
+        next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+    })
+}
+
+// LoggingMiddleware привязывает к запросу логгер с атрибутами запроса и пишет
+// одну запись по завершении обработки
+func LoggingMiddleware(base *slog.Logger) func(http.Handler) http.Handler {
     return func(next http.Handler) http.Handler {
         return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
             start := time.Now()
 
-            // Логируем входящий запрос
-            logger.Printf(
-                "Входящий запрос | Method: %s | Path: %s | RemoteAddr: %s",
-                r.Method,
-                r.URL.Path,
-                r.RemoteAddr,
-            )
+            attrs := []any{
+                slog.String("request_id", requestid.FromContext(r.Context())),
+                slog.String("method", r.Method),
+                slog.String("route", routeTemplate(r)),
+            }
+            ctx := logger.NewContext(r.Context(), base.With(attrs...))
 
             // Создаем обертку для ResponseWriter чтобы отслеживать статус ответа
             wrapped := NewResponseWriter(w)
 
-            // Обрабатываем запрос
-            next.ServeHTTP(wrapped, r)
+            next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+            status := wrapped.Status()
+            level := slog.LevelInfo
+            switch {
+            case status >= http.StatusInternalServerError:
+                level = slog.LevelError
+            case status >= http.StatusBadRequest:
+                level = slog.LevelWarn
+            }
 
-            // Логируем результат обработки запроса
-            logger.Printf(
-                "Исходящий ответ | Status: %d | Duration: %v | Path: %s",
-                wrapped.Status(),
-                time.Since(start),
-                r.URL.Path,
+            logger.FromContext(ctx, base).LogAttrs(ctx, level, "request completed",
+                slog.String("path", r.URL.Path),
+                slog.String("remote_addr", r.RemoteAddr),
+                slog.Int("status", status),
+                slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
             )
         })
     }
 }
+
+// routeTemplate возвращает шаблон маршрута mux (например /users/{id}),
+// а для запросов вне маршрутов - исходный путь
+func routeTemplate(r *http.Request) string {
+    if route := mux.CurrentRoute(r); route != nil {
+        if template, err := route.GetPathTemplate(); err == nil {
+            return template
+        }
+    }
+    return r.URL.Path
+}
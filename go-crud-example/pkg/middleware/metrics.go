@@ -0,0 +1,25 @@
+package middleware
+
+import (
+    "net/http"
+    "strconv"
+    "time"
+
+    "go-crud-example/pkg/metrics"
+)
+
+// MetricsMiddleware записывает количество и длительность запросов.
+// В метку endpoint попадает шаблон маршрута, а не исходный путь, чтобы
+// /users/1, /users/2, ... не порождали отдельные временные ряды.
+func MetricsMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        start := time.Now()
+        wrapped := NewResponseWriter(w)
+
+        next.ServeHTTP(wrapped, r)
+
+        endpoint := routeTemplate(r)
+        metrics.HttpRequestsTotal.WithLabelValues(r.Method, endpoint, strconv.Itoa(wrapped.Status())).Inc()
+        metrics.HttpRequestDuration.WithLabelValues(r.Method, endpoint).Observe(time.Since(start).Seconds())
+    })
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+    "log/slog"
+    "math"
+    "net"
+    "net/http"
+    "strconv"
+    "time"
+
+    "go-crud-example/pkg/actor"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/metrics"
+    "go-crud-example/pkg/problem"
+    "go-crud-example/pkg/ratelimit"
+)
+
+// CodeRateLimited - код ответа 429 при превышении лимита
+const CodeRateLimited = "rate_limited"
+
+// defaultRouteKey - общая корзина клиента для маршрутов без собственного лимита
+const defaultRouteKey = "*"
+
+// RateLimitOptions задает лимиты запросов
+type RateLimitOptions struct {
+    Store ratelimit.Store
+    // Default - общий лимит клиента на маршруты без собственного, нулевой их не ограничивает
+    Default ratelimit.Limit
+    // Routes - лимиты отдельных маршрутов по ключу "METHOD /шаблон", например
+    // "POST /users" или "GET /users/{id}"
+    Routes map[string]ratelimit.Limit
+    // Exempt - пути без ограничений, в формате AuthOptions.Exempt
+    Exempt []string
+}
+
+// RateLimitMiddleware ограничивает частоту запросов каждого клиента. Клиент -
+// API-ключ, sub токена или IP, поэтому middleware подключается после
+// AuthMiddleware. Ответы содержат заголовки RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset и RateLimit-Policy, превышение лимита - 429 с Retry-After.
+// Если хранилище недоступно, запрос пропускается: лимиты не должны ронять API.
+func RateLimitMiddleware(opts RateLimitOptions) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if isExempt(r.URL.Path, opts.Exempt) {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            route := r.Method + " " + routeTemplate(r)
+            limit, ok := opts.Routes[route]
+            if !ok {
+                route, limit = defaultRouteKey, opts.Default
+            }
+            if limit.IsZero() {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            result, err := opts.Store.Take(r.Context(), route+" "+clientKey(r), limit, time.Now())
+            if err != nil {
+                logger.FromContext(r.Context(), slog.Default()).Error("rate limit store failed", slog.Any("error", err))
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            h := w.Header()
+            h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+            h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+            h.Set("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+            h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+ceilSeconds(limit.Period))
+            if !result.Allowed {
+                metrics.RateLimitedTotal.WithLabelValues(r.Method, routeTemplate(r)).Inc()
+                h.Set("Retry-After", ceilSeconds(result.RetryAfter))
+                problem.Error(w, r, http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded, retry after "+ceilSeconds(result.RetryAfter)+"s")
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// clientKey определяет клиента: API-ключ, sub токена или IP для анонимных
+// запросов. За обратным прокси все анонимные клиенты получат его IP.
+func clientKey(r *http.Request) string {
+    subject := actor.FromContext(r.Context())
+    if _, ok := actor.ScopesFromContext(r.Context()); ok {
+        return "key:" + subject
+    }
+    if subject != actor.Anonymous {
+        return "sub:" + subject
+    }
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        host = r.RemoteAddr
+    }
+    return "ip:" + host
+}
+
+// ceilSeconds округляет вверх до целых секунд: клиент, повторивший запрос
+// через Retry-After, не должен снова получить 429
+func ceilSeconds(d time.Duration) string {
+    return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
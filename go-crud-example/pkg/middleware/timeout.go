@@ -0,0 +1,35 @@
+package middleware
+
+import (
+    "context"
+    "net/http"
+    "time"
+)
+
+// TimeoutMiddleware ограничивает время обработки запроса: контекст запроса
+// получает дедлайн, который учитывают сервис и репозиторий. Маршруты с шаблонами
+// из exempt (например, потоковые выгрузки) ограничиваются только отключением клиента.
+func TimeoutMiddleware(timeout time.Duration, exempt ...string) func(http.Handler) http.Handler {
+    skip := make(map[string]bool, len(exempt))
+    for _, template := range exempt {
+        skip[template] = true
+    }
+
+    return func(next http.Handler) http.Handler {
+        if timeout <= 0 {
+            return next
+        }
+
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if skip[routeTemplate(r)] {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            ctx, cancel := context.WithTimeout(r.Context(), timeout)
+            defer cancel()
+
+            next.ServeHTTP(w, r.WithContext(ctx))
+        })
+    }
+}
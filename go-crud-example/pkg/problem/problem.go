@@ -0,0 +1,71 @@
+package problem
+
+import (
+    "encoding/json"
+    "net/http"
+
+    "go-crud-example/pkg/requestid"
+)
+
+// ContentType - media type ответов об ошибках по RFC 7807
+const ContentType = "application/problem+json"
+
+// Общие коды ошибок. Код стабилен и предназначен для обработки клиентом,
+// в отличие от title и detail, которые могут меняться.
+const (
+    CodeInvalidRequest     = "invalid_request"
+    CodeValidationFailed   = "validation_failed"
+    CodeNotFound           = "not_found"
+    CodePreconditionFailed = "precondition_failed"
+    CodeRequestTimeout     = "request_timeout"
+    CodeInternal           = "internal_error"
+)
+
+// Problem - тело ответа об ошибке (RFC 7807) с расширениями code, request_id и errors
+type Problem struct {
+    Type      string       `json:"type"`
+    Title     string       `json:"title"`
+    Status    int          `json:"status"`
+    Detail    string       `json:"detail,omitempty"`
+    Instance  string       `json:"instance,omitempty"`
+    Code      string       `json:"code"`
+    RequestID string       `json:"request_id,omitempty"`
+    Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError описывает ошибку валидации одного поля
+type FieldError struct {
+    Field   string `json:"field"`
+    Rule    string `json:"rule"`
+    Message string `json:"message"`
+}
+
+func New(status int, code, detail string) *Problem {
+    return &Problem{
+        Type:   "about:blank",
+        Title:  http.StatusText(status),
+        Status: status,
+        Detail: detail,
+        Code:   code,
+    }
+}
+
+// Write отправляет problem+json ответ, дополняя его путем и идентификатором запроса
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+    if p.Instance == "" {
+        p.Instance = r.URL.Path
+    }
+    if p.RequestID == "" {
+        p.RequestID = requestid.FromContext(r.Context())
+    }
+
+    w.Header().Set("Content-Type", ContentType)
+    w.Header().Set("X-Content-Type-Options", "nosniff")
+    w.WriteHeader(p.Status)
+    _ = json.NewEncoder(w).Encode(p)
+}
+
+// Error - сокращение для Write(w, r, New(status, code, detail))
+func Error(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+    Write(w, r, New(status, code, detail))
+}
@@ -0,0 +1,152 @@
+// Package ratelimit ограничивает частоту запросов алгоритмом token bucket.
+// Состояние корзин хранится в Store: MemoryStore подходит для одного
+// экземпляра сервиса, для нескольких нужна реализация на общем хранилище.
+package ratelimit
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Limit разрешает Requests запросов за Period. Это же емкость корзины: после
+// простоя клиент может сделать Requests запросов подряд.
+type Limit struct {
+    Requests int
+    Period   time.Duration
+}
+
+// IsZero сообщает, что лимит не задан и запросы не ограничиваются
+func (l Limit) IsZero() bool {
+    return l.Requests == 0
+}
+
+// rate - сколько токенов добавляется в корзину за секунду
+func (l Limit) rate() float64 {
+    return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) String() string {
+    return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimit разбирает лимит вида "10/m": число запросов и период - s, m, h
+// или длительность в формате time.ParseDuration, например "100/10s"
+func ParseLimit(value string) (Limit, error) {
+    requests, period, found := strings.Cut(strings.TrimSpace(value), "/")
+    if !found {
+        return Limit{}, fmt.Errorf("invalid limit %q, want <requests>/<period>", value)
+    }
+
+    n, err := strconv.Atoi(requests)
+    if err != nil || n <= 0 {
+        return Limit{}, fmt.Errorf("invalid limit %q: requests must be a positive integer", value)
+    }
+
+    var d time.Duration
+    switch period {
+    case "s":
+        d = time.Second
+    case "m":
+        d = time.Minute
+    case "h":
+        d = time.Hour
+    default:
+        if d, err = time.ParseDuration(period); err != nil || d <= 0 {
+            return Limit{}, fmt.Errorf("invalid limit %q: period must be s, m, h or a positive duration", value)
+        }
+    }
+    return Limit{Requests: n, Period: d}, nil
+}
+
+// Result - решение по одному запросу
+type Result struct {
+    Allowed bool
+    // Limit - емкость корзины
+    Limit int
+    // Remaining - сколько запросов еще можно сделать сразу
+    Remaining int
+    // RetryAfter - через сколько появится следующий токен, если запрос отклонен
+    RetryAfter time.Duration
+    // ResetAfter - через сколько корзина снова заполнится целиком
+    ResetAfter time.Duration
+}
+
+// Store хранит корзины клиентов. Take забирает токен из корзины key, если он
+// есть, и должен быть атомарным: реализация для общего хранилища выполняет
+// его одной операцией (например, Lua-скриптом в Redis). now - время запроса,
+// общее хранилище может вместо него использовать собственные часы.
+type Store interface {
+    Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// sweepInterval - как часто MemoryStore удаляет заполнившиеся корзины
+const sweepInterval = time.Minute
+
+type bucket struct {
+    tokens  float64
+    updated time.Time
+    period  time.Duration
+}
+
+// MemoryStore хранит корзины в памяти процесса. Заполнившиеся корзины ничем не
+// отличаются от новых, поэтому периодически удаляются.
+type MemoryStore struct {
+    mu        sync.Mutex
+    buckets   map[string]*bucket
+    lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if now.Sub(s.lastSweep) >= sweepInterval {
+        s.sweep(now)
+    }
+
+    capacity := float64(limit.Requests)
+    b, ok := s.buckets[key]
+    if !ok {
+        b = &bucket{tokens: capacity, updated: now}
+        s.buckets[key] = b
+    }
+    b.period = limit.Period
+    if elapsed := now.Sub(b.updated); elapsed > 0 {
+        b.tokens = math.Min(capacity, b.tokens+elapsed.Seconds()*limit.rate())
+        b.updated = now
+    }
+
+    result := Result{Limit: limit.Requests}
+    if b.tokens >= 1 {
+        b.tokens--
+        result.Allowed = true
+    } else {
+        result.RetryAfter = seconds((1 - b.tokens) / limit.rate())
+    }
+    result.Remaining = int(b.tokens)
+    result.ResetAfter = seconds((capacity - b.tokens) / limit.rate())
+    return result, nil
+}
+
+// sweep удаляет корзины, которые успели заполниться с последнего запроса
+func (s *MemoryStore) sweep(now time.Time) {
+    for key, b := range s.buckets {
+        if now.Sub(b.updated) >= b.period {
+            delete(s.buckets, key)
+        }
+    }
+    s.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+    return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,31 @@
+package requestid
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+)
+
+// Header - заголовок, в котором передается идентификатор запроса
+const Header = "X-Request-ID"
+
+type ctxKey struct{}
+
+// New генерирует случайный идентификатор запроса
+func New() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return ""
+    }
+    return hex.EncodeToString(b)
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+    return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает идентификатор запроса или пустую строку
+func FromContext(ctx context.Context) string {
+    id, _ := ctx.Value(ctxKey{}).(string)
+    return id
+}
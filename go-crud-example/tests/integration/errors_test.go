@@ -0,0 +1,132 @@
+//go:build integration
+
+package integration
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/pkg/problem"
+    "go-crud-example/pkg/requestid"
+)
+
+func TestAPI_ErrorStatusCodes(t *testing.T) {
+    db := openTestDB(t)
+    server := newTestServer(t, db)
+
+    created := doJSON(t, server.URL, "POST", "/users", `{"name": "John", "age": 30}`, http.StatusOK)
+    var user model.User
+    if err := json.Unmarshal(created, &user); err != nil {
+        t.Fatalf("failed to decode user: %v", err)
+    }
+
+    tests := []struct {
+        name     string
+        method   string
+        path     string
+        body     string
+        wantCode int
+        wantType string
+    }{
+        {"create invalid user", "POST", "/users", `{"name": "J", "age": 200}`, http.StatusBadRequest, "validation_failed"},
+        {"create malformed body", "POST", "/users", `{"name":`, http.StatusBadRequest, "invalid_request"},
+        {"get missing user", "GET", "/users/999999", "", http.StatusNotFound, "user_not_found"},
+        {"get non-numeric id", "GET", "/users/abc", "", http.StatusNotFound, "user_not_found"},
+        {"update missing user", "PUT", "/users/999999", `{"name": "John", "age": 30}`, http.StatusNotFound, "user_not_found"},
+        {"update invalid user", "PUT", "/users/" + user.ID, `{"name": "", "age": 30}`, http.StatusBadRequest, "validation_failed"},
+        {"delete missing user", "DELETE", "/users/999999", "", http.StatusNotFound, "user_not_found"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            body := doJSON(t, server.URL, tt.method, tt.path, tt.body, tt.wantCode)
+            assertProblem(t, body, tt.wantType)
+        })
+    }
+}
+
+func TestAPI_UniqueViolationIsConflict(t *testing.T) {
+    db := openTestDB(t)
+    server := newTestServer(t, db)
+
+    // Строка с явным id не сдвигает последовательность, поэтому следующая
+    // вставка через API получит тот же id и нарушит первичный ключ
+    if _, err := db.Exec("INSERT INTO users (id, name, age) VALUES (1, 'Imported', 40)"); err != nil {
+        t.Fatalf("failed to insert row: %v", err)
+    }
+
+    body := doJSON(t, server.URL, "POST", "/users", `{"name": "John", "age": 30}`, http.StatusConflict)
+    assertProblem(t, body, "user_conflict")
+}
+
+func TestAPI_LongRequestIDIsAudited(t *testing.T) {
+    db := openTestDB(t)
+    server := newTestServer(t, db)
+
+    // RequestIDMiddleware принимает X-Request-ID до 128 символов
+    requestID := strings.Repeat("r", 128)
+    req, err := http.NewRequest("POST", server.URL+"/users", bytes.NewBufferString(`{"name": "John", "age": 30}`))
+    if err != nil {
+        t.Fatalf("failed to build request: %v", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set(requestid.Header, requestID)
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("POST /users failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("POST /users returned %d, want %d", resp.StatusCode, http.StatusOK)
+    }
+
+    var stored string
+    if err := db.QueryRow("SELECT request_id FROM audit_events").Scan(&stored); err != nil {
+        t.Fatalf("failed to read audit event: %v", err)
+    }
+    if stored != requestID {
+        t.Errorf("audit event request_id = %q, want %q", stored, requestID)
+    }
+}
+
+func doJSON(t *testing.T, baseURL, method, path, body string, wantCode int) []byte {
+    t.Helper()
+
+    req, err := http.NewRequest(method, baseURL+path, bytes.NewBufferString(body))
+    if err != nil {
+        t.Fatalf("failed to build request: %v", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("%s %s failed: %v", method, path, err)
+    }
+    defer resp.Body.Close()
+
+    var buf bytes.Buffer
+    if _, err := buf.ReadFrom(resp.Body); err != nil {
+        t.Fatalf("failed to read response: %v", err)
+    }
+    if resp.StatusCode != wantCode {
+        t.Fatalf("%s %s returned %d, want %d: %s", method, path, resp.StatusCode, wantCode, buf.String())
+    }
+    return buf.Bytes()
+}
+
+func assertProblem(t *testing.T, body []byte, wantCode string) {
+    t.Helper()
+
+    var p problem.Problem
+    if err := json.Unmarshal(body, &p); err != nil {
+        t.Fatalf("response is not a problem: %v (%s)", err, body)
+    }
+    if p.Code != wantCode {
+        t.Errorf("problem code = %q, want %q", p.Code, wantCode)
+    }
+}
@@ -0,0 +1,74 @@
+//go:build integration
+
+package integration
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "go-crud-example/internal/repository"
+    "go-crud-example/pkg/idempotency"
+)
+
+func TestPostgresIdempotencyStore_LongKey(t *testing.T) {
+    store := repository.NewIdempotencyStore(openTestDB(t))
+    ctx := context.Background()
+    now := time.Now().UTC()
+
+    // subject токена не ограничен по длине, а ключ хранилища включает его
+    key := "sub:" + strings.Repeat("a", 4096) + " k1"
+    first, reserved, err := store.Reserve(ctx, key, "fp", now, now.Add(time.Minute))
+    if err != nil || !reserved {
+        t.Fatalf("Reserve() = %v, %v, want reserved", reserved, err)
+    }
+    response := idempotency.Response{Status: http.StatusCreated, Header: http.Header{}, Body: []byte(`{"id":"1"}`)}
+    if err := store.Complete(ctx, key, first.Token, response, now.Add(time.Hour)); err != nil {
+        t.Fatalf("Complete() error = %v", err)
+    }
+    record, reserved, err := store.Reserve(ctx, key, "fp", now, now.Add(time.Minute))
+    if err != nil || reserved || record.Response == nil || record.Response.Status != http.StatusCreated {
+        t.Fatalf("Reserve() of completed key = %+v, %v, %v", record, reserved, err)
+    }
+
+    // Ключи, отличающиеся только концом, не совпадают
+    if _, reserved, err := store.Reserve(ctx, key+"2", "fp", now, now.Add(time.Minute)); err != nil || !reserved {
+        t.Errorf("Reserve() of another long key = %v, %v, want reserved", reserved, err)
+    }
+}
+
+func TestPostgresIdempotencyStore_ExpiredReservation(t *testing.T) {
+    store := repository.NewIdempotencyStore(openTestDB(t))
+    ctx := context.Background()
+    now := time.Now().UTC()
+
+    stale, reserved, err := store.Reserve(ctx, "sub:alice k1", "fp1", now, now.Add(time.Minute))
+    if err != nil || !reserved {
+        t.Fatalf("Reserve() = %v, %v, want reserved", reserved, err)
+    }
+    // Блокировка истекла, ключ занял повтор запроса
+    fresh, reserved, err := store.Reserve(ctx, "sub:alice k1", "fp2", now.Add(2*time.Minute), now.Add(3*time.Minute))
+    if err != nil || !reserved {
+        t.Fatalf("Reserve() of expired key = %v, %v, want reserved", reserved, err)
+    }
+
+    // Медленный первый запрос не затирает и не освобождает ключ нового владельца
+    response := idempotency.Response{Status: http.StatusCreated, Header: http.Header{}, Body: []byte(`{"id":"1"}`)}
+    if err := store.Complete(ctx, "sub:alice k1", stale.Token, response, now.Add(time.Hour)); !errors.Is(err, idempotency.ErrNotReserved) {
+        t.Errorf("Complete() with expired reservation error = %v, want ErrNotReserved", err)
+    }
+    if err := store.Release(ctx, "sub:alice k1", stale.Token); err != nil {
+        t.Fatalf("Release() error = %v", err)
+    }
+    record, reserved, err := store.Reserve(ctx, "sub:alice k1", "fp3", now.Add(2*time.Minute), now.Add(3*time.Minute))
+    if err != nil || reserved || record.Fingerprint != "fp2" || record.Response != nil {
+        t.Fatalf("Reserve() = %+v, %v, %v, want the new holder in flight", record, reserved, err)
+    }
+
+    if err := store.Complete(ctx, "sub:alice k1", fresh.Token, response, now.Add(time.Hour)); err != nil {
+        t.Errorf("Complete() by the new holder error = %v", err)
+    }
+}
@@ -0,0 +1,16 @@
+//go:build integration
+
+package integration
+
+import (
+    "testing"
+
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/repository/repositorytest"
+)
+
+func TestPostgresUserRepository_Conformance(t *testing.T) {
+    repositorytest.Run(t, func(t *testing.T) repository.UserRepository {
+        return repository.NewUserRepository(openTestDB(t))
+    })
+}
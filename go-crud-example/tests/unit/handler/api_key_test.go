@@ -0,0 +1,97 @@
+package handler
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+)
+
+func TestUserHandler_APIKeys(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    keys := service.NewAPIKeyService(repository.NewMemoryAPIKeyRepository())
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repository.NewMemoryUserRepository()), logger).
+        WithAPIKeys(keys).
+        RegisterRoutes(router)
+
+    serve := func(method, path, body string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+        return w
+    }
+
+    w := serve("POST", "/api-keys", `{"name": "nightly import", "scopes": ["users:import"], "expires_at": "2100-01-01T00:00:00Z"}`)
+    if w.Code != http.StatusCreated {
+        t.Fatalf("POST /api-keys returned %d: %s", w.Code, w.Body)
+    }
+    if got := w.Header().Get("Cache-Control"); got != "no-store" {
+        t.Errorf("Cache-Control = %q, want no-store", got)
+    }
+    var created model.CreatedAPIKey
+    if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+        t.Fatalf("failed to decode api key: %v", err)
+    }
+    if created.Key == "" || created.ExpiresAt == nil {
+        t.Errorf("POST /api-keys returned %+v", created)
+    }
+    if _, err := keys.AuthenticateAPIKey(context.Background(), created.Key); err != nil {
+        t.Errorf("created key is not accepted: %v", err)
+    }
+
+    // Ключ показывается только при создании
+    w = serve("GET", "/api-keys", "")
+    if w.Code != http.StatusOK || bytes.Contains(w.Body.Bytes(), []byte(created.Key)) ||
+        !bytes.Contains(w.Body.Bytes(), []byte(created.Prefix)) {
+        t.Errorf("GET /api-keys returned %d: %s", w.Code, w.Body)
+    }
+
+    w = serve("DELETE", "/api-keys/"+created.ID, "")
+    if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"revoked_at"`)) {
+        t.Errorf("DELETE /api-keys/{id} returned %d: %s", w.Code, w.Body)
+    }
+
+    tests := []struct {
+        name     string
+        method   string
+        path     string
+        body     string
+        wantCode int
+        wantBody string
+    }{
+        {name: "unknown key", method: "DELETE", path: "/api-keys/42", wantCode: http.StatusNotFound, wantBody: handler.CodeAPIKeyNotFound},
+        {name: "unknown scope", method: "POST", path: "/api-keys", body: `{"name": "job", "scopes": ["users:drop"]}`, wantCode: http.StatusBadRequest, wantBody: handler.CodeInvalidAPIKey},
+        {name: "malformed body", method: "POST", path: "/api-keys", body: `{`, wantCode: http.StatusBadRequest},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := serve(tt.method, tt.path, tt.body)
+            if w.Code != tt.wantCode || !bytes.Contains(w.Body.Bytes(), []byte(tt.wantBody)) {
+                t.Errorf("%s %s returned %d: %s", tt.method, tt.path, w.Code, w.Body)
+            }
+        })
+    }
+}
+
+func TestUserHandler_APIKeysDisabled(t *testing.T) {
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repository.NewMemoryUserRepository()), logger).RegisterRoutes(router)
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest("GET", "/api-keys", nil))
+    if w.Code != http.StatusNotFound {
+        t.Errorf("GET /api-keys without WithAPIKeys returned %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+)
+
+func TestUserHandler_Audit(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+    expect := func(method, path, body string, want int) *httptest.ResponseRecorder {
+        t.Helper()
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+        if w.Code != want {
+            t.Fatalf("%s %s returned %d, want %d: %s", method, path, w.Code, want, w.Body)
+        }
+        return w
+    }
+    decode := func(w *httptest.ResponseRecorder) model.AuditPage {
+        t.Helper()
+        var page model.AuditPage
+        if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+            t.Fatalf("failed to decode audit page: %v", err)
+        }
+        return page
+    }
+
+    expect("POST", "/users", `{"name": "John", "age": 30}`, http.StatusOK)
+    expect("POST", "/users", `{"name": "Ann", "age": 25}`, http.StatusOK)
+    expect("PUT", "/users/1", `{"name": "Johnny", "age": 30}`, http.StatusOK)
+    expect("DELETE", "/users/1", "", http.StatusNoContent)
+    expect("POST", "/users/1:purge", "", http.StatusNoContent)
+
+    page := decode(expect("GET", "/users/1/history", "", http.StatusOK))
+    var actions []string
+    for _, e := range page.Events {
+        actions = append(actions, e.Action)
+    }
+    want := []string{model.AuditActionCreate, model.AuditActionUpdate, model.AuditActionDelete, model.AuditActionPurge}
+    if len(actions) != len(want) {
+        t.Fatalf("history actions = %v, want %v", actions, want)
+    }
+    for i := range want {
+        if actions[i] != want[i] {
+            t.Fatalf("history actions = %v, want %v", actions, want)
+        }
+    }
+    if string(page.Events[1].Before) != `{"name":"John"}` || string(page.Events[1].After) != `{"name":"Johnny"}` {
+        t.Errorf("update diff = %s -> %s", page.Events[1].Before, page.Events[1].After)
+    }
+
+    page = decode(expect("GET", "/audit?limit=2", "", http.StatusOK))
+    if len(page.Events) != 2 || page.NextCursor == "" {
+        t.Fatalf("GET /audit?limit=2 = %+v, want 2 events and next cursor", page)
+    }
+    page = decode(expect("GET", "/audit?cursor="+page.NextCursor, "", http.StatusOK))
+    if len(page.Events) != 3 || page.NextCursor != "" {
+        t.Errorf("GET /audit second page has %d events, cursor %q, want 3 and none", len(page.Events), page.NextCursor)
+    }
+
+    page = decode(expect("GET", "/audit?since=2100-01-01T00:00:00Z", "", http.StatusOK))
+    if len(page.Events) != 0 {
+        t.Errorf("GET /audit?since=future returned %d events, want 0", len(page.Events))
+    }
+    page = decode(expect("GET", "/users/42/history", "", http.StatusOK))
+    if len(page.Events) != 0 {
+        t.Errorf("history of unknown user has %d events, want 0", len(page.Events))
+    }
+
+    expect("GET", "/audit?since=yesterday", "", http.StatusBadRequest)
+    expect("GET", "/audit?limit=0", "", http.StatusBadRequest)
+    expect("GET", "/audit?cursor=abc", "", http.StatusBadRequest)
+}
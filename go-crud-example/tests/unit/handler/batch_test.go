@@ -0,0 +1,107 @@
+package handler
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+)
+
+type batchResponse struct {
+    Succeeded int `json:"succeeded"`
+    Failed    int `json:"failed"`
+    Results   []struct {
+        Index  int `json:"index"`
+        Status int `json:"status"`
+        User   *struct {
+            ID      string `json:"id"`
+            Version int64  `json:"version"`
+        } `json:"user"`
+        Error *struct {
+            Code string `json:"code"`
+        } `json:"error"`
+    } `json:"results"`
+}
+
+func TestUserHandler_BatchUsers(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+    batch := func(body string, want int) batchResponse {
+        t.Helper()
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest("POST", "/users:batch", bytes.NewBufferString(body)))
+        if w.Code != want {
+            t.Fatalf("POST /users:batch returned %d, want %d: %s", w.Code, want, w.Body)
+        }
+        var response batchResponse
+        if want == http.StatusOK {
+            if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+                t.Fatalf("failed to decode batch response: %v", err)
+            }
+        }
+        return response
+    }
+
+    response := batch(`{"operations": [
+        {"op": "create", "user": {"name": "John", "age": 30}},
+        {"op": "create", "user": {"name": "Ann", "age": 25}},
+        {"op": "update", "id": "1", "version": 1, "user": {"name": "Johnny", "age": 31}},
+        {"op": "delete", "id": "2"}
+    ]}`, http.StatusOK)
+    if response.Succeeded != 4 || response.Failed != 0 {
+        t.Fatalf("atomic batch succeeded %d, failed %d, want 4 and 0", response.Succeeded, response.Failed)
+    }
+    wantStatus := []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusNoContent}
+    for i, result := range response.Results {
+        if result.Index != i || result.Status != wantStatus[i] {
+            t.Errorf("result %d = index %d, status %d, want status %d", i, result.Index, result.Status, wantStatus[i])
+        }
+    }
+    if u := response.Results[2].User; u == nil || u.ID != "1" || u.Version != 2 {
+        t.Errorf("update result user = %+v, want id 1 version 2", u)
+    }
+
+    // Одна ошибка отменяет атомарный пакет целиком
+    response = batch(`{"mode": "atomic", "operations": [
+        {"op": "create", "user": {"name": "Kate", "age": 41}},
+        {"op": "update", "id": "2", "user": {"name": "Ann", "age": 26}}
+    ]}`, http.StatusOK)
+    if response.Failed != 2 || response.Results[0].Error.Code != handler.CodeBatchAborted || response.Results[1].Status != http.StatusNotFound {
+        t.Errorf("aborted batch = %+v", response)
+    }
+
+    response = batch(`{"mode": "best_effort", "operations": [
+        {"op": "create", "user": {"name": "Kate", "age": 41}},
+        {"op": "create", "user": {"name": "", "age": 41}},
+        {"op": "update", "id": "1", "version": 1, "user": {"name": "John", "age": 30}},
+        {"op": "merge", "id": "1"}
+    ]}`, http.StatusOK)
+    wantStatus = []int{http.StatusOK, http.StatusBadRequest, http.StatusPreconditionFailed, http.StatusBadRequest}
+    for i, result := range response.Results {
+        if result.Status != wantStatus[i] {
+            t.Errorf("best effort result %d status = %d, want %d", i, result.Status, wantStatus[i])
+        }
+    }
+    if response.Succeeded != 1 || response.Failed != 3 {
+        t.Errorf("best effort batch succeeded %d, failed %d, want 1 and 3", response.Succeeded, response.Failed)
+    }
+
+    batch(`{"operations": []}`, http.StatusBadRequest)
+    batch(`{"mode": "sometimes", "operations": [{"op": "delete", "id": "1"}]}`, http.StatusBadRequest)
+    batch(`{"operations": [{"op": "delete", "id": "1", "extra": true}]}`, http.StatusBadRequest)
+    batch(`not json`, http.StatusBadRequest)
+    batch(`{"operations": [{"op": "delete", "id": "`+strings.Repeat("1", 4<<20)+`"}]}`, http.StatusRequestEntityTooLarge)
+}
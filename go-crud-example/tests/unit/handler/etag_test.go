@@ -0,0 +1,101 @@
+package handler
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+    "go-crud-example/pkg/problem"
+)
+
+func TestUserHandler_ConditionalRequests(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+    serve := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+        r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+        for k, v := range headers {
+            r.Header.Set(k, v)
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, r)
+        return w
+    }
+
+    w := serve("POST", "/users", `{"name": "John", "age": 30}`, nil)
+    if got := w.Header().Get("ETag"); got != `"1"` {
+        t.Errorf("POST /users ETag = %q, want %q", got, `"1"`)
+    }
+    var user model.User
+    if err := json.NewDecoder(w.Body).Decode(&user); err != nil {
+        t.Fatalf("failed to decode user: %v", err)
+    }
+    path := "/users/" + user.ID
+
+    tests := []struct {
+        name     string
+        method   string
+        body     string
+        headers  map[string]string
+        wantCode int
+        wantETag string
+    }{
+        {"get returns etag", "GET", "", nil, http.StatusOK, `"1"`},
+        {"get with matching if-none-match", "GET", "", map[string]string{"If-None-Match": `"1"`}, http.StatusNotModified, `"1"`},
+        {"get with weak if-none-match", "GET", "", map[string]string{"If-None-Match": `"7", W/"1"`}, http.StatusNotModified, `"1"`},
+        {"get with stale if-none-match", "GET", "", map[string]string{"If-None-Match": `"0"`}, http.StatusOK, `"1"`},
+        {"put with stale if-match", "PUT", `{"name": "Ann", "age": 25}`, map[string]string{"If-Match": `"5"`}, http.StatusPreconditionFailed, ""},
+        {"put with weak if-match", "PUT", `{"name": "Ann", "age": 25}`, map[string]string{"If-Match": `W/"1"`}, http.StatusPreconditionFailed, ""},
+        {"put with matching if-match", "PUT", `{"name": "Ann", "age": 25}`, map[string]string{"If-Match": `"1"`}, http.StatusOK, `"2"`},
+        {"put with list containing current", "PUT", `{"name": "Ann", "age": 26}`, map[string]string{"If-Match": `"1", "2"`}, http.StatusOK, `"3"`},
+        {"put ignores version in body", "PUT", `{"name": "Ann", "age": 27, "version": 1}`, nil, http.StatusOK, `"4"`},
+        {"put with wildcard if-match", "PUT", `{"name": "Ann", "age": 28}`, map[string]string{"If-Match": "*"}, http.StatusOK, `"5"`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := serve(tt.method, path, tt.body, tt.headers)
+            if w.Code != tt.wantCode {
+                t.Fatalf("%s %s returned %d, want %d: %s", tt.method, path, w.Code, tt.wantCode, w.Body)
+            }
+            if got := w.Header().Get("ETag"); got != tt.wantETag {
+                t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+            }
+            switch w.Code {
+            case http.StatusNotModified:
+                if w.Body.Len() != 0 {
+                    t.Errorf("304 response has body %q", w.Body)
+                }
+            case http.StatusPreconditionFailed:
+                var p problem.Problem
+                if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+                    t.Fatalf("failed to decode problem: %v", err)
+                }
+                if p.Code != problem.CodePreconditionFailed {
+                    t.Errorf("problem code = %q, want %q", p.Code, problem.CodePreconditionFailed)
+                }
+            }
+        })
+    }
+
+    got, err := repo.GetByID(context.Background(), user.ID)
+    if err != nil {
+        t.Fatalf("GetByID() error = %v", err)
+    }
+    if got.Age != 28 || got.Version != 5 {
+        t.Errorf("stored user = %+v, want age 28 version 5", *got)
+    }
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+)
+
+func TestUserHandler_ExportUsers(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+    export := func(path, accept string, want int) *httptest.ResponseRecorder {
+        t.Helper()
+        r := httptest.NewRequest("GET", path, nil)
+        if accept != "" {
+            r.Header.Set("Accept", accept)
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, r)
+        if w.Code != want {
+            t.Fatalf("GET %s (Accept: %s) returned %d, want %d: %s", path, accept, w.Code, want, w.Body)
+        }
+        return w
+    }
+
+    // Больше одной порции сброса буфера
+    for i := 0; i < 1200; i++ {
+        name := "User"
+        if i%2 == 0 {
+            name = "Ann"
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest("POST", "/users", bytes.NewBufferString(`{"name": "`+name+`", "age": 30}`)))
+        if w.Code != http.StatusOK {
+            t.Fatalf("POST /users returned %d: %s", w.Code, w.Body)
+        }
+    }
+
+    w := export("/users/export", "", http.StatusOK)
+    if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+        t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+    }
+    if !w.Flushed {
+        t.Error("export was not flushed")
+    }
+    decoder := json.NewDecoder(w.Body)
+    count := 0
+    for {
+        var u model.User
+        if err := decoder.Decode(&u); err == io.EOF {
+            break
+        } else if err != nil {
+            t.Fatalf("failed to decode NDJSON line %d: %v", count, err)
+        }
+        count++
+    }
+    if count != 1200 {
+        t.Errorf("NDJSON export has %d users, want 1200", count)
+    }
+
+    w = export("/users/export?name_prefix=Ann&sort=-id&limit=5", "text/csv;q=0.9, application/json;q=0.5", http.StatusOK)
+    if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+        t.Errorf("Content-Type = %q, want text/csv", ct)
+    }
+    records, err := csv.NewReader(w.Body).ReadAll()
+    if err != nil {
+        t.Fatalf("failed to read CSV: %v", err)
+    }
+    if len(records) != 601 {
+        t.Fatalf("CSV export has %d records, want header and 600 rows", len(records))
+    }
+    if strings.Join(records[0], ",") != "id,name,age,email,version,deleted_at,created_at,updated_at" {
+        t.Errorf("CSV header = %v", records[0])
+    }
+    if first := records[1]; first[0] != "1199" || first[1] != "Ann" || first[2] != "30" || first[3] != "" || first[4] != "1" || first[5] != "" {
+        t.Errorf("first CSV row = %v, want the last created Ann", first)
+    }
+
+    // Пустая выгрузка содержит только заголовок
+    w = export("/users/export?name_prefix=Zed", "text/*", http.StatusOK)
+    if body := w.Body.String(); body != "id,name,age,email,version,deleted_at,created_at,updated_at\n" {
+        t.Errorf("empty CSV export = %q", body)
+    }
+
+    export("/users/export", "application/xml", http.StatusNotAcceptable)
+    export("/users/export?min_age=abc", "", http.StatusBadRequest)
+}
@@ -2,24 +2,34 @@ package handler
 
 import (
     "bytes"
+    "context"
     "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
     "go-crud-example/internal/handler"
     "go-crud-example/internal/model"
     "go-crud-example/internal/repository"
     "go-crud-example/internal/service"
+    "go-crud-example/pkg/problem"
     "net/http"
     "net/http/httptest"
+    "strings"
     "testing"
 
     "github.com/gorilla/mux"
-    "log"
 )
 
 type mockUserService struct {
     users map[string]model.User
+    err   error
 }
 
-func (m *mockUserService) CreateUser(user *model.User) error {
+func (m *mockUserService) CreateUser(ctx context.Context, user *model.User) error {
+    if m.err != nil {
+        return m.err
+    }
     if user.Name == "" || user.Age == 0 {
         return service.ErrInvalidUser
     }
@@ -28,15 +38,52 @@ func (m *mockUserService) CreateUser(user *model.User) error {
     return nil
 }
 
-func (m *mockUserService) GetUsers() ([]model.User, error) {
+func (m *mockUserService) GetUsers(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
+    if m.err != nil {
+        return nil, m.err
+    }
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
     users := make([]model.User, 0, len(m.users))
     for _, user := range m.users {
         users = append(users, user)
     }
-    return users, nil
+    return &model.UserPage{Users: users, Total: len(users)}, nil
+}
+
+func (m *mockUserService) ExportUsers(ctx context.Context, query model.UserQuery, fn func(model.User) error) error {
+    if m.err != nil {
+        return m.err
+    }
+    for _, user := range m.users {
+        if err := fn(user); err != nil {
+            return err
+        }
+    }
+    return nil
 }
 
-func (m *mockUserService) GetUser(id string) (*model.User, error) {
+func (m *mockUserService) SearchUsers(ctx context.Context, query model.SearchQuery) (*model.SearchResults, error) {
+    if m.err != nil {
+        return nil, m.err
+    }
+    if err := query.Normalize(); err != nil {
+        return nil, err
+    }
+    results := []model.SearchResult{}
+    for _, user := range m.users {
+        if strings.Contains(strings.ToLower(user.Name), strings.ToLower(query.Q)) {
+            results = append(results, model.SearchResult{User: user, Score: 1})
+        }
+    }
+    return &model.SearchResults{Results: results}, nil
+}
+
+func (m *mockUserService) GetUser(ctx context.Context, id string) (*model.User, error) {
+    if m.err != nil {
+        return nil, m.err
+    }
     user, exists := m.users[id]
     if !exists {
         return nil, repository.ErrUserNotFound
@@ -44,7 +91,10 @@ func (m *mockUserService) GetUser(id string) (*model.User, error) {
     return &user, nil
 }
 
-func (m *mockUserService) UpdateUser(user *model.User) error {
+func (m *mockUserService) UpdateUser(ctx context.Context, user *model.User) error {
+    if m.err != nil {
+        return m.err
+    }
     if _, exists := m.users[user.ID]; !exists {
         return repository.ErrUserNotFound
     }
@@ -52,7 +102,30 @@ func (m *mockUserService) UpdateUser(user *model.User) error {
     return nil
 }
 
-func (m *mockUserService) DeleteUser(id string) error {
+func (m *mockUserService) PatchUser(ctx context.Context, id string, version int64, patch service.PatchFunc) (*model.User, error) {
+    if m.err != nil {
+        return nil, m.err
+    }
+    user, exists := m.users[id]
+    if !exists {
+        return nil, repository.ErrUserNotFound
+    }
+    doc, _ := json.Marshal(user)
+    patched, err := patch(doc)
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(patched, &user); err != nil {
+        return nil, err
+    }
+    m.users[id] = user
+    return &user, nil
+}
+
+func (m *mockUserService) DeleteUser(ctx context.Context, id string) error {
+    if m.err != nil {
+        return m.err
+    }
     if _, exists := m.users[id]; !exists {
         return repository.ErrUserNotFound
     }
@@ -60,11 +133,53 @@ func (m *mockUserService) DeleteUser(id string) error {
     return nil
 }
 
+func (m *mockUserService) RestoreUser(ctx context.Context, id string) (*model.User, error) {
+    if m.err != nil {
+        return nil, m.err
+    }
+    user, exists := m.users[id]
+    if !exists {
+        return nil, repository.ErrUserNotFound
+    }
+    return &user, nil
+}
+
+func (m *mockUserService) PurgeUser(ctx context.Context, id string) error {
+    if m.err != nil {
+        return m.err
+    }
+    if _, exists := m.users[id]; !exists {
+        return repository.ErrUserNotFound
+    }
+    return nil
+}
+
+func (m *mockUserService) ImportUsers(ctx context.Context, r io.Reader, opts model.ImportOptions) (*model.ImportReport, error) {
+    if m.err != nil {
+        return nil, m.err
+    }
+    return &model.ImportReport{DryRun: opts.DryRun, Errors: []model.ImportError{}}, nil
+}
+
+func (m *mockUserService) BatchUsers(ctx context.Context, batch model.BatchRequest) ([]model.BatchResult, error) {
+    if m.err != nil {
+        return nil, m.err
+    }
+    return make([]model.BatchResult, len(batch.Operations)), nil
+}
+
+func (m *mockUserService) GetAuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+    if m.err != nil {
+        return nil, m.err
+    }
+    return &model.AuditPage{Events: []model.AuditEvent{}}, nil
+}
+
 func setupTest() (*handler.UserHandler, *mockUserService) {
     mockService := &mockUserService{
         users: make(map[string]model.User),
     }
-    logger := log.New(log.Writer(), "TEST: ", log.LstdFlags)
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
     return handler.NewUserHandler(mockService, logger), mockService
 }
 
@@ -117,6 +232,69 @@ func TestUserHandler_GetUsers(t *testing.T) {
     }
 }
 
+func TestUserHandler_GetUsers_Query(t *testing.T) {
+    tests := []struct {
+        name     string
+        query    string
+        wantCode int
+    }{
+        {
+            name:     "filters and sort",
+            query:    "?limit=10&sort=-age&name_prefix=Jo&min_age=18&max_age=60",
+            wantCode: http.StatusOK,
+        },
+        {
+            name:     "invalid limit",
+            query:    "?limit=abc",
+            wantCode: http.StatusBadRequest,
+        },
+        {
+            name:     "limit too large",
+            query:    "?limit=1000",
+            wantCode: http.StatusBadRequest,
+        },
+        {
+            name:     "unsupported sort",
+            query:    "?sort=password",
+            wantCode: http.StatusBadRequest,
+        },
+        {
+            name:     "min age above max age",
+            query:    "?min_age=50&max_age=20",
+            wantCode: http.StatusBadRequest,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            h, _ := setupTest()
+
+            req := httptest.NewRequest("GET", "/users"+tt.query, nil)
+            w := httptest.NewRecorder()
+
+            h.GetUsers(w, req)
+
+            if w.Code != tt.wantCode {
+                t.Errorf("handler returned wrong status code: got %v want %v", w.Code, tt.wantCode)
+            }
+        })
+    }
+}
+
+func TestUserHandler_GetUsers_Timeout(t *testing.T) {
+    h, mockService := setupTest()
+    mockService.err = fmt.Errorf("failed to get users: %w", context.DeadlineExceeded)
+
+    req := httptest.NewRequest("GET", "/users", nil)
+    w := httptest.NewRecorder()
+
+    h.GetUsers(w, req)
+
+    if w.Code != http.StatusGatewayTimeout {
+        t.Errorf("handler returned wrong status code: got %v want %v", w.Code, http.StatusGatewayTimeout)
+    }
+}
+
 func TestUserHandler_GetUser(t *testing.T) {
     tests := []struct {
         name     string
@@ -228,3 +406,81 @@ func TestUserHandler_DeleteUser(t *testing.T) {
         })
     }
 }
+
+func TestUserHandler_ProblemResponses(t *testing.T) {
+    invalid := model.User{Name: "J", Age: 200}
+    validationErr := invalid.Validate()
+
+    tests := []struct {
+        name       string
+        serviceErr error
+        wantCode   int
+        wantType   string
+        wantFields []string
+    }{
+        {
+            name:       "not found",
+            serviceErr: fmt.Errorf("failed to get user: %w", repository.ErrUserNotFound),
+            wantCode:   http.StatusNotFound,
+            wantType:   "user_not_found",
+        },
+        {
+            name:       "validation errors",
+            serviceErr: fmt.Errorf("validation error: %w", validationErr),
+            wantCode:   http.StatusBadRequest,
+            wantType:   "validation_failed",
+            wantFields: []string{"name", "age"},
+        },
+        {
+            name:       "forbidden",
+            serviceErr: fmt.Errorf("%w: users:read is not allowed", service.ErrForbidden),
+            wantCode:   http.StatusForbidden,
+            wantType:   "forbidden",
+        },
+        {
+            name:       "internal error is not leaked",
+            serviceErr: fmt.Errorf("failed to get user: %w", errors.New("pq: password authentication failed")),
+            wantCode:   http.StatusInternalServerError,
+            wantType:   "internal_error",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            h, mockService := setupTest()
+            mockService.err = tt.serviceErr
+
+            req := httptest.NewRequest("GET", "/users/1", nil)
+            req = mux.SetURLVars(req, map[string]string{"id": "1"})
+            w := httptest.NewRecorder()
+
+            h.GetUser(w, req)
+
+            if w.Code != tt.wantCode {
+                t.Errorf("handler returned wrong status code: got %v want %v", w.Code, tt.wantCode)
+            }
+            if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+                t.Errorf("Content-Type = %q, want application/problem+json", ct)
+            }
+
+            var p problem.Problem
+            if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+                t.Fatalf("failed to decode problem: %v", err)
+            }
+            if p.Code != tt.wantType || p.Status != tt.wantCode {
+                t.Errorf("problem code/status = %s/%d, want %s/%d", p.Code, p.Status, tt.wantType, tt.wantCode)
+            }
+            if strings.Contains(p.Detail, "pq:") {
+                t.Errorf("problem detail leaks internal error: %q", p.Detail)
+            }
+
+            fields := make([]string, 0, len(p.Errors))
+            for _, fe := range p.Errors {
+                fields = append(fields, fe.Field)
+            }
+            if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+                t.Errorf("problem fields = %v, want %v", fields, tt.wantFields)
+            }
+        })
+    }
+}
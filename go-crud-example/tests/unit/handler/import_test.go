@@ -0,0 +1,119 @@
+package handler
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "mime/multipart"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+)
+
+func TestUserHandler_ImportUsers(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+    post := func(path, contentType, accept string, body io.Reader, want int) *httptest.ResponseRecorder {
+        t.Helper()
+        r := httptest.NewRequest("POST", path, body)
+        r.Header.Set("Content-Type", contentType)
+        if accept != "" {
+            r.Header.Set("Accept", accept)
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, r)
+        if w.Code != want {
+            t.Fatalf("POST %s (%s) returned %d, want %d: %s", path, contentType, w.Code, want, w.Body)
+        }
+        return w
+    }
+    report := func(w *httptest.ResponseRecorder) model.ImportReport {
+        t.Helper()
+        var report model.ImportReport
+        if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+            t.Fatalf("failed to decode report: %v", err)
+        }
+        return report
+    }
+    users := func() int {
+        t.Helper()
+        page, err := repo.GetAll(context.Background(), model.UserQuery{})
+        if err != nil {
+            t.Fatalf("GetAll() error = %v", err)
+        }
+        return page.Total
+    }
+
+    csvBody := "name,age\nJohn,30\nJ,30\nAnn,25\n"
+
+    // Пробный запуск только проверяет строки
+    got := report(post("/users/import?dry_run=true", "text/csv", "", strings.NewReader(csvBody), http.StatusOK))
+    if !got.DryRun || got.Total != 3 || got.Imported != 2 || got.Failed != 1 || users() != 0 {
+        t.Errorf("dry run report = %+v, %d users stored", got, users())
+    }
+
+    got = report(post("/users/import", "text/csv; charset=utf-8", "", strings.NewReader(csvBody), http.StatusOK))
+    if got.DryRun || got.Imported != 2 || len(got.Errors) != 1 || got.Errors[0].Line != 3 || got.Errors[0].Field != "name" {
+        t.Errorf("import report = %+v", got)
+    }
+    if users() != 2 {
+        t.Errorf("repository has %d users, want 2", users())
+    }
+
+    // Отчет в CSV для скачивания
+    w := post("/users/import", "application/x-ndjson", "text/csv", strings.NewReader(`{"name": "Bob", "age": 200}`+"\n"), http.StatusOK)
+    if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "import-report.csv") {
+        t.Errorf("Content-Disposition = %q", cd)
+    }
+    if w.Header().Get("X-Import-Failed") != "1" {
+        t.Errorf("X-Import-Failed = %q, want 1", w.Header().Get("X-Import-Failed"))
+    }
+    if want := "line,field,message\n1,age,must satisfy lte=150\n"; w.Body.String() != want {
+        t.Errorf("CSV report = %q, want %q", w.Body.String(), want)
+    }
+
+    // Файл в multipart/form-data, формат по расширению
+    var form bytes.Buffer
+    mw := multipart.NewWriter(&form)
+    _ = mw.WriteField("comment", "ignored")
+    part, err := mw.CreateFormFile("file", "users.jsonl")
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, _ = part.Write([]byte(`{"name": "Kate", "age": 41}` + "\n"))
+    _ = mw.Close()
+    got = report(post("/users/import", mw.FormDataContentType(), "", &form, http.StatusOK))
+    if got.Imported != 1 || users() != 3 {
+        t.Errorf("multipart report = %+v, %d users stored", got, users())
+    }
+
+    // Формат не определить
+    w = post("/users/import", "application/json", "", strings.NewReader(`[]`), http.StatusUnsupportedMediaType)
+    if !strings.Contains(w.Body.String(), handler.CodeUnsupportedMediaType) {
+        t.Errorf("unexpected 415 body: %s", w.Body)
+    }
+    // Параметр format имеет приоритет
+    got = report(post("/users/import?format=csv", "text/plain", "", strings.NewReader("name,age\nMax,50\n"), http.StatusOK))
+    if got.Imported != 1 {
+        t.Errorf("format parameter report = %+v", got)
+    }
+
+    w = post("/users/import", "text/csv", "", strings.NewReader("name\nJohn\n"), http.StatusBadRequest)
+    if !strings.Contains(w.Body.String(), handler.CodeInvalidImport) {
+        t.Errorf("unexpected 400 body: %s", w.Body)
+    }
+    post("/users/import?dry_run=maybe", "text/csv", "", strings.NewReader(csvBody), http.StatusBadRequest)
+}
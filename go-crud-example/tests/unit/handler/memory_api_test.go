@@ -0,0 +1,69 @@
+package handler
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+)
+
+// Полный стек обработчик -> сервис -> репозиторий в памяти, без PostgreSQL
+func TestUserHandler_MemoryStorage(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+    serve := func(method, path, body string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+        return w
+    }
+
+    w := serve("POST", "/users", `{"name": "John", "age": 30}`)
+    if w.Code != http.StatusOK {
+        t.Fatalf("POST /users returned %d: %s", w.Code, w.Body)
+    }
+    var created model.User
+    if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+        t.Fatalf("failed to decode user: %v", err)
+    }
+
+    if w := serve("POST", "/users", `{"name": "", "age": 30}`); w.Code != http.StatusBadRequest {
+        t.Errorf("POST /users with invalid body returned %d, want %d", w.Code, http.StatusBadRequest)
+    }
+    if created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt) {
+        t.Errorf("POST /users created_at = %v, updated_at = %v", created.CreatedAt, created.UpdatedAt)
+    }
+
+    // Email уникален без учета регистра
+    if w := serve("POST", "/users", `{"name": "Ann", "age": 25, "email": "ann@example.com"}`); w.Code != http.StatusOK {
+        t.Errorf("POST /users with email returned %d: %s", w.Code, w.Body)
+    }
+    w = serve("POST", "/users", `{"name": "Anna", "age": 26, "email": "ANN@example.com"}`)
+    if w.Code != http.StatusConflict || !bytes.Contains(w.Body.Bytes(), []byte(handler.CodeEmailTaken)) {
+        t.Errorf("POST /users with taken email returned %d: %s", w.Code, w.Body)
+    }
+    if w := serve("PUT", "/users/"+created.ID, `{"name": "John Updated", "age": 31}`); w.Code != http.StatusOK {
+        t.Errorf("PUT /users/{id} returned %d, want %d", w.Code, http.StatusOK)
+    }
+    if w := serve("GET", "/users/"+created.ID, ""); w.Code != http.StatusOK {
+        t.Errorf("GET /users/{id} returned %d, want %d", w.Code, http.StatusOK)
+    }
+    if w := serve("DELETE", "/users/"+created.ID, ""); w.Code != http.StatusNoContent {
+        t.Errorf("DELETE /users/{id} returned %d, want %d", w.Code, http.StatusNoContent)
+    }
+    if w := serve("GET", "/users/"+created.ID, ""); w.Code != http.StatusNotFound {
+        t.Errorf("GET /users/{id} after delete returned %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
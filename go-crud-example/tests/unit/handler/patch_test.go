@@ -0,0 +1,176 @@
+package handler
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+    "go-crud-example/pkg/problem"
+)
+
+func TestUserHandler_PatchUser(t *testing.T) {
+    tests := []struct {
+        name        string
+        contentType string
+        ifMatch     string
+        body        string
+        wantCode    int
+        wantProblem string
+        wantUser    model.User
+    }{
+        {
+            name:        "merge patch",
+            contentType: "application/merge-patch+json",
+            body:        `{"age": 31}`,
+            wantCode:    http.StatusOK,
+            wantUser:    model.User{ID: "1", Name: "John", Age: 31, Version: 2},
+        },
+        {
+            name:        "merge patch with charset",
+            contentType: "application/merge-patch+json; charset=utf-8",
+            body:        `{"name": "Johnny"}`,
+            wantCode:    http.StatusOK,
+            wantUser:    model.User{ID: "1", Name: "Johnny", Age: 30, Version: 2},
+        },
+        {
+            name:        "json patch",
+            contentType: "application/json-patch+json",
+            ifMatch:     `"1"`,
+            body:        `[{"op": "test", "path": "/age", "value": 30}, {"op": "replace", "path": "/age", "value": 40}]`,
+            wantCode:    http.StatusOK,
+            wantUser:    model.User{ID: "1", Name: "John", Age: 40, Version: 2},
+        },
+        {
+            name:        "no changes keeps version",
+            contentType: "application/merge-patch+json",
+            body:        `{"age": 30}`,
+            wantCode:    http.StatusOK,
+            wantUser:    model.User{ID: "1", Name: "John", Age: 30, Version: 1},
+        },
+        {
+            name:        "merge patch sets email",
+            contentType: "application/merge-patch+json",
+            body:        `{"email": "john@example.com"}`,
+            wantCode:    http.StatusOK,
+            wantUser:    model.User{ID: "1", Name: "John", Age: 30, Email: "john@example.com", Version: 2},
+        },
+        {
+            name:        "read-only created_at",
+            contentType: "application/json-patch+json",
+            body:        `[{"op": "replace", "path": "/created_at", "value": "2000-01-01T00:00:00Z"}]`,
+            wantCode:    http.StatusBadRequest,
+            wantProblem: problem.CodeValidationFailed,
+        },
+        {
+            name:        "plain json is not a patch",
+            contentType: "application/json",
+            body:        `{"age": 31}`,
+            wantCode:    http.StatusUnsupportedMediaType,
+            wantProblem: handler.CodeUnsupportedMediaType,
+        },
+        {
+            name:        "malformed json patch",
+            contentType: "application/json-patch+json",
+            body:        `{"op": "replace"}`,
+            wantCode:    http.StatusBadRequest,
+            wantProblem: handler.CodeInvalidPatch,
+        },
+        {
+            name:        "json patch on missing path",
+            contentType: "application/json-patch+json",
+            body:        `[{"op": "remove", "path": "/nickname"}]`,
+            wantCode:    http.StatusBadRequest,
+            wantProblem: handler.CodeInvalidPatch,
+        },
+        {
+            name:        "failed test operation",
+            contentType: "application/json-patch+json",
+            body:        `[{"op": "test", "path": "/name", "value": "Ann"}]`,
+            wantCode:    http.StatusConflict,
+            wantProblem: handler.CodePatchTestFailed,
+        },
+        {
+            name:        "invalid result",
+            contentType: "application/merge-patch+json",
+            body:        `{"age": -1}`,
+            wantCode:    http.StatusBadRequest,
+            wantProblem: problem.CodeValidationFailed,
+        },
+        {
+            name:        "too large body",
+            contentType: "application/merge-patch+json",
+            body:        `{"name": "` + strings.Repeat("a", 1<<20) + `"}`,
+            wantCode:    http.StatusRequestEntityTooLarge,
+            wantProblem: handler.CodeRequestTooLarge,
+        },
+        {
+            name:        "stale if-match",
+            contentType: "application/merge-patch+json",
+            ifMatch:     `"7"`,
+            body:        `{"age": 31}`,
+            wantCode:    http.StatusPreconditionFailed,
+            wantProblem: problem.CodePreconditionFailed,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            repo := repository.NewMemoryUserRepository()
+            if err := repo.Create(context.Background(), &model.User{Name: "John", Age: 30}); err != nil {
+                t.Fatalf("Create() error = %v", err)
+            }
+            router := mux.NewRouter()
+            logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+            handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+            r := httptest.NewRequest("PATCH", "/users/1", bytes.NewBufferString(tt.body))
+            r.Header.Set("Content-Type", tt.contentType)
+            if tt.ifMatch != "" {
+                r.Header.Set("If-Match", tt.ifMatch)
+            }
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, r)
+
+            if w.Code != tt.wantCode {
+                t.Fatalf("PATCH /users/1 returned %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+            }
+            if tt.wantProblem != "" {
+                var p problem.Problem
+                if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+                    t.Fatalf("failed to decode problem: %v", err)
+                }
+                if p.Code != tt.wantProblem {
+                    t.Errorf("problem code = %q, want %q", p.Code, tt.wantProblem)
+                }
+                return
+            }
+
+            var got model.User
+            if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+                t.Fatalf("failed to decode user: %v", err)
+            }
+            if got.CreatedAt.IsZero() || got.UpdatedAt.Before(got.CreatedAt) {
+                t.Errorf("PATCH /users/1 created_at = %v, updated_at = %v", got.CreatedAt, got.UpdatedAt)
+            }
+            got.CreatedAt, got.UpdatedAt = time.Time{}, time.Time{}
+            if got != tt.wantUser {
+                t.Errorf("PATCH /users/1 = %+v, want %+v", got, tt.wantUser)
+            }
+            if etag := w.Header().Get("ETag"); etag == "" {
+                t.Error("PATCH /users/1 response has no ETag")
+            }
+        })
+    }
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+)
+
+func TestUserHandler_SearchUsers(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+    expect := func(method, path, body string, want int) *httptest.ResponseRecorder {
+        t.Helper()
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+        if w.Code != want {
+            t.Fatalf("%s %s returned %d, want %d: %s", method, path, w.Code, want, w.Body)
+        }
+        return w
+    }
+
+    expect("POST", "/users", `{"name": "Anna Lee", "age": 25}`, http.StatusOK)
+    expect("POST", "/users", `{"name": "Ann Taylor", "age": 41}`, http.StatusOK)
+    expect("POST", "/users", `{"name": "Bob", "age": 40}`, http.StatusOK)
+
+    // Маршрут /users/search не должен попадать в /users/{id}
+    w := expect("GET", "/users/search?q=ANN&limit=1", "", http.StatusOK)
+    var got model.SearchResults
+    if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+        t.Fatalf("failed to decode results: %v", err)
+    }
+    if len(got.Results) != 1 || got.Results[0].User.Name != "Ann Taylor" || got.Results[0].Highlights["name"] != "<mark>Ann</mark> Taylor" {
+        t.Errorf("search results = %+v", got.Results)
+    }
+
+    // Пустой результат - пустой массив, а не null
+    w = expect("GET", "/users/search?q=zzz", "", http.StatusOK)
+    if body := strings.TrimSpace(w.Body.String()); body != `{"results":[]}` {
+        t.Errorf("empty search body = %s", body)
+    }
+
+    for _, path := range []string{"/users/search", "/users/search?q=%20", "/users/search?q=ann&limit=0", "/users/search?q=ann&limit=101", "/users/search?q=ann&limit=x"} {
+        w := expect("GET", path, "", http.StatusBadRequest)
+        if !strings.Contains(w.Body.String(), handler.CodeInvalidQuery) {
+            t.Errorf("GET %s body = %s", path, w.Body)
+        }
+    }
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/internal/handler"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/service"
+)
+
+func TestUserHandler_SoftDelete(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    router := mux.NewRouter()
+    handler.NewUserHandler(service.NewUserService(repo), logger).RegisterRoutes(router)
+
+    serve := func(method, path, body string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+        return w
+    }
+    expect := func(method, path string, want int) *httptest.ResponseRecorder {
+        t.Helper()
+        w := serve(method, path, "")
+        if w.Code != want {
+            t.Fatalf("%s %s returned %d, want %d: %s", method, path, w.Code, want, w.Body)
+        }
+        return w
+    }
+
+    if w := serve("POST", "/users", `{"name": "John", "age": 30}`); w.Code != http.StatusOK {
+        t.Fatalf("POST /users returned %d: %s", w.Code, w.Body)
+    }
+
+    expect("POST", "/users/1:purge", http.StatusConflict)
+    expect("DELETE", "/users/1", http.StatusNoContent)
+    expect("GET", "/users/1", http.StatusNotFound)
+    expect("DELETE", "/users/1", http.StatusNotFound)
+
+    var page model.UserPage
+    w := expect("GET", "/users", http.StatusOK)
+    if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+        t.Fatalf("failed to decode page: %v", err)
+    }
+    if page.Total != 0 {
+        t.Errorf("GET /users total = %d, want 0", page.Total)
+    }
+
+    w = expect("GET", "/users?include_deleted=true", http.StatusOK)
+    if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+        t.Fatalf("failed to decode page: %v", err)
+    }
+    if page.Total != 1 || page.Users[0].DeletedAt == nil {
+        t.Errorf("GET /users?include_deleted=true = %+v, want one deleted user", page.Users)
+    }
+    expect("GET", "/users?include_deleted=maybe", http.StatusBadRequest)
+
+    w = expect("POST", "/users/1:restore", http.StatusOK)
+    var restored model.User
+    if err := json.NewDecoder(w.Body).Decode(&restored); err != nil {
+        t.Fatalf("failed to decode user: %v", err)
+    }
+    if restored.DeletedAt != nil || restored.Version != 3 {
+        t.Errorf("POST /users/1:restore = %+v, want active user with version 3", restored)
+    }
+    if got := w.Header().Get("ETag"); got != `"3"` {
+        t.Errorf("POST /users/1:restore ETag = %q, want %q", got, `"3"`)
+    }
+    expect("GET", "/users/1", http.StatusOK)
+
+    expect("DELETE", "/users/1", http.StatusNoContent)
+    expect("POST", "/users/1:purge", http.StatusNoContent)
+    expect("POST", "/users/1:restore", http.StatusNotFound)
+    expect("POST", "/users/1:purge", http.StatusNotFound)
+}
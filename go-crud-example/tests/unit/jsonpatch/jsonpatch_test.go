@@ -0,0 +1,131 @@
+package jsonpatch
+
+import (
+    "encoding/json"
+    "errors"
+    "reflect"
+    "testing"
+
+    "go-crud-example/pkg/jsonpatch"
+)
+
+func TestMergePatch(t *testing.T) {
+    // Примеры из приложения A RFC 7396
+    tests := []struct {
+        doc   string
+        patch string
+        want  string
+    }{
+        {`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+        {`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+        {`{"a":"b"}`, `{"a":null}`, `{}`},
+        {`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+        {`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+        {`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+        {`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+        {`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+        {`["a","b"]`, `["c","d"]`, `["c","d"]`},
+        {`{"a":"b"}`, `["c"]`, `["c"]`},
+        {`{"a":"foo"}`, `null`, `null`},
+        {`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+        {`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+        {`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.patch, func(t *testing.T) {
+            got, err := jsonpatch.MergePatch([]byte(tt.doc), []byte(tt.patch))
+            if err != nil {
+                t.Fatalf("MergePatch() error = %v", err)
+            }
+            assertJSONEqual(t, got, tt.want)
+        })
+    }
+
+    if _, err := jsonpatch.MergePatch([]byte(`{}`), []byte(`{"a":`)); !errors.Is(err, jsonpatch.ErrInvalidPatch) {
+        t.Errorf("MergePatch() with malformed patch error = %v, want ErrInvalidPatch", err)
+    }
+}
+
+func TestApply(t *testing.T) {
+    // Примеры из приложения A RFC 6902
+    tests := []struct {
+        name  string
+        doc   string
+        patch string
+        want  string
+    }{
+        {"add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+        {"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+        {"add to array end", `{"foo":[1]}`, `[{"op":"add","path":"/foo/-","value":2}]`, `{"foo":[1,2]}`},
+        {"remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+        {"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+        {"replace value", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+        {"move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+            `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+            `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+        {"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+        {"copy value", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+        {"test success", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+        {"add nested object", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+        {"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+        {"add null value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"foo":"bar","baz":null}`},
+        {"replace whole document", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := jsonpatch.Apply([]byte(tt.doc), []byte(tt.patch))
+            if err != nil {
+                t.Fatalf("Apply() error = %v", err)
+            }
+            assertJSONEqual(t, got, tt.want)
+        })
+    }
+}
+
+func TestApply_Errors(t *testing.T) {
+    tests := []struct {
+        name    string
+        doc     string
+        patch   string
+        wantErr error
+    }{
+        {"malformed patch", `{}`, `{"op":"add"}`, jsonpatch.ErrInvalidPatch},
+        {"unknown operation", `{}`, `[{"op":"merge","path":"/a","value":1}]`, jsonpatch.ErrInvalidPatch},
+        {"missing path", `{}`, `[{"op":"add","value":1}]`, jsonpatch.ErrInvalidPatch},
+        {"missing value", `{}`, `[{"op":"add","path":"/a"}]`, jsonpatch.ErrInvalidPatch},
+        {"add to missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, jsonpatch.ErrInvalidPatch},
+        {"remove missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, jsonpatch.ErrInvalidPatch},
+        {"replace missing member", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, jsonpatch.ErrInvalidPatch},
+        {"array index out of range", `{"foo":[1]}`, `[{"op":"add","path":"/foo/5","value":2}]`, jsonpatch.ErrInvalidPatch},
+        {"array index with leading zero", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/01"}]`, jsonpatch.ErrInvalidPatch},
+        {"pointer without slash", `{"foo":1}`, `[{"op":"remove","path":"foo"}]`, jsonpatch.ErrInvalidPatch},
+        {"move into own child", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, jsonpatch.ErrInvalidPatch},
+        {"test failure", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, jsonpatch.ErrTestFailed},
+        {"test number vs string", `{"n":10}`, `[{"op":"test","path":"/n","value":"10"}]`, jsonpatch.ErrTestFailed},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := jsonpatch.Apply([]byte(tt.doc), []byte(tt.patch)); !errors.Is(err, tt.wantErr) {
+                t.Errorf("Apply() error = %v, want %v", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+    t.Helper()
+
+    var g, w interface{}
+    if err := json.Unmarshal(got, &g); err != nil {
+        t.Fatalf("result is not valid JSON: %v", err)
+    }
+    if err := json.Unmarshal([]byte(want), &w); err != nil {
+        t.Fatalf("expected value is not valid JSON: %v", err)
+    }
+    if !reflect.DeepEqual(g, w) {
+        t.Errorf("got %s, want %s", got, want)
+    }
+}
@@ -0,0 +1,396 @@
+package jwt
+
+import (
+    "context"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "math/big"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "go-crud-example/pkg/jwt"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+// sign собирает токен с заголовком {alg, kid} и подписывает его ключом key
+func sign(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+    t.Helper()
+
+    enc := base64.RawURLEncoding
+    header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+    payload, _ := json.Marshal(claims)
+    signed := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+    digest := sha256.Sum256([]byte(signed))
+
+    var signature []byte
+    switch k := key.(type) {
+    case []byte:
+        mac := hmac.New(sha256.New, k)
+        mac.Write([]byte(signed))
+        signature = mac.Sum(nil)
+    case *rsa.PrivateKey:
+        var err error
+        if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+            t.Fatal(err)
+        }
+    case *ecdsa.PrivateKey:
+        r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+        if err != nil {
+            t.Fatal(err)
+        }
+        signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+    }
+    return signed + "." + enc.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+    return map[string]interface{}{
+        "sub":  "alice",
+        "iss":  "https://issuer.example",
+        "aud":  []string{"users-api", "other"},
+        "exp":  time.Now().Add(time.Hour).Unix(),
+        "role": "admin",
+    }
+}
+
+func with(claims map[string]interface{}, key string, value interface{}) map[string]interface{} {
+    claims[key] = value
+    return claims
+}
+
+func without(claims map[string]interface{}, key string) map[string]interface{} {
+    delete(claims, key)
+    return claims
+}
+
+func TestVerifier_HS256(t *testing.T) {
+    verifier := jwt.NewVerifier(jwt.Secret(secret), jwt.Options{
+        Issuer:   "https://issuer.example",
+        Audience: "users-api",
+        Leeway:   time.Minute,
+    })
+    ctx := context.Background()
+
+    claims, err := verifier.Verify(ctx, sign(t, jwt.HS256, "", secret, validClaims()))
+    if err != nil {
+        t.Fatalf("Verify() error = %v", err)
+    }
+    if claims.Subject != "alice" || !claims.Audience.Contains("users-api") || claims.Raw["role"] != "admin" {
+        t.Errorf("Verify() claims = %+v", claims)
+    }
+
+    tests := []struct {
+        name    string
+        token   string
+        wantErr error
+    }{
+        {"wrong secret", sign(t, jwt.HS256, "", []byte("another secret"), validClaims()), jwt.ErrSignature},
+        {"expired", sign(t, jwt.HS256, "", secret, with(validClaims(), "exp", time.Now().Add(-2*time.Minute).Unix())), jwt.ErrExpired},
+        {"expired within leeway", sign(t, jwt.HS256, "", secret, with(validClaims(), "exp", time.Now().Add(-30*time.Second).Unix())), nil},
+        {"not valid yet", sign(t, jwt.HS256, "", secret, with(validClaims(), "nbf", time.Now().Add(time.Hour).Unix())), jwt.ErrNotValidYet},
+        {"without exp", sign(t, jwt.HS256, "", secret, without(validClaims(), "exp")), jwt.ErrInvalidClaims},
+        {"without sub", sign(t, jwt.HS256, "", secret, without(validClaims(), "sub")), jwt.ErrInvalidClaims},
+        {"wrong issuer", sign(t, jwt.HS256, "", secret, with(validClaims(), "iss", "https://evil.example")), jwt.ErrInvalidClaims},
+        {"wrong audience", sign(t, jwt.HS256, "", secret, with(validClaims(), "aud", "other")), jwt.ErrInvalidClaims},
+        {"alg none", sign(t, "none", "", secret, validClaims()), jwt.ErrUnsupportedAlgorithm},
+        {"RS256 with secret", sign(t, jwt.RS256, "", secret, validClaims()), jwt.ErrUnknownKey},
+        {"two segments", "a.b", jwt.ErrMalformed},
+        {"bad base64", "a.b.c!", jwt.ErrMalformed},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := verifier.Verify(ctx, tt.token)
+            if !errors.Is(err, tt.wantErr) {
+                t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+            }
+            if err != nil && !errors.Is(err, jwt.ErrInvalidToken) {
+                t.Errorf("Verify() error = %v does not wrap ErrInvalidToken", err)
+            }
+        })
+    }
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+    return map[string]string{
+        "kty": "RSA", "kid": kid, "use": "sig", "alg": jwt.RS256,
+        "n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+        "e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+    }
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+    return map[string]string{
+        "kty": "EC", "kid": kid, "crv": "P-256",
+        "x": base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+        "y": base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+    }
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+    t.Helper()
+    data, _ := json.Marshal(map[string]interface{}{"keys": keys})
+    if err := os.WriteFile(path, data, 0o600); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestVerifier_JWKS(t *testing.T) {
+    rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ctx := context.Background()
+
+    path := filepath.Join(t.TempDir(), "jwks.json")
+    writeJWKS(t, path, rsaJWK("rsa-1", rsaKey), ecJWK("ec-1", ecKey),
+        map[string]string{"kty": "EC", "kid": "p384", "crv": "P-384"},
+        map[string]string{"kty": "RSA", "kid": "enc", "use": "enc"})
+    jwks := jwt.NewJWKS(path, time.Hour)
+    if err := jwks.Load(ctx); err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    verifier := jwt.NewVerifier(jwks, jwt.Options{})
+
+    for _, token := range []string{
+        sign(t, jwt.RS256, "rsa-1", rsaKey, validClaims()),
+        sign(t, jwt.ES256, "ec-1", ecKey, validClaims()),
+    } {
+        if _, err := verifier.Verify(ctx, token); err != nil {
+            t.Errorf("Verify() error = %v", err)
+        }
+    }
+
+    // Ключ используется только со своим алгоритмом и kid
+    if _, err := verifier.Verify(ctx, sign(t, jwt.ES256, "rsa-1", ecKey, validClaims())); !errors.Is(err, jwt.ErrUnknownKey) {
+        t.Errorf("Verify() with mismatched alg error = %v, want ErrUnknownKey", err)
+    }
+    other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if _, err := verifier.Verify(ctx, sign(t, jwt.ES256, "ec-1", other, validClaims())); !errors.Is(err, jwt.ErrSignature) {
+        t.Errorf("Verify() with foreign key error = %v, want ErrSignature", err)
+    }
+    // Открытый ключ RSA не может служить секретом HS256
+    pub := rsaJWK("rsa-1", rsaKey)["n"]
+    if _, err := verifier.Verify(ctx, sign(t, jwt.HS256, "rsa-1", []byte(pub), validClaims())); !errors.Is(err, jwt.ErrUnknownKey) {
+        t.Errorf("Verify() with HS256 confusion error = %v, want ErrUnknownKey", err)
+    }
+    if _, err := verifier.Verify(ctx, sign(t, jwt.RS256, "", rsaKey, validClaims())); !errors.Is(err, jwt.ErrUnknownKey) {
+        t.Errorf("Verify() without kid error = %v, want ErrUnknownKey", err)
+    }
+
+    // Некорректный набор не загружается
+    writeJWKS(t, path, map[string]string{"kty": "EC", "kid": "bad", "crv": "P-256", "x": "AAAA", "y": "AAAA"})
+    if err := jwt.NewJWKS(path, time.Hour).Load(ctx); err == nil {
+        t.Error("Load() of invalid key succeeded")
+    }
+}
+
+func TestJWKS_Rotation(t *testing.T) {
+    oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    var (
+        current  atomic.Value
+        requests atomic.Int32
+    )
+    current.Store([]map[string]string{ecJWK("old", oldKey)})
+
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        _ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": current.Load()})
+    }))
+    defer server.Close()
+
+    ctx := context.Background()
+    verifier := jwt.NewVerifier(jwt.NewJWKS(server.URL, 10*time.Millisecond), jwt.Options{})
+
+    verify := func(kid string, key *ecdsa.PrivateKey) error {
+        _, err := verifier.Verify(ctx, sign(t, jwt.ES256, kid, key, validClaims()))
+        return err
+    }
+    if err := verify("old", oldKey); err != nil {
+        t.Fatalf("Verify() error = %v", err)
+    }
+    if err := verify("old", oldKey); err != nil || requests.Load() != 1 {
+        t.Fatalf("Verify() error = %v after %d requests, want cached keys", err, requests.Load())
+    }
+
+    // Новый ключ подхватывается после смены набора
+    current.Store([]map[string]string{ecJWK("new", newKey)})
+    time.Sleep(20 * time.Millisecond)
+    if err := verify("new", newKey); err != nil {
+        t.Errorf("Verify() with rotated key error = %v", err)
+    }
+    if err := verify("old", oldKey); !errors.Is(err, jwt.ErrUnknownKey) {
+        t.Errorf("Verify() with removed key error = %v, want ErrUnknownKey", err)
+    }
+
+    // Недоступный источник не мешает проверять загруженными ключами
+    server.Close()
+    time.Sleep(20 * time.Millisecond)
+    if err := verify("new", newKey); err != nil {
+        t.Errorf("Verify() with unavailable JWKS error = %v", err)
+    }
+}
+
+func TestJWKS_UnavailableSource(t *testing.T) {
+    key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    var requests atomic.Int32
+    release := make(chan struct{})
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        // Источник отвечает только на первый запрос, остальные зависают
+        if requests.Add(1) > 1 {
+            <-release
+            return
+        }
+        _ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{ecJWK("k", key)}})
+    }))
+    defer server.Close()
+    defer close(release)
+
+    ctx := context.Background()
+    jwks := jwt.NewJWKS(server.URL, 10*time.Millisecond)
+    verifier := jwt.NewVerifier(jwks, jwt.Options{})
+    if err := jwks.Load(ctx); err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+
+    // Пока источник не отвечает, устаревший набор перечитывается одной фоновой
+    // загрузкой, а токены проверяются загруженными ключами без ожидания
+    time.Sleep(20 * time.Millisecond)
+    token := sign(t, jwt.ES256, "k", key, validClaims())
+    verify := func() {
+        t.Helper()
+        for i := 0; i < 10; i++ {
+            if _, err := verifier.Verify(ctx, token); err != nil {
+                t.Fatalf("Verify() with stale keys error = %v", err)
+            }
+        }
+    }
+    verify()
+    for deadline := time.Now().Add(time.Second); requests.Load() < 2 && time.Now().Before(deadline); {
+        time.Sleep(time.Millisecond)
+    }
+    verify()
+    if got := requests.Load(); got != 2 {
+        t.Errorf("JWKS requested %d times, want 2", got)
+    }
+
+    // Запрос с неизвестным kid ждет текущую загрузку не дольше своего контекста
+    timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+    defer cancel()
+    if _, err := verifier.Verify(timeoutCtx, sign(t, jwt.ES256, "other", key, validClaims())); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("Verify() with unknown kid error = %v, want context.DeadlineExceeded", err)
+    }
+}
+
+func TestJWKS_LoadBackoff(t *testing.T) {
+    var requests atomic.Int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        w.WriteHeader(http.StatusServiceUnavailable)
+    }))
+    defer server.Close()
+
+    // После неудачной загрузки ошибка возвращается без повторных запросов к источнику
+    ctx := context.Background()
+    jwks := jwt.NewJWKS(server.URL, time.Hour)
+    for i := 0; i < 5; i++ {
+        if _, err := jwks.Key(ctx, "k", jwt.ES256); err == nil || errors.Is(err, jwt.ErrUnknownKey) {
+            t.Fatalf("Key() error = %v, want load error", err)
+        }
+    }
+    if got := requests.Load(); got != 1 {
+        t.Errorf("JWKS requested %d times, want 1", got)
+    }
+}
+
+func TestKeySets_JWKSBeforeSecret(t *testing.T) {
+    ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    octSecret := []byte("fedcba9876543210fedcba9876543210")
+    var requests atomic.Int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests.Add(1)
+        _ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+            ecJWK("ec-1", ecKey),
+            {"kty": "oct", "kid": "hs-1", "alg": jwt.HS256, "k": base64.RawURLEncoding.EncodeToString(octSecret)},
+        }})
+    }))
+    defer server.Close()
+
+    ctx := context.Background()
+    jwks := jwt.NewJWKS(server.URL, time.Hour)
+    if err := jwks.Load(ctx); err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    verifier := jwt.NewVerifier(jwt.KeySets{jwks, jwt.Secret(secret)}, jwt.Options{})
+
+    // Ключ oct из набора выбирается по kid, хотя задан и общий секрет
+    if _, err := verifier.Verify(ctx, sign(t, jwt.HS256, "hs-1", octSecret, validClaims())); err != nil {
+        t.Errorf("Verify() with JWKS oct key error = %v", err)
+    }
+    if _, err := verifier.Verify(ctx, sign(t, jwt.HS256, "hs-1", secret, validClaims())); !errors.Is(err, jwt.ErrSignature) {
+        t.Errorf("Verify() with secret under JWKS kid error = %v, want ErrSignature", err)
+    }
+    if _, err := verifier.Verify(ctx, sign(t, jwt.ES256, "ec-1", ecKey, validClaims())); err != nil {
+        t.Errorf("Verify() with JWKS EC key error = %v", err)
+    }
+
+    // Токен без kid проверяется секретом и не вызывает перечитывания набора
+    if _, err := verifier.Verify(ctx, sign(t, jwt.HS256, "", secret, validClaims())); err != nil {
+        t.Errorf("Verify() with static secret error = %v", err)
+    }
+    if got := requests.Load(); got != 1 {
+        t.Errorf("JWKS requested %d times, want 1", got)
+    }
+}
+
+func TestVerifier_Roles(t *testing.T) {
+    ctx := context.Background()
+    tests := []struct {
+        name       string
+        rolesClaim string
+        claims     map[string]interface{}
+        want       []string
+        wantErr    error
+    }{
+        {name: "default claim", claims: with(validClaims(), "roles", []string{"admin", "editor"}), want: []string{"admin", "editor"}},
+        {name: "single role", claims: with(validClaims(), "roles", "viewer"), want: []string{"viewer"}},
+        {name: "no roles", claims: validClaims(), want: nil},
+        {
+            name: "nested claim", rolesClaim: "realm_access.roles",
+            claims: with(validClaims(), "realm_access", map[string]interface{}{"roles": []string{"editor"}}),
+            want:   []string{"editor"},
+        },
+        {name: "not strings", claims: with(validClaims(), "roles", []int{1}), wantErr: jwt.ErrInvalidClaims},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            verifier := jwt.NewVerifier(jwt.Secret(secret), jwt.Options{RolesClaim: tt.rolesClaim})
+            claims, err := verifier.Verify(ctx, sign(t, jwt.HS256, "", secret, tt.claims))
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+            }
+            if err == nil && !reflect.DeepEqual(claims.Roles, tt.want) {
+                t.Errorf("Verify() roles = %v, want %v", claims.Roles, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,189 @@
+package lifecycle
+
+import (
+    "context"
+    "io"
+    "log/slog"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "sync"
+    "testing"
+    "time"
+
+    "go-crud-example/pkg/lifecycle"
+)
+
+func TestManager_Run(t *testing.T) {
+    manager := lifecycle.NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)), time.Second, 0)
+    manager.AddServer("api", &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()})
+
+    var closed []string
+    manager.OnShutdown("database", func(context.Context) error {
+        closed = append(closed, "database")
+        return nil
+    })
+    manager.OnShutdown("cache", func(context.Context) error {
+        closed = append(closed, "cache")
+        return nil
+    })
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        done <- manager.Run(ctx)
+    }()
+
+    deadline := time.Now().Add(time.Second)
+    for !manager.Ready() {
+        if time.Now().After(deadline) {
+            t.Fatal("manager did not become ready")
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+
+    w := httptest.NewRecorder()
+    manager.ReadinessHandler().ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+    if w.Code != http.StatusOK {
+        t.Errorf("readiness returned %d before shutdown, want %d", w.Code, http.StatusOK)
+    }
+
+    cancel()
+    if err := <-done; err != nil {
+        t.Fatalf("Run() error = %v", err)
+    }
+
+    w = httptest.NewRecorder()
+    manager.ReadinessHandler().ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+    if w.Code != http.StatusServiceUnavailable {
+        t.Errorf("readiness returned %d after shutdown, want %d", w.Code, http.StatusServiceUnavailable)
+    }
+
+    if want := []string{"cache", "database"}; !reflect.DeepEqual(closed, want) {
+        t.Errorf("closers ran in order %v, want %v", closed, want)
+    }
+}
+
+func TestManager_RunListenError(t *testing.T) {
+    manager := lifecycle.NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)), time.Second, 0)
+    manager.AddServer("api", &http.Server{Addr: "invalid-address"})
+
+    closed := false
+    manager.OnShutdown("database", func(context.Context) error {
+        closed = true
+        return nil
+    })
+
+    if err := manager.Run(context.Background()); err == nil {
+        t.Fatal("Run() expected listen error")
+    }
+    if !closed {
+        t.Error("resources were not released after listen error")
+    }
+}
+
+func TestManager_BackgroundTasks(t *testing.T) {
+    manager := lifecycle.NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)), time.Second, 0)
+
+    started := make(chan struct{})
+    var events []string
+    manager.Go("purger", func(ctx context.Context) {
+        close(started)
+        <-ctx.Done()
+        events = append(events, "task stopped")
+    })
+    manager.OnShutdown("database", func(context.Context) error {
+        events = append(events, "database closed")
+        return nil
+    })
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        done <- manager.Run(ctx)
+    }()
+
+    select {
+    case <-started:
+    case <-time.After(time.Second):
+        t.Fatal("background task was not started")
+    }
+
+    cancel()
+    if err := <-done; err != nil {
+        t.Fatalf("Run() error = %v", err)
+    }
+
+    // Задача останавливается до освобождения ресурсов, которыми пользуется
+    if want := []string{"task stopped", "database closed"}; !reflect.DeepEqual(events, want) {
+        t.Errorf("shutdown events = %v, want %v", events, want)
+    }
+}
+
+func TestManager_CancelsUnfinishedRequests(t *testing.T) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    addr := listener.Addr().String()
+    listener.Close()
+
+    var (
+        mu     sync.Mutex
+        events []string
+    )
+    record := func(event string) {
+        mu.Lock()
+        defer mu.Unlock()
+        events = append(events, event)
+    }
+
+    // Потоковый ответ без таймаута пишет, пока не отменят контекст запроса
+    started := make(chan struct{})
+    manager := lifecycle.NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)), 50*time.Millisecond, 0)
+    manager.AddServer("api", &http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        close(started)
+        <-r.Context().Done()
+        time.Sleep(20 * time.Millisecond)
+        record("request finished")
+    })})
+    manager.OnShutdown("database", func(context.Context) error {
+        record("database closed")
+        return nil
+    })
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan error, 1)
+    go func() {
+        done <- manager.Run(ctx)
+    }()
+
+    go func() {
+        for {
+            resp, err := http.Get("http://" + addr)
+            if err == nil {
+                resp.Body.Close()
+                return
+            }
+            select {
+            case <-started:
+                return
+            case <-time.After(5 * time.Millisecond):
+            }
+        }
+    }()
+    select {
+    case <-started:
+    case <-time.After(time.Second):
+        t.Fatal("request was not started")
+    }
+
+    cancel()
+    if err := <-done; err == nil {
+        t.Error("Run() expected shutdown timeout error")
+    }
+    if want := []string{"request finished", "database closed"}; !reflect.DeepEqual(events, want) {
+        t.Errorf("shutdown events = %v, want %v", events, want)
+    }
+}
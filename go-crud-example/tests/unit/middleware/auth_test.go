@@ -0,0 +1,131 @@
+package middleware
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/pkg/actor"
+    "go-crud-example/pkg/jwt"
+    "go-crud-example/pkg/middleware"
+)
+
+var authSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func hs256Token(claims map[string]interface{}) string {
+    enc := base64.RawURLEncoding
+    header, _ := json.Marshal(map[string]string{"alg": jwt.HS256, "typ": "JWT"})
+    payload, _ := json.Marshal(claims)
+    signed := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+    mac := hmac.New(sha256.New, authSecret)
+    mac.Write([]byte(signed))
+    return signed + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthMiddleware(t *testing.T) {
+    verifier := jwt.NewVerifier(jwt.Secret(authSecret), jwt.Options{})
+    keyExpiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+    apiKeys := func(ctx context.Context, key string) (string, []string, *time.Time, error) {
+        switch key {
+        case "valid-key":
+            return "apikey:1", []string{"users:read"}, &keyExpiresAt, nil
+        case "broken-store":
+            return "", nil, nil, errors.New("connection refused")
+        }
+        return "", nil, nil, middleware.ErrInvalidAPIKey
+    }
+
+    router := mux.NewRouter()
+    router.Use(middleware.AuthMiddleware(middleware.AuthOptions{
+        Verifier: verifier,
+        APIKey:   apiKeys,
+        Exempt:   []string{"/health", "/swagger/"},
+    }))
+
+    whoami := func(w http.ResponseWriter, r *http.Request) {
+        claims, ok := middleware.ClaimsFromContext(r.Context())
+        if ok && claims.Subject != actor.FromContext(r.Context()) {
+            t.Errorf("actor = %q, subject = %q", actor.FromContext(r.Context()), claims.Subject)
+        }
+        if scopes, ok := actor.ScopesFromContext(r.Context()); ok && (claims != nil || len(scopes) != 1) {
+            t.Errorf("unexpected scopes %v for claims %v", scopes, claims)
+        }
+        // Срок передается только для API-ключа
+        if expiresAt, ok := actor.ExpiryFromContext(r.Context()); ok != (actor.FromContext(r.Context()) == "apikey:1") || (ok && !expiresAt.Equal(keyExpiresAt)) {
+            t.Errorf("actor expiry = %v, %v", expiresAt, ok)
+        }
+        _, _ = w.Write([]byte(actor.FromContext(r.Context())))
+    }
+    router.HandleFunc("/users", whoami).Methods("GET")
+    router.HandleFunc("/health", whoami).Methods("GET")
+    router.PathPrefix("/swagger/").HandlerFunc(whoami)
+
+    valid := hs256Token(map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+    expired := hs256Token(map[string]interface{}{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()})
+
+    tests := []struct {
+        name          string
+        path          string
+        authorization string
+        apiKey        string
+        wantCode      int
+        wantBody      string
+        wantChallenge string
+    }{
+        {name: "valid token", path: "/users", authorization: "Bearer " + valid, wantCode: http.StatusOK, wantBody: "alice"},
+        {name: "scheme is case-insensitive", path: "/users", authorization: "bearer " + valid, wantCode: http.StatusOK, wantBody: "alice"},
+        {name: "no token", path: "/users", wantCode: http.StatusUnauthorized, wantChallenge: `Bearer realm="api"`},
+        {name: "basic auth", path: "/users", authorization: "Basic YWxpY2U6c2VjcmV0", wantCode: http.StatusUnauthorized, wantChallenge: `Bearer realm="api"`},
+        {
+            name: "expired token", path: "/users", authorization: "Bearer " + expired, wantCode: http.StatusUnauthorized,
+            wantChallenge: `Bearer realm="api", error="invalid_token", error_description="invalid token: token is expired"`,
+        },
+        {name: "api key header", path: "/users", apiKey: "valid-key", wantCode: http.StatusOK, wantBody: "apikey:1"},
+        {name: "api key scheme", path: "/users", authorization: "ApiKey valid-key", wantCode: http.StatusOK, wantBody: "apikey:1"},
+        {name: "invalid api key", path: "/users", apiKey: "revoked-key", wantCode: http.StatusUnauthorized, wantChallenge: `Bearer realm="api"`},
+        {name: "api key store failure", path: "/users", apiKey: "broken-store", wantCode: http.StatusInternalServerError},
+        {name: "exempt path", path: "/health", wantCode: http.StatusOK, wantBody: actor.Anonymous},
+        {name: "exempt prefix", path: "/swagger/index.html", wantCode: http.StatusOK, wantBody: actor.Anonymous},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := httptest.NewRequest("GET", tt.path, nil)
+            if tt.authorization != "" {
+                r.Header.Set("Authorization", tt.authorization)
+            }
+            if tt.apiKey != "" {
+                r.Header.Set("X-API-Key", tt.apiKey)
+            }
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, r)
+
+            if w.Code != tt.wantCode {
+                t.Fatalf("GET %s returned %d, want %d: %s", tt.path, w.Code, tt.wantCode, w.Body)
+            }
+            if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+                t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+            }
+            if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+                t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+            }
+            if tt.wantCode == http.StatusUnauthorized {
+                if !strings.Contains(w.Body.String(), middleware.CodeUnauthorized) {
+                    t.Errorf("unexpected 401 body: %s", w.Body)
+                }
+                // Клиент узнает обе принимаемые схемы
+                if challenges := w.Header().Values("WWW-Authenticate"); len(challenges) != 2 || challenges[1] != `ApiKey realm="api"` {
+                    t.Errorf("WWW-Authenticate = %q, want Bearer and ApiKey challenges", challenges)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/pkg/idempotency"
+    "go-crud-example/pkg/middleware"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+    var calls atomic.Int32
+    release := make(chan struct{})
+    started := make(chan struct{})
+
+    router := mux.NewRouter()
+    router.Use(middleware.IdempotencyMiddleware(middleware.IdempotencyOptions{
+        Store:       idempotency.NewMemoryStore(),
+        TTL:         time.Hour,
+        LockTimeout: time.Minute,
+        Routes:      []string{"POST /users"},
+    }))
+    router.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+        n := calls.Add(1)
+        body, _ := io.ReadAll(r.Body)
+        switch {
+        case bytes.Contains(body, []byte("slow")):
+            close(started)
+            <-release
+        case bytes.Contains(body, []byte("fail")):
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        case bytes.Contains(body, []byte("silent")) && n%2 == 1:
+            // Так отвечает обработчик, когда клиент отключился
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("ETag", fmt.Sprintf(`"%d"`, n))
+        w.WriteHeader(http.StatusOK)
+        fmt.Fprintf(w, `{"id":"%d"}`, n)
+    }).Methods("POST")
+    router.HandleFunc("/users:batch", func(w http.ResponseWriter, r *http.Request) {
+        calls.Add(1)
+    }).Methods("POST")
+
+    serveContext := func(ctx context.Context, path, key, body string) *httptest.ResponseRecorder {
+        r := httptest.NewRequest("POST", path, strings.NewReader(body)).WithContext(ctx)
+        if key != "" {
+            r.Header.Set(middleware.IdempotencyKeyHeader, key)
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, r)
+        return w
+    }
+    serve := func(path, key, body string) *httptest.ResponseRecorder {
+        return serveContext(context.Background(), path, key, body)
+    }
+
+    first := serve("/users", "k1", `{"name": "John"}`)
+    if first.Code != http.StatusOK || first.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+        t.Fatalf("first POST returned %d with headers %v", first.Code, first.Header())
+    }
+
+    // Повтор получает исходный ответ, обработчик не вызывается
+    retry := serve("/users", "k1", `{"name": "John"}`)
+    if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() ||
+        retry.Header().Get("ETag") != first.Header().Get("ETag") || retry.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+        t.Errorf("retry returned %d %s with headers %v", retry.Code, retry.Body, retry.Header())
+    }
+    if got := calls.Load(); got != 1 {
+        t.Errorf("handler called %d times, want 1", got)
+    }
+
+    if w := serve("/users", "k1", `{"name": "Ann"}`); w.Code != http.StatusUnprocessableEntity ||
+        !strings.Contains(w.Body.String(), middleware.CodeIdempotencyKeyReused) {
+        t.Errorf("reused key with another body returned %d: %s", w.Code, w.Body)
+    }
+
+    // Ответ 5xx не сохраняется, запрос выполняется повторно
+    serve("/users", "k2", `{"name": "fail"}`)
+    if w := serve("/users", "k2", `{"name": "fail"}`); w.Code != http.StatusServiceUnavailable || calls.Load() != 3 {
+        t.Errorf("retry after 503 returned %d, handler called %d times", w.Code, calls.Load())
+    }
+
+    // Без ключа и на других маршрутах запросы не дедуплицируются
+    serve("/users", "", `{"name": "John"}`)
+    serve("/users:batch", "k1", `{}`)
+    serve("/users:batch", "k1", `{}`)
+    if got := calls.Load(); got != 6 {
+        t.Errorf("handler called %d times, want 6", got)
+    }
+
+    // Пустой ответ и ответ на отмененный запрос не сохраняются
+    serve("/users", "k4", `{"name": "silent"}`)
+    if w := serve("/users", "k4", `{"name": "silent"}`); w.Code != http.StatusOK || w.Header().Get(middleware.IdempotentReplayedHeader) != "" || calls.Load() != 8 {
+        t.Errorf("retry after empty response returned %d with headers %v, handler called %d times", w.Code, w.Header(), calls.Load())
+    }
+    canceled, cancel := context.WithCancel(context.Background())
+    cancel()
+    serveContext(canceled, "/users", "k5", `{"name": "John"}`)
+    if w := serve("/users", "k5", `{"name": "John"}`); w.Header().Get(middleware.IdempotentReplayedHeader) != "" || calls.Load() != 10 {
+        t.Errorf("retry after canceled request returned headers %v, handler called %d times", w.Header(), calls.Load())
+    }
+
+    if w := serve("/users", strings.Repeat("k", 256), `{}`); w.Code != http.StatusBadRequest ||
+        !strings.Contains(w.Body.String(), middleware.CodeInvalidIdempotencyKey) {
+        t.Errorf("too long key returned %d: %s", w.Code, w.Body)
+    }
+
+    // Одновременный дубликат получает 409, пока первый запрос выполняется
+    done := make(chan *httptest.ResponseRecorder)
+    go func() { done <- serve("/users", "k3", `{"name": "slow"}`) }()
+    <-started
+    if w := serve("/users", "k3", `{"name": "slow"}`); w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" ||
+        !strings.Contains(w.Body.String(), middleware.CodeIdempotencyKeyInUse) {
+        t.Errorf("concurrent duplicate returned %d: %s", w.Code, w.Body)
+    }
+    close(release)
+    if w := <-done; w.Code != http.StatusOK {
+        t.Errorf("slow request returned %d", w.Code)
+    }
+    if w := serve("/users", "k3", `{"name": "slow"}`); w.Header().Get(middleware.IdempotentReplayedHeader) != "true" {
+        t.Errorf("retry after slow request returned %d without replay", w.Code)
+    }
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+    "bytes"
+    "encoding/json"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/pkg/config"
+    "go-crud-example/pkg/logger"
+    "go-crud-example/pkg/middleware"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+    var buf bytes.Buffer
+    log, err := logger.New(&buf, config.LogConfig{Level: "info", Format: "json"})
+    if err != nil {
+        t.Fatalf("logger.New() error = %v", err)
+    }
+
+    router := mux.NewRouter()
+    router.Use(middleware.RequestIDMiddleware)
+    router.Use(middleware.LoggingMiddleware(log))
+    router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+        logger.AddAttrs(r.Context(), slog.String("user_id", mux.Vars(r)["id"]))
+        w.WriteHeader(http.StatusNotFound)
+    })
+
+    req := httptest.NewRequest("GET", "/users/42", nil)
+    req.Header.Set("X-Request-ID", "req-1")
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+
+    if got := w.Header().Get("X-Request-ID"); got != "req-1" {
+        t.Errorf("X-Request-ID = %q, want %q", got, "req-1")
+    }
+
+    var entry map[string]interface{}
+    if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+        t.Fatalf("log entry is not JSON: %v (%s)", err, buf.String())
+    }
+
+    want := map[string]interface{}{
+        "level":      "WARN",
+        "msg":        "request completed",
+        "request_id": "req-1",
+        "method":     "GET",
+        "route":      "/users/{id}",
+        "status":     float64(http.StatusNotFound),
+        "user_id":    "42",
+    }
+    for key, value := range want {
+        if entry[key] != value {
+            t.Errorf("log entry %s = %v, want %v", key, entry[key], value)
+        }
+    }
+}
+
+func TestRequestIDMiddleware_Generates(t *testing.T) {
+    handler := middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+    w := httptest.NewRecorder()
+    handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+    if w.Header().Get("X-Request-ID") == "" {
+        t.Error("expected generated X-Request-ID")
+    }
+}
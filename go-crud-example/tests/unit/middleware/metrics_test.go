@@ -0,0 +1,34 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "github.com/prometheus/client_golang/prometheus/testutil"
+    "go-crud-example/pkg/metrics"
+    "go-crud-example/pkg/middleware"
+)
+
+func TestMetricsMiddleware_UsesRouteTemplate(t *testing.T) {
+    router := mux.NewRouter()
+    router.Use(middleware.MetricsMiddleware)
+    router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusNoContent)
+    }).Methods("DELETE")
+
+    counter := metrics.HttpRequestsTotal.WithLabelValues("DELETE", "/users/{id}", "204")
+    before := testutil.ToFloat64(counter)
+
+    for _, id := range []string{"1", "2", "3"} {
+        router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/users/"+id, nil))
+    }
+
+    if got := testutil.ToFloat64(counter) - before; got != 3 {
+        t.Errorf("http_requests_total increased by %v, want 3", got)
+    }
+    if n := testutil.CollectAndCount(metrics.HttpRequestsTotal, "http_requests_total"); n != 1 {
+        t.Errorf("http_requests_total has %d series, want 1", n)
+    }
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/pkg/actor"
+    "go-crud-example/pkg/middleware"
+    "go-crud-example/pkg/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+    return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func newRateLimitedRouter(store ratelimit.Store) *mux.Router {
+    router := mux.NewRouter()
+    // Вместо AuthMiddleware актор задается заголовком
+    router.Use(func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if subject := r.Header.Get("X-Subject"); subject != "" {
+                r = r.WithContext(actor.NewContext(r.Context(), subject))
+            }
+            next.ServeHTTP(w, r)
+        })
+    })
+    router.Use(middleware.RateLimitMiddleware(middleware.RateLimitOptions{
+        Store:   store,
+        Default: ratelimit.Limit{Requests: 3, Period: time.Minute},
+        Routes: map[string]ratelimit.Limit{
+            "POST /users": {Requests: 1, Period: 10 * time.Second},
+        },
+        Exempt: []string{"/health"},
+    }))
+
+    ok := func(w http.ResponseWriter, r *http.Request) {}
+    router.HandleFunc("/users", ok).Methods("GET", "POST")
+    router.HandleFunc("/users/{id}", ok).Methods("GET")
+    router.HandleFunc("/health", ok).Methods("GET")
+    return router
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+    router := newRateLimitedRouter(ratelimit.NewMemoryStore())
+
+    serve := func(method, path, subject, ip string) *httptest.ResponseRecorder {
+        r := httptest.NewRequest(method, path, nil)
+        r.RemoteAddr = ip + ":40000"
+        if subject != "" {
+            r.Header.Set("X-Subject", subject)
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, r)
+        return w
+    }
+
+    // Маршрут с собственным лимитом
+    w := serve("POST", "/users", "alice", "10.0.0.1")
+    if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Remaining") != "0" ||
+        w.Header().Get("RateLimit-Reset") != "10" || w.Header().Get("RateLimit-Policy") != "1;w=10" {
+        t.Fatalf("first POST /users returned %d with headers %v", w.Code, w.Header())
+    }
+    w = serve("POST", "/users", "alice", "10.0.0.1")
+    if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "10" ||
+        !strings.Contains(w.Body.String(), middleware.CodeRateLimited) {
+        t.Errorf("second POST /users returned %d with Retry-After %q: %s", w.Code, w.Header().Get("Retry-After"), w.Body)
+    }
+    // Лимит считается на клиента
+    if w := serve("POST", "/users", "bob", "10.0.0.1"); w.Code != http.StatusOK {
+        t.Errorf("POST /users by another subject returned %d", w.Code)
+    }
+
+    // Маршруты без своего лимита делят общую корзину клиента
+    for i, path := range []string{"/users", "/users/1", "/users/2"} {
+        if w := serve("GET", path, "", "10.0.0.2"); w.Code != http.StatusOK {
+            t.Fatalf("GET %s #%d returned %d", path, i, w.Code)
+        }
+    }
+    if w := serve("GET", "/users", "", "10.0.0.2"); w.Code != http.StatusTooManyRequests {
+        t.Errorf("GET /users over the default limit returned %d", w.Code)
+    }
+    if w := serve("GET", "/users", "", "10.0.0.3"); w.Code != http.StatusOK {
+        t.Errorf("GET /users from another IP returned %d", w.Code)
+    }
+
+    for i := 0; i < 5; i++ {
+        if w := serve("GET", "/health", "", "10.0.0.2"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+            t.Fatalf("exempt GET /health returned %d with headers %v", w.Code, w.Header())
+        }
+    }
+}
+
+func TestRateLimitMiddleware_StoreFailure(t *testing.T) {
+    router := newRateLimitedRouter(failingStore{})
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest("GET", "/users", nil))
+    if w.Code != http.StatusOK {
+        t.Errorf("GET /users with failing store returned %d, want %d", w.Code, http.StatusOK)
+    }
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/gorilla/mux"
+    "go-crud-example/pkg/middleware"
+)
+
+func TestTimeoutMiddleware_ExemptRoutes(t *testing.T) {
+    router := mux.NewRouter()
+    router.Use(middleware.TimeoutMiddleware(time.Second, "/users/export"))
+
+    hasDeadline := func(w http.ResponseWriter, r *http.Request) {
+        if _, ok := r.Context().Deadline(); ok {
+            w.WriteHeader(http.StatusOK)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    }
+    router.HandleFunc("/users/export", hasDeadline).Methods("GET")
+    router.HandleFunc("/users/{id}", hasDeadline).Methods("GET")
+
+    tests := []struct {
+        path string
+        want int
+    }{
+        {path: "/users/1", want: http.StatusOK},
+        {path: "/users/export", want: http.StatusNoContent},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+        if w.Code != tt.want {
+            t.Errorf("GET %s: deadline status %d, want %d", tt.path, w.Code, tt.want)
+        }
+    }
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+    "strings"
+    "testing"
+
+    "go-crud-example/internal/migrations"
+)
+
+func TestAll(t *testing.T) {
+    for _, dialect := range []migrations.Dialect{migrations.Postgres, migrations.SQLite} {
+        t.Run(dialect.String(), func(t *testing.T) {
+            all, err := migrations.All(dialect)
+            if err != nil {
+                t.Fatalf("migrations.All() error = %v", err)
+            }
+            if len(all) == 0 {
+                t.Fatal("migrations.All() returned no migrations")
+            }
+
+            for i, m := range all {
+                if m.Version != int64(i+1) {
+                    t.Errorf("migration %d_%s: version = %d, want %d", m.Version, m.Name, m.Version, i+1)
+                }
+                if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+                    t.Errorf("migration %d_%s has empty up or down script", m.Version, m.Name)
+                }
+            }
+        })
+    }
+}
+
+// Все диалекты должны иметь одинаковый набор версий, чтобы схема
+// развивалась согласованно
+func TestAll_DialectsInSync(t *testing.T) {
+    postgres, err := migrations.All(migrations.Postgres)
+    if err != nil {
+        t.Fatalf("migrations.All(Postgres) error = %v", err)
+    }
+    sqlite, err := migrations.All(migrations.SQLite)
+    if err != nil {
+        t.Fatalf("migrations.All(SQLite) error = %v", err)
+    }
+
+    if len(postgres) != len(sqlite) {
+        t.Fatalf("postgres has %d migrations, sqlite has %d", len(postgres), len(sqlite))
+    }
+    for i := range postgres {
+        if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+            t.Errorf("migration %d: postgres %d_%s, sqlite %d_%s", i,
+                postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+        }
+    }
+}
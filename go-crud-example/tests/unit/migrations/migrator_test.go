@@ -0,0 +1,72 @@
+package migrations
+
+import (
+    "context"
+    "database/sql"
+    "path/filepath"
+    "testing"
+
+    "go-crud-example/internal/migrations"
+    _ "modernc.org/sqlite"
+)
+
+func TestMigrator_SQLite(t *testing.T) {
+    db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+    if err != nil {
+        t.Fatalf("failed to open database: %v", err)
+    }
+    defer db.Close()
+
+    migrator, err := migrations.NewMigrator(db, migrations.SQLite)
+    if err != nil {
+        t.Fatalf("NewMigrator() error = %v", err)
+    }
+    ctx := context.Background()
+
+    if err := migrator.Up(ctx); err != nil {
+        t.Fatalf("Up() error = %v", err)
+    }
+    // Повторный запуск ничего не делает
+    if err := migrator.Up(ctx); err != nil {
+        t.Fatalf("second Up() error = %v", err)
+    }
+    assertApplied(t, migrator, -1)
+    if _, err := db.Exec("INSERT INTO users (name, age) VALUES ('John', 30)"); err != nil {
+        t.Fatalf("users table is not usable after Up(): %v", err)
+    }
+
+    if err := migrator.Goto(ctx, 0); err != nil {
+        t.Fatalf("Goto(0) error = %v", err)
+    }
+    assertApplied(t, migrator, 0)
+    if _, err := db.Exec("SELECT 1 FROM users"); err == nil {
+        t.Error("users table still exists after Goto(0)")
+    }
+
+    if err := migrator.Goto(ctx, 1); err != nil {
+        t.Fatalf("Goto(1) error = %v", err)
+    }
+    assertApplied(t, migrator, 1)
+
+    if err := migrator.Goto(ctx, 999); err == nil {
+        t.Error("Goto(999) expected unknown version error")
+    }
+}
+
+// assertApplied проверяет, что применено ровно n первых миграций (-1 - все)
+func assertApplied(t *testing.T, migrator *migrations.Migrator, n int) {
+    t.Helper()
+
+    statuses, err := migrator.Status(context.Background())
+    if err != nil {
+        t.Fatalf("Status() error = %v", err)
+    }
+    if n < 0 {
+        n = len(statuses)
+    }
+    for i, s := range statuses {
+        if want := i < n; s.Applied != want {
+            t.Errorf("migration %d_%s applied = %v, want %v", s.Version, s.Name, s.Applied, want)
+        }
+    }
+}
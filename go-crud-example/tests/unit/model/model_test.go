@@ -1,7 +1,11 @@
 package model
 
 import (
+    "errors"
+    "fmt"
+    "reflect"
     "testing"
+
     m "go-crud-example/internal/model"
 )
 
@@ -27,6 +31,24 @@ func TestUser_Validate(t *testing.T) {
             },
             wantErr: true,
         },
+        {
+            name: "valid user with email",
+            user: m.User{
+                Name:  "John Doe",
+                Age:   25,
+                Email: "john@example.com",
+            },
+            wantErr: false,
+        },
+        {
+            name: "invalid email",
+            user: m.User{
+                Name:  "John Doe",
+                Age:   25,
+                Email: "john.example.com",
+            },
+            wantErr: true,
+        },
         {
             name: "invalid age - too high",
             user: m.User{
@@ -45,3 +67,27 @@ func TestUser_Validate(t *testing.T) {
         })
     }
 }
+
+func TestImportReport_AddError(t *testing.T) {
+    var report m.ImportReport
+    // Текст ошибок хранилища не попадает в отчет
+    storageErr := fmt.Errorf("%w: %w", m.ErrInvalidUser, errors.New(`pq: value too long for type character varying(254) in column "email"`))
+    if !report.AddError(2, storageErr) {
+        t.Error("AddError() of invalid user = false, want true")
+    }
+    if report.AddError(3, errors.New("pq: connection reset")) {
+        t.Error("AddError() of unknown error = true, want false")
+    }
+    if !report.AddError(4, &m.ImportFieldError{Message: "invalid JSON: unexpected EOF"}) {
+        t.Error("AddError() of parse error = false, want true")
+    }
+
+    want := []m.ImportError{
+        {Line: 2, Message: "invalid user"},
+        {Line: 3, Message: "row could not be imported"},
+        {Line: 4, Message: "invalid JSON: unexpected EOF"},
+    }
+    if !reflect.DeepEqual(report.Errors, want) || report.Failed != 3 {
+        t.Errorf("report = %+v, want errors %+v", report, want)
+    }
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "go-crud-example/pkg/ratelimit"
+)
+
+func TestParseLimit(t *testing.T) {
+    tests := []struct {
+        value   string
+        want    ratelimit.Limit
+        wantErr bool
+    }{
+        {value: "10/s", want: ratelimit.Limit{Requests: 10, Period: time.Second}},
+        {value: "10/m", want: ratelimit.Limit{Requests: 10, Period: time.Minute}},
+        {value: " 1000/h ", want: ratelimit.Limit{Requests: 1000, Period: time.Hour}},
+        {value: "5/10s", want: ratelimit.Limit{Requests: 5, Period: 10 * time.Second}},
+        {value: "10", wantErr: true},
+        {value: "0/m", wantErr: true},
+        {value: "-1/m", wantErr: true},
+        {value: "10/week", wantErr: true},
+        {value: "10/-1s", wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.value, func(t *testing.T) {
+            got, err := ratelimit.ParseLimit(tt.value)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+            }
+            if got != tt.want {
+                t.Errorf("ParseLimit(%q) = %v, want %v", tt.value, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+    store := ratelimit.NewMemoryStore()
+    limit := ratelimit.Limit{Requests: 3, Period: time.Minute}
+    ctx := context.Background()
+    now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+    take := func(key string, at time.Time) ratelimit.Result {
+        t.Helper()
+        result, err := store.Take(ctx, key, limit, at)
+        if err != nil {
+            t.Fatalf("Take() error = %v", err)
+        }
+        return result
+    }
+
+    // Полная корзина позволяет сделать Requests запросов подряд
+    for i := 2; i >= 0; i-- {
+        if result := take("alice", now); !result.Allowed || result.Remaining != i || result.Limit != 3 {
+            t.Fatalf("Take() = %+v, want allowed with %d remaining", result, i)
+        }
+    }
+    result := take("alice", now)
+    if result.Allowed || result.RetryAfter != 20*time.Second || result.ResetAfter != time.Minute {
+        t.Errorf("Take() on empty bucket = %+v, want rejected with retry after 20s", result)
+    }
+
+    // Другой клиент не делит корзину с первым
+    if result := take("bob", now); !result.Allowed {
+        t.Errorf("Take() for another key = %+v, want allowed", result)
+    }
+
+    // Токен возвращается за Period/Requests
+    if result := take("alice", now.Add(19*time.Second)); result.Allowed {
+        t.Errorf("Take() before refill = %+v, want rejected", result)
+    }
+    if result := take("alice", now.Add(20*time.Second)); !result.Allowed || result.Remaining != 0 {
+        t.Errorf("Take() after refill = %+v, want allowed", result)
+    }
+
+    // После долгого простоя корзина полна, но не больше емкости
+    if result := take("alice", now.Add(time.Hour)); !result.Allowed || result.Remaining != 2 {
+        t.Errorf("Take() after idle = %+v, want allowed with 2 remaining", result)
+    }
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+)
+
+func TestMemoryAPIKeyRepository(t *testing.T) {
+    testAPIKeyRepository(t, repository.NewMemoryAPIKeyRepository())
+}
+
+func TestSQLiteAPIKeyRepository(t *testing.T) {
+    testAPIKeyRepository(t, repository.NewSQLiteAPIKeyRepository(openSQLite(t)))
+}
+
+func testAPIKeyRepository(t *testing.T, repo repository.APIKeyRepository) {
+    ctx := context.Background()
+    expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+    key := &model.APIKey{
+        Name:      "nightly import",
+        Prefix:    "gck_abcdefgh",
+        Scopes:    []string{"users:import", "users:create"},
+        CreatedBy: "alice",
+        ExpiresAt: &expiresAt,
+    }
+    if err := repo.Create(ctx, key, "hash-1"); err != nil {
+        t.Fatalf("Create() error = %v", err)
+    }
+    if key.ID == "" || key.CreatedAt.IsZero() {
+        t.Fatalf("Create() did not set id and created_at: %+v", key)
+    }
+    if err := repo.Create(ctx, &model.APIKey{Name: "other", Prefix: "gck_ijklmnop", Scopes: []string{"*"}, CreatedBy: "alice"}, "hash-2"); err != nil {
+        t.Fatalf("Create() error = %v", err)
+    }
+
+    found, err := repo.GetByHash(ctx, "hash-1")
+    if err != nil {
+        t.Fatalf("GetByHash() error = %v", err)
+    }
+    if found.ID != key.ID || len(found.Scopes) != 2 || found.Scopes[1] != "users:create" ||
+        found.ExpiresAt == nil || !found.ExpiresAt.Equal(expiresAt) || found.RevokedAt != nil {
+        t.Errorf("GetByHash() = %+v, want %+v", found, key)
+    }
+    if _, err := repo.GetByHash(ctx, "unknown"); !errors.Is(err, repository.ErrAPIKeyNotFound) {
+        t.Errorf("GetByHash(unknown) error = %v, want ErrAPIKeyNotFound", err)
+    }
+
+    revoked, err := repo.Revoke(ctx, key.ID)
+    if err != nil || revoked.RevokedAt == nil {
+        t.Fatalf("Revoke() = %+v, %v", revoked, err)
+    }
+    again, err := repo.Revoke(ctx, key.ID)
+    if err != nil || !again.RevokedAt.Equal(*revoked.RevokedAt) {
+        t.Errorf("second Revoke() = %+v, %v, want revoked_at unchanged", again, err)
+    }
+    for _, id := range []string{"999", "abc"} {
+        if _, err := repo.Revoke(ctx, id); !errors.Is(err, repository.ErrAPIKeyNotFound) {
+            t.Errorf("Revoke(%q) error = %v, want ErrAPIKeyNotFound", id, err)
+        }
+    }
+
+    keys, err := repo.List(ctx)
+    if err != nil {
+        t.Fatalf("List() error = %v", err)
+    }
+    if len(keys) != 2 || keys[0].ID != key.ID || keys[0].RevokedAt == nil || keys[1].RevokedAt != nil {
+        t.Errorf("List() = %+v", keys)
+    }
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "sync"
+    "testing"
+    "time"
+
+    "go-crud-example/internal/repository"
+    "go-crud-example/pkg/idempotency"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+    testIdempotencyStore(t, idempotency.NewMemoryStore())
+}
+
+func TestSQLiteIdempotencyStore(t *testing.T) {
+    testIdempotencyStore(t, repository.NewSQLiteIdempotencyStore(openSQLite(t)))
+}
+
+func testIdempotencyStore(t *testing.T, store idempotency.Store) {
+    ctx := context.Background()
+    now := time.Now().UTC()
+
+    alice, reserved, err := store.Reserve(ctx, "alice k1", "fp1", now, now.Add(time.Minute))
+    if err != nil || !reserved || alice.Token == "" {
+        t.Fatalf("Reserve() = %+v, %v, %v, want reserved with token", alice, reserved, err)
+    }
+
+    // Пока запрос выполняется, ключ занят и ответа нет
+    record, reserved, err := store.Reserve(ctx, "alice k1", "fp1", now, now.Add(time.Minute))
+    if err != nil || reserved || record.Fingerprint != "fp1" || record.Response != nil || record.Token != "" {
+        t.Fatalf("Reserve() of in-flight key = %+v, %v, %v", record, reserved, err)
+    }
+
+    response := idempotency.Response{Status: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte(`{"id":"1"}`)}
+    if err := store.Complete(ctx, "alice k1", alice.Token, response, now.Add(time.Hour)); err != nil {
+        t.Fatalf("Complete() error = %v", err)
+    }
+    record, reserved, err = store.Reserve(ctx, "alice k1", "fp2", now.Add(2*time.Minute), now.Add(3*time.Minute))
+    if err != nil || reserved || record.Fingerprint != "fp1" || record.Response == nil {
+        t.Fatalf("Reserve() of completed key = %+v, %v, %v", record, reserved, err)
+    }
+    if got := record.Response; got.Status != response.Status || string(got.Body) != string(response.Body) ||
+        got.Header.Get("Content-Type") != "application/json" {
+        t.Errorf("stored response = %+v, want %+v", got, response)
+    }
+
+    // Ключи разных клиентов независимы, освобожденный ключ можно занять снова
+    bob, reserved, err := store.Reserve(ctx, "bob k1", "fp3", now, now.Add(time.Minute))
+    if err != nil || !reserved {
+        t.Fatalf("Reserve() for another client = %v, %v", reserved, err)
+    }
+    if err := store.Release(ctx, "bob k1", bob.Token); err != nil {
+        t.Fatalf("Release() error = %v", err)
+    }
+    stale, reserved, err := store.Reserve(ctx, "bob k1", "fp4", now, now.Add(time.Minute))
+    if err != nil || !reserved {
+        t.Fatalf("Reserve() after Release() = %v, %v", reserved, err)
+    }
+
+    // Release не удаляет сохраненный ответ
+    if err := store.Release(ctx, "alice k1", alice.Token); err != nil {
+        t.Fatalf("Release() error = %v", err)
+    }
+    if record, _, _ := store.Reserve(ctx, "alice k1", "fp1", now, now.Add(time.Minute)); record == nil || record.Response == nil {
+        t.Errorf("Release() removed the completed key: %+v", record)
+    }
+
+    // Истекший ключ занимается заново. Запрос, потерявший ключ, не может
+    // ни сохранить ответ, ни освободить ключ нового владельца
+    fresh, reserved, err := store.Reserve(ctx, "bob k1", "fp5", now.Add(time.Minute), now.Add(2*time.Minute))
+    if err != nil || !reserved || fresh.Token == stale.Token {
+        t.Fatalf("Reserve() of expired key = %+v, %v, %v, want reserved with a new token", fresh, reserved, err)
+    }
+    if err := store.Complete(ctx, "bob k1", stale.Token, response, now.Add(time.Hour)); !errors.Is(err, idempotency.ErrNotReserved) {
+        t.Errorf("Complete() with expired reservation error = %v, want ErrNotReserved", err)
+    }
+    if err := store.Release(ctx, "bob k1", stale.Token); err != nil {
+        t.Fatalf("Release() error = %v", err)
+    }
+    record, reserved, err = store.Reserve(ctx, "bob k1", "fp6", now.Add(time.Minute), now.Add(2*time.Minute))
+    if err != nil || reserved || record.Fingerprint != "fp5" || record.Response != nil {
+        t.Errorf("Reserve() after stale Complete() and Release() = %+v, %v, %v, want in-flight fp5", record, reserved, err)
+    }
+
+    deleted, err := store.DeleteExpired(ctx, now.Add(30*time.Minute))
+    if err != nil || deleted != 1 {
+        t.Errorf("DeleteExpired() = %d, %v, want 1", deleted, err)
+    }
+}
+
+func TestSQLiteIdempotencyStore_Concurrent(t *testing.T) {
+    store := repository.NewSQLiteIdempotencyStore(openSQLite(t))
+    now := time.Now().UTC()
+
+    var (
+        wg       sync.WaitGroup
+        mu       sync.Mutex
+        reserved int
+    )
+    for i := 0; i < 10; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            _, ok, err := store.Reserve(context.Background(), "alice k1", "fp", now, now.Add(time.Minute))
+            if err != nil {
+                t.Errorf("Reserve() error = %v", err)
+                return
+            }
+            if ok {
+                mu.Lock()
+                reserved++
+                mu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+
+    if reserved != 1 {
+        t.Errorf("key reserved %d times, want 1", reserved)
+    }
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+    "context"
+    "testing"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+)
+
+func TestMemoryUserRepository_SnapshotRestore(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    ctx := context.Background()
+
+    if err := repo.Create(ctx, &model.User{Name: "John", Age: 30}); err != nil {
+        t.Fatalf("Create() error = %v", err)
+    }
+    snapshot := repo.Snapshot()
+
+    if err := repo.Create(ctx, &model.User{Name: "Ann", Age: 25}); err != nil {
+        t.Fatalf("Create() error = %v", err)
+    }
+    if err := repo.Delete(ctx, "1"); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+
+    repo.RestoreSnapshot(snapshot)
+
+    page, err := repo.GetAll(ctx, model.UserQuery{})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    if page.Total != 1 || page.Users[0].Name != "John" {
+        t.Errorf("GetAll() after restore = %+v, want only John", page.Users)
+    }
+
+    // Счетчик id тоже восстанавливается
+    user := &model.User{Name: "Kate", Age: 20}
+    if err := repo.Create(ctx, user); err != nil {
+        t.Fatalf("Create() error = %v", err)
+    }
+    if user.ID != "2" {
+        t.Errorf("Create() after restore assigned id %s, want 2", user.ID)
+    }
+}
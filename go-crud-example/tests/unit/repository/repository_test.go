@@ -0,0 +1,47 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "path/filepath"
+    "testing"
+
+    "go-crud-example/internal/migrations"
+    "go-crud-example/internal/repository"
+    "go-crud-example/internal/repository/repositorytest"
+    _ "modernc.org/sqlite"
+)
+
+func TestMemoryUserRepository_Conformance(t *testing.T) {
+    repositorytest.Run(t, func(t *testing.T) repository.UserRepository {
+        return repository.NewMemoryUserRepository()
+    })
+}
+
+func TestSQLiteUserRepository_Conformance(t *testing.T) {
+    repositorytest.Run(t, func(t *testing.T) repository.UserRepository {
+        return repository.NewSQLiteUserRepository(openSQLite(t))
+    })
+}
+
+// openSQLite создает файл БД во временном каталоге теста и применяет миграции
+func openSQLite(t *testing.T) *sql.DB {
+    t.Helper()
+
+    dsn := "file:" + filepath.Join(t.TempDir(), "users.db") +
+        "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=case_sensitive_like(1)&_txlock=immediate"
+    db, err := sql.Open("sqlite", dsn)
+    if err != nil {
+        t.Fatalf("failed to open sqlite: %v", err)
+    }
+    t.Cleanup(func() { db.Close() })
+
+    migrator, err := migrations.NewMigrator(db, migrations.SQLite)
+    if err != nil {
+        t.Fatalf("failed to load migrations: %v", err)
+    }
+    if err := migrator.Up(context.Background()); err != nil {
+        t.Fatalf("failed to apply migrations: %v", err)
+    }
+    return db
+}
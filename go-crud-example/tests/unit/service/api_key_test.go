@@ -0,0 +1,155 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "go-crud-example/internal/authz"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    svc "go-crud-example/internal/service"
+    "go-crud-example/pkg/actor"
+)
+
+func TestAPIKeyService_Lifecycle(t *testing.T) {
+    service := svc.NewAPIKeyService(repository.NewMemoryAPIKeyRepository())
+    ctx := actor.NewContext(context.Background(), "alice")
+
+    created, err := service.CreateAPIKey(ctx, model.APIKeyRequest{Name: " nightly import ", Scopes: []string{"users:import"}})
+    if err != nil {
+        t.Fatalf("CreateAPIKey() error = %v", err)
+    }
+    if !strings.HasPrefix(created.Key, model.APIKeyPrefix) || created.Prefix != created.Key[:model.APIKeyDisplayLength] {
+        t.Errorf("key = %q, prefix = %q", created.Key, created.Prefix)
+    }
+    if created.Name != "nightly import" || created.CreatedBy != "alice" {
+        t.Errorf("name = %q, created_by = %q", created.Name, created.CreatedBy)
+    }
+
+    found, err := service.AuthenticateAPIKey(ctx, created.Key)
+    if err != nil || found.ID != created.ID {
+        t.Fatalf("AuthenticateAPIKey() = %v, %v", found, err)
+    }
+    if _, err := service.AuthenticateAPIKey(ctx, created.Key+"x"); !errors.Is(err, svc.ErrInvalidAPIKey) {
+        t.Errorf("AuthenticateAPIKey(unknown) error = %v, want ErrInvalidAPIKey", err)
+    }
+
+    revoked, err := service.RevokeAPIKey(ctx, created.ID)
+    if err != nil || revoked.RevokedAt == nil {
+        t.Fatalf("RevokeAPIKey() = %v, %v", revoked, err)
+    }
+    if _, err := service.AuthenticateAPIKey(ctx, created.Key); !errors.Is(err, svc.ErrInvalidAPIKey) {
+        t.Errorf("AuthenticateAPIKey(revoked) error = %v, want ErrInvalidAPIKey", err)
+    }
+    if _, err := service.RevokeAPIKey(ctx, "42"); !errors.Is(err, svc.ErrAPIKeyNotFound) {
+        t.Errorf("RevokeAPIKey(unknown) error = %v, want ErrAPIKeyNotFound", err)
+    }
+
+    keys, err := service.ListAPIKeys(ctx)
+    if err != nil || len(keys) != 1 || keys[0].RevokedAt == nil {
+        t.Errorf("ListAPIKeys() = %+v, %v", keys, err)
+    }
+}
+
+func TestAPIKeyService_Expired(t *testing.T) {
+    repo := repository.NewMemoryAPIKeyRepository()
+    service := svc.NewAPIKeyService(repo)
+    ctx := context.Background()
+
+    expiresAt := time.Now().Add(50 * time.Millisecond)
+    created, err := service.CreateAPIKey(ctx, model.APIKeyRequest{Name: "short", Scopes: []string{"users:read"}, ExpiresAt: &expiresAt})
+    if err != nil {
+        t.Fatalf("CreateAPIKey() error = %v", err)
+    }
+    if _, err := service.AuthenticateAPIKey(ctx, created.Key); err != nil {
+        t.Fatalf("AuthenticateAPIKey() before expiry error = %v", err)
+    }
+    time.Sleep(time.Until(expiresAt))
+    if _, err := service.AuthenticateAPIKey(ctx, created.Key); !errors.Is(err, svc.ErrInvalidAPIKey) {
+        t.Errorf("AuthenticateAPIKey() after expiry error = %v, want ErrInvalidAPIKey", err)
+    }
+}
+
+func TestAPIKeyService_InvalidRequest(t *testing.T) {
+    past := time.Now().Add(-time.Hour)
+    tests := []struct {
+        name string
+        req  model.APIKeyRequest
+    }{
+        {name: "empty name", req: model.APIKeyRequest{Name: " ", Scopes: []string{"users:read"}}},
+        {name: "no scopes", req: model.APIKeyRequest{Name: "job"}},
+        {name: "unknown scope", req: model.APIKeyRequest{Name: "job", Scopes: []string{"users:drop"}}},
+        {name: "expired", req: model.APIKeyRequest{Name: "job", Scopes: []string{"users:read"}, ExpiresAt: &past}},
+    }
+    service := svc.NewAPIKeyService(repository.NewMemoryAPIKeyRepository())
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := service.CreateAPIKey(context.Background(), tt.req); !errors.Is(err, svc.ErrInvalidAPIKeyRequest) {
+                t.Errorf("CreateAPIKey() error = %v, want ErrInvalidAPIKeyRequest", err)
+            }
+        })
+    }
+}
+
+func TestAuthorizedAPIKeyService(t *testing.T) {
+    service := svc.NewAuthorizedAPIKeyService(svc.NewAPIKeyService(repository.NewMemoryAPIKeyRepository()), authz.Default())
+    req := model.APIKeyRequest{Name: "job", Scopes: []string{"users:read"}}
+
+    editor := actor.WithRoles(actor.NewContext(context.Background(), "bob"), "editor")
+    if _, err := service.CreateAPIKey(editor, req); !errors.Is(err, svc.ErrForbidden) {
+        t.Errorf("CreateAPIKey() by editor error = %v, want ErrForbidden", err)
+    }
+
+    admin := actor.WithRoles(actor.NewContext(context.Background(), "alice"), "admin")
+    created, err := service.CreateAPIKey(admin, req)
+    if err != nil {
+        t.Fatalf("CreateAPIKey() by admin error = %v", err)
+    }
+
+    // Ключ не может получить больше прав, чем у того, кто его выпускает
+    manager := actor.WithScopes(actor.NewContext(context.Background(), "apikey:1"), authz.ActionAPIKeys, "users:read")
+    for _, scopes := range [][]string{{authz.AllActions}, {"users:read", "users:purge"}} {
+        if _, err := service.CreateAPIKey(manager, model.APIKeyRequest{Name: "job", Scopes: scopes}); !errors.Is(err, svc.ErrForbidden) {
+            t.Errorf("CreateAPIKey(%v) by scoped key error = %v, want ErrForbidden", scopes, err)
+        }
+    }
+    if _, err := service.CreateAPIKey(manager, req); err != nil {
+        t.Errorf("CreateAPIKey() within own scopes error = %v", err)
+    }
+
+    // Ключ, выпущенный по API-ключу со сроком, не переживает его
+    parentExpiresAt := time.Now().Add(time.Hour)
+    expiring := actor.WithExpiry(manager, parentExpiresAt)
+    later, earlier := parentExpiresAt.Add(time.Minute), parentExpiresAt.Add(-time.Minute)
+    for name, expiresAt := range map[string]*time.Time{"without expiry": nil, "expiring later": &later} {
+        if _, err := service.CreateAPIKey(expiring, model.APIKeyRequest{Name: "job", Scopes: req.Scopes, ExpiresAt: expiresAt}); !errors.Is(err, svc.ErrForbidden) {
+            t.Errorf("CreateAPIKey() %s by expiring key error = %v, want ErrForbidden", name, err)
+        }
+    }
+    if _, err := service.CreateAPIKey(expiring, model.APIKeyRequest{Name: "job", Scopes: req.Scopes, ExpiresAt: &earlier}); err != nil {
+        t.Errorf("CreateAPIKey() within own expiry error = %v", err)
+    }
+    if _, err := service.CreateAPIKey(admin, model.APIKeyRequest{Name: "all", Scopes: []string{authz.AllActions}}); err != nil {
+        t.Errorf("CreateAPIKey(*) by admin error = %v", err)
+    }
+    policy, err := authz.Parse([]byte(`{"roles": {"keys": {"allow": ["api_keys:manage", "users:list"]}}}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    limited := svc.NewAuthorizedAPIKeyService(svc.NewAPIKeyService(repository.NewMemoryAPIKeyRepository()), policy)
+    keyManager := actor.WithRoles(actor.NewContext(context.Background(), "carol"), "keys")
+    if _, err := limited.CreateAPIKey(keyManager, model.APIKeyRequest{Name: "job", Scopes: []string{"users:delete"}}); !errors.Is(err, svc.ErrForbidden) {
+        t.Errorf("CreateAPIKey() beyond role error = %v, want ErrForbidden", err)
+    }
+    if _, err := limited.CreateAPIKey(keyManager, model.APIKeyRequest{Name: "job", Scopes: []string{"users:list"}}); err != nil {
+        t.Errorf("CreateAPIKey() within role error = %v", err)
+    }
+
+    // Проверка ключа выполняется до аутентификации и прав не требует
+    if _, err := service.AuthenticateAPIKey(context.Background(), created.Key); err != nil {
+        t.Errorf("AuthenticateAPIKey() error = %v", err)
+    }
+}
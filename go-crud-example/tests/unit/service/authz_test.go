@@ -0,0 +1,180 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+
+    "go-crud-example/internal/authz"
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    svc "go-crud-example/internal/service"
+    "go-crud-example/pkg/actor"
+)
+
+func TestAuthorizedUserService_Roles(t *testing.T) {
+    // Каждая операция выполняется над пользователем 1, actor пользователя 2 - "2"
+    operations := map[string]func(ctx context.Context, s svc.UserService) error{
+        "list": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.GetUsers(ctx, model.UserQuery{})
+            return err
+        },
+        "list deleted": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.GetUsers(ctx, model.UserQuery{IncludeDeleted: true})
+            return err
+        },
+        "read": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.GetUser(ctx, "1")
+            return err
+        },
+        "read self": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.GetUser(ctx, "2")
+            return err
+        },
+        "search": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.SearchUsers(ctx, model.SearchQuery{Q: "john"})
+            return err
+        },
+        "create": func(ctx context.Context, s svc.UserService) error {
+            return s.CreateUser(ctx, &model.User{Name: "Kate", Age: 41})
+        },
+        "update": func(ctx context.Context, s svc.UserService) error {
+            return s.UpdateUser(ctx, &model.User{ID: "1", Name: "Johnny", Age: 31})
+        },
+        "update self": func(ctx context.Context, s svc.UserService) error {
+            return s.UpdateUser(ctx, &model.User{ID: "2", Name: "Anna", Age: 26})
+        },
+        "delete": func(ctx context.Context, s svc.UserService) error {
+            return s.DeleteUser(ctx, "1")
+        },
+        "delete self": func(ctx context.Context, s svc.UserService) error {
+            return s.DeleteUser(ctx, "2")
+        },
+        "purge": func(ctx context.Context, s svc.UserService) error {
+            if err := s.PurgeUser(ctx, "1"); !errors.Is(err, svc.ErrUserNotDeleted) {
+                return err
+            }
+            return nil
+        },
+        "import": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.ImportUsers(ctx, strings.NewReader("name,age\nMax,50\n"), model.ImportOptions{Format: model.ImportFormatCSV})
+            return err
+        },
+        "batch": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.BatchUsers(ctx, model.BatchRequest{Operations: []model.BatchOperation{
+                {Op: model.BatchOpCreate, User: &model.User{Name: "Bob", Age: 40}},
+                {Op: model.BatchOpDelete, ID: "1"},
+            }})
+            return err
+        },
+        "audit": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.GetAuditEvents(ctx, model.AuditQuery{})
+            return err
+        },
+        "history self": func(ctx context.Context, s svc.UserService) error {
+            _, err := s.GetAuditEvents(ctx, model.AuditQuery{UserID: "2"})
+            return err
+        },
+    }
+
+    tests := []struct {
+        role    string
+        scopes  []string
+        allowed []string
+    }{
+        {
+            role: "admin",
+            allowed: []string{"list", "list deleted", "read", "read self", "search", "create", "update", "update self",
+                "delete", "delete self", "purge", "import", "batch", "audit", "history self"},
+        },
+        {
+            role: "editor",
+            allowed: []string{"list", "read", "read self", "search", "create", "update", "update self",
+                "delete", "delete self", "batch", "audit", "history self"},
+        },
+        {
+            role:    "viewer",
+            allowed: []string{"list", "read", "read self", "search", "update self", "history self"},
+        },
+        {
+            // Без ролей в токене действуют только правила self роли по умолчанию
+            role:    "",
+            allowed: []string{"read self", "update self", "history self"},
+        },
+        {
+            role:    "unknown",
+            allowed: []string{"read self", "update self", "history self"},
+        },
+        {
+            // Scopes API-ключа заменяют роли и правила self
+            role:    "admin",
+            scopes:  []string{"users:read", "users:import"},
+            allowed: []string{"read", "read self", "import"},
+        },
+    }
+
+    for _, tt := range tests {
+        allowed := make(map[string]bool)
+        for _, name := range tt.allowed {
+            allowed[name] = true
+        }
+        label := tt.role
+        if tt.scopes != nil {
+            label += "+scopes"
+        }
+
+        for name, operation := range operations {
+            t.Run(label+"/"+name, func(t *testing.T) {
+                repo := repository.NewMemoryUserRepository()
+                ctx := context.Background()
+                for _, u := range []*model.User{{Name: "John", Age: 30}, {Name: "Ann", Age: 25}} {
+                    if err := repo.Create(ctx, u); err != nil {
+                        t.Fatalf("Create() error = %v", err)
+                    }
+                }
+                service := svc.NewAuthorizedUserService(svc.NewUserService(repo), authz.Default())
+
+                ctx = actor.NewContext(ctx, "2")
+                if tt.role != "" {
+                    ctx = actor.WithRoles(ctx, tt.role)
+                }
+                if tt.scopes != nil {
+                    ctx = actor.WithScopes(ctx, tt.scopes...)
+                }
+                err := operation(ctx, service)
+                if allowed[name] && err != nil {
+                    t.Errorf("%s by %q error = %v, want allowed", name, label, err)
+                }
+                if !allowed[name] && !errors.Is(err, svc.ErrForbidden) {
+                    t.Errorf("%s by %q error = %v, want ErrForbidden", name, label, err)
+                }
+            })
+        }
+    }
+}
+
+func TestPolicy_Parse(t *testing.T) {
+    tests := []struct {
+        name    string
+        policy  string
+        wantErr string
+    }{
+        {name: "valid", policy: `{"default_roles": ["user"], "roles": {"user": {"allow": ["users:list"], "self": ["users:update"]}}}`},
+        {name: "unknown action", policy: `{"roles": {"user": {"allow": ["users:drop"]}}}`, wantErr: `unknown action "users:drop"`},
+        {name: "self on list", policy: `{"roles": {"user": {"self": ["users:list"]}}}`, wantErr: "cannot be limited to self"},
+        {name: "unknown default role", policy: `{"default_roles": ["guest"], "roles": {}}`, wantErr: `unknown default role "guest"`},
+        {name: "unknown field", policy: `{"roles": {"user": {"deny": ["users:list"]}}}`, wantErr: "unknown field"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := authz.Parse([]byte(tt.policy))
+            if tt.wantErr == "" && err != nil {
+                t.Fatalf("Parse() error = %v", err)
+            }
+            if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+                t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+            }
+        })
+    }
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    svc "go-crud-example/internal/service"
+)
+
+// conflictRepository отклоняет пользователей с занятым именем, как это делало
+// бы уникальное ограничение в БД
+type conflictRepository struct {
+    repository.UserRepository
+    taken   string
+    batches []int
+}
+
+func (r *conflictRepository) Create(ctx context.Context, user *model.User) error {
+    if user.Name == r.taken {
+        return repository.ErrUserConflict
+    }
+    return r.UserRepository.Create(ctx, user)
+}
+
+func (r *conflictRepository) CreateMany(ctx context.Context, users []*model.User) error {
+    r.batches = append(r.batches, len(users))
+    for _, user := range users {
+        if user.Name == r.taken {
+            return repository.ErrUserConflict
+        }
+    }
+    return r.UserRepository.CreateMany(ctx, users)
+}
+
+func TestUserService_ImportUsers(t *testing.T) {
+    tests := []struct {
+        name        string
+        input       string
+        opts        model.ImportOptions
+        wantErr     error
+        wantReport  model.ImportReport
+        wantBatches []int
+    }{
+        {
+            name:  "CSV with extra columns",
+            input: "\ufeffid,Name,age,version\n7,John,30,2\n8,Ann,25,1\n",
+            opts:  model.ImportOptions{Format: model.ImportFormatCSV},
+            wantReport: model.ImportReport{
+                Total: 2, Imported: 2, Errors: []model.ImportError{},
+            },
+            wantBatches: []int{2},
+        },
+        {
+            name:  "CSV row errors",
+            input: "name,age\nJohn,30\nJ,30\nAnn,old\nBob\n\"Kate,30\nMax,40\n",
+            opts:  model.ImportOptions{Format: model.ImportFormatCSV},
+            wantReport: model.ImportReport{
+                Total: 5, Imported: 1, Failed: 4, Errors: []model.ImportError{
+                    {Line: 3, Field: "name", Message: "must satisfy min=2"},
+                    {Line: 4, Field: "age", Message: "must be an integer"},
+                    {Line: 5, Message: "expected 2 fields, got 1"},
+                    {Line: 6, Message: "extraneous or missing \" in quoted-field"},
+                },
+            },
+            wantBatches: []int{1},
+        },
+        {
+            name:  "NDJSON",
+            input: "{\"name\": \"John\", \"age\": 30}\n\n{\"name\": \"Ann\", \"age\": 200}\n{\"name\": \"Bob\", \"phone\": \"x\"}\n{\"id\": \"5\", \"name\": \"Kate\", \"age\": 20}\n",
+            opts:  model.ImportOptions{Format: model.ImportFormatNDJSON, BatchSize: 1},
+            wantReport: model.ImportReport{
+                Total: 4, Imported: 2, Failed: 2, Errors: []model.ImportError{
+                    {Line: 3, Field: "age", Message: "must satisfy lte=150"},
+                    {Line: 4, Message: "invalid JSON: json: unknown field \"phone\""},
+                },
+            },
+            wantBatches: []int{1, 1},
+        },
+        {
+            name:  "Dry run",
+            input: "name,age\nJohn,30\nJ,30\n",
+            opts:  model.ImportOptions{Format: model.ImportFormatCSV, DryRun: true},
+            wantReport: model.ImportReport{
+                DryRun: true, Total: 2, Imported: 1, Failed: 1, Errors: []model.ImportError{
+                    {Line: 3, Field: "name", Message: "must satisfy min=2"},
+                },
+            },
+        },
+        {
+            name:  "CSV with email",
+            input: "name,age,email\nJohn,30,john@example.com\nAnn,25,JOHN@example.com\nBob,40,\nKate,41,kate\n",
+            opts:  model.ImportOptions{Format: model.ImportFormatCSV},
+            wantReport: model.ImportReport{
+                Total: 4, Imported: 2, Failed: 2, Errors: []model.ImportError{
+                    {Line: 3, Field: "email", Message: "is already taken"},
+                    {Line: 5, Field: "email", Message: "must satisfy email"},
+                },
+            },
+            wantBatches: []int{3},
+        },
+        {
+            name:  "Conflict falls back to single inserts",
+            input: "name,age\nJohn,30\nTaken,30\nAnn,25\nBob,40\n",
+            opts:  model.ImportOptions{Format: model.ImportFormatCSV, BatchSize: 3},
+            wantReport: model.ImportReport{
+                Total: 4, Imported: 3, Failed: 1, Errors: []model.ImportError{
+                    {Line: 3, Message: "user conflicts with existing data"},
+                },
+            },
+            wantBatches: []int{3, 1},
+        },
+        {
+            name:  "Errors beyond the limit are counted but not listed",
+            input: "name,age\n" + strings.Repeat("J,30\n", model.MaxImportErrors+1),
+            opts:  model.ImportOptions{Format: model.ImportFormatCSV},
+            wantReport: model.ImportReport{
+                Total: model.MaxImportErrors + 1, Failed: model.MaxImportErrors + 1,
+                Errors: shortNameErrors(model.MaxImportErrors), ErrorsTruncated: true,
+            },
+        },
+        {
+            name:  "Limit keeps the errors of the first lines",
+            input: "name,age\nTaken,30\n" + strings.Repeat("J,30\n", model.MaxImportErrors),
+            opts:  model.ImportOptions{Format: model.ImportFormatCSV},
+            wantReport: model.ImportReport{
+                Total: model.MaxImportErrors + 1, Failed: model.MaxImportErrors + 1,
+                Errors: append([]model.ImportError{{Line: 2, Message: "user conflicts with existing data"}},
+                    shortNameErrors(model.MaxImportErrors)[1:]...),
+                ErrorsTruncated: true,
+            },
+            wantBatches: []int{1},
+        },
+        {
+            name:    "CSV without age column",
+            input:   "name\nJohn\n",
+            opts:    model.ImportOptions{Format: model.ImportFormatCSV},
+            wantErr: svc.ErrInvalidImport,
+        },
+        {
+            name:    "Empty CSV",
+            opts:    model.ImportOptions{Format: model.ImportFormatCSV},
+            wantErr: svc.ErrInvalidImport,
+        },
+        {
+            name:    "Unknown format",
+            opts:    model.ImportOptions{Format: "xml"},
+            wantErr: svc.ErrInvalidImport,
+        },
+        {
+            name:    "Batch size too large",
+            opts:    model.ImportOptions{Format: model.ImportFormatCSV, BatchSize: model.MaxImportBatchSize + 1},
+            wantErr: svc.ErrInvalidImport,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            repo := &conflictRepository{UserRepository: repository.NewMemoryUserRepository(), taken: "Taken"}
+            service := svc.NewUserService(repo)
+
+            report, err := service.ImportUsers(context.Background(), strings.NewReader(tt.input), tt.opts)
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("ImportUsers() error = %v, want %v", err, tt.wantErr)
+            }
+            if err != nil {
+                return
+            }
+            if !reflect.DeepEqual(*report, tt.wantReport) {
+                t.Errorf("ImportUsers() report = %+v, want %+v", *report, tt.wantReport)
+            }
+            if !reflect.DeepEqual(repo.batches, tt.wantBatches) {
+                t.Errorf("CreateMany() batches = %v, want %v", repo.batches, tt.wantBatches)
+            }
+
+            page, err := repo.GetAll(context.Background(), model.UserQuery{})
+            if err != nil {
+                t.Fatalf("GetAll() error = %v", err)
+            }
+            wantUsers := tt.wantReport.Imported
+            if tt.opts.DryRun {
+                wantUsers = 0
+            }
+            if page.Total != wantUsers {
+                t.Errorf("repository has %d users, want %d", page.Total, wantUsers)
+            }
+        })
+    }
+}
+
+// shortNameErrors - ошибки строк 2..n+1 со слишком коротким именем
+func shortNameErrors(n int) []model.ImportError {
+    errs := make([]model.ImportError, n)
+    for i := range errs {
+        errs[i] = model.ImportError{Line: i + 2, Field: "name", Message: "must satisfy min=2"}
+    }
+    return errs
+}
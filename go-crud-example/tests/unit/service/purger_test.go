@@ -0,0 +1,64 @@
+package service
+
+import (
+    "context"
+    "io"
+    "log/slog"
+    "testing"
+    "time"
+
+    "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
+    svc "go-crud-example/internal/service"
+    "go-crud-example/pkg/actor"
+)
+
+func TestPurger_PurgeOnce(t *testing.T) {
+    repo := repository.NewMemoryUserRepository()
+    ctx := context.Background()
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    for _, name := range []string{"John", "Ann"} {
+        if err := repo.Create(ctx, &model.User{Name: name, Age: 30}); err != nil {
+            t.Fatalf("Create() error = %v", err)
+        }
+    }
+    if err := repo.Delete(ctx, "1"); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+
+    // Срок хранения не истек
+    purged, err := svc.NewPurger(repo, time.Hour, time.Minute, logger).PurgeOnce(ctx)
+    if err != nil {
+        t.Fatalf("PurgeOnce() error = %v", err)
+    }
+    if purged != 0 {
+        t.Errorf("PurgeOnce() purged %d users, want 0", purged)
+    }
+
+    // Отрицательный срок хранения эквивалентен удалению из будущего
+    purged, err = svc.NewPurger(repo, -time.Minute, time.Minute, logger).PurgeOnce(ctx)
+    if err != nil {
+        t.Fatalf("PurgeOnce() error = %v", err)
+    }
+    if purged != 1 {
+        t.Errorf("PurgeOnce() purged %d users, want 1", purged)
+    }
+
+    page, err := repo.GetAll(ctx, model.UserQuery{IncludeDeleted: true})
+    if err != nil {
+        t.Fatalf("GetAll() error = %v", err)
+    }
+    if page.Total != 1 || page.Users[0].Name != "Ann" {
+        t.Errorf("users after purge = %+v, want only Ann", page.Users)
+    }
+
+    history, err := repo.AuditEvents(ctx, model.AuditQuery{UserID: "1"})
+    if err != nil {
+        t.Fatalf("AuditEvents() error = %v", err)
+    }
+    last := history.Events[len(history.Events)-1]
+    if last.Action != model.AuditActionPurge || last.Actor != actor.System {
+        t.Errorf("last audit event = %s by %s, want %s by %s", last.Action, last.Actor, model.AuditActionPurge, actor.System)
+    }
+}
@@ -1,13 +1,21 @@
 package service
 
 import (
-    "database/sql"
+    "context"
+    "errors"
+    "reflect"
+    "strings"
     "testing"
+    "time"
     "go-crud-example/internal/model"
+    "go-crud-example/internal/repository"
     svc "go-crud-example/internal/service"
+    "go-crud-example/pkg/jsonpatch"
 )
 type mockRepository struct {
-    users map[string]model.User
+    users   map[string]model.User
+    patches []model.UserPatch
+    batches [][]model.BatchOperation
 }
 
 func newMockRepository() *mockRepository {
@@ -16,44 +24,127 @@ func newMockRepository() *mockRepository {
     }
 }
 
-func (m *mockRepository) GetAll() ([]model.User, error) {
+func (m *mockRepository) GetAll(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
     users := make([]model.User, 0, len(m.users))
     for _, user := range m.users {
         users = append(users, user)
     }
-    return users, nil
+    return &model.UserPage{Users: users, Total: len(users)}, nil
 }
 
-func (m *mockRepository) GetByID(id string) (*model.User, error) {
+func (m *mockRepository) Export(ctx context.Context, query model.UserQuery, fn func(model.User) error) error {
+    for _, user := range m.users {
+        if err := fn(user); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (m *mockRepository) Search(ctx context.Context, query model.SearchQuery) ([]model.SearchResult, error) {
+    results := []model.SearchResult{}
+    for _, user := range m.users {
+        if strings.Contains(strings.ToLower(user.Name), strings.ToLower(query.Q)) {
+            results = append(results, model.SearchResult{User: user, Score: 1})
+        }
+    }
+    return results, nil
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
     user, exists := m.users[id]
     if !exists {
-        return nil, sql.ErrNoRows
+        return nil, repository.ErrUserNotFound
     }
     return &user, nil
 }
 
-func (m *mockRepository) Create(user *model.User) error {
+func (m *mockRepository) Create(ctx context.Context, user *model.User) error {
     user.ID = "1" // Для тестов используем фиксированный ID
     m.users[user.ID] = *user
     return nil
 }
 
-func (m *mockRepository) Update(user *model.User) error {
+func (m *mockRepository) Update(ctx context.Context, user *model.User) error {
     if _, exists := m.users[user.ID]; !exists {
-        return sql.ErrNoRows
+        return repository.ErrUserNotFound
     }
     m.users[user.ID] = *user
     return nil
 }
 
-func (m *mockRepository) Delete(id string) error {
+func (m *mockRepository) Patch(ctx context.Context, id string, patch model.UserPatch) (*model.User, error) {
+    user, exists := m.users[id]
+    if !exists {
+        return nil, repository.ErrUserNotFound
+    }
+    if patch.Version > 0 && patch.Version != user.Version {
+        return nil, repository.ErrVersionMismatch
+    }
+    if patch.Name != nil {
+        user.Name = *patch.Name
+    }
+    if patch.Age != nil {
+        user.Age = *patch.Age
+    }
+    user.Version++
+    m.users[id] = user
+    m.patches = append(m.patches, patch)
+    return &user, nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string) error {
     if _, exists := m.users[id]; !exists {
-        return sql.ErrNoRows
+        return repository.ErrUserNotFound
     }
     delete(m.users, id)
     return nil
 }
 
+func (m *mockRepository) Restore(ctx context.Context, id string) (*model.User, error) {
+    user, exists := m.users[id]
+    if !exists {
+        return nil, repository.ErrUserNotFound
+    }
+    return &user, nil
+}
+
+func (m *mockRepository) Purge(ctx context.Context, id string) error {
+    if _, exists := m.users[id]; !exists {
+        return repository.ErrUserNotFound
+    }
+    delete(m.users, id)
+    return nil
+}
+
+func (m *mockRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+    return 0, nil
+}
+
+func (m *mockRepository) CreateMany(ctx context.Context, users []*model.User) error {
+    for _, user := range users {
+        if err := m.Create(ctx, user); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (m *mockRepository) Batch(ctx context.Context, ops []model.BatchOperation, atomic bool) ([]model.BatchResult, error) {
+    m.batches = append(m.batches, ops)
+    results := make([]model.BatchResult, len(ops))
+    for i, op := range ops {
+        if op.Op == model.BatchOpCreate {
+            results[i].User = op.User
+        }
+    }
+    return results, nil
+}
+
+func (m *mockRepository) AuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+    return &model.AuditPage{Events: []model.AuditEvent{}}, nil
+}
+
 func TestUserService_CreateUser(t *testing.T) {
     repo := newMockRepository()
     service := svc.NewUserService(repo)
@@ -83,10 +174,174 @@ func TestUserService_CreateUser(t *testing.T) {
 
     for _, tt := range tests {
         t.Run(tt.name, func(t *testing.T) {
-            err := service.CreateUser(&tt.user)
+            err := service.CreateUser(context.Background(), &tt.user)
             if (err != nil) != tt.wantErr {
                 t.Errorf("UserService.CreateUser() error = %v, wantErr %v", err, tt.wantErr)
             }
         })
     }
 }
+
+func TestUserService_ErrorTaxonomy(t *testing.T) {
+    repo := newMockRepository()
+    service := svc.NewUserService(repo)
+    ctx := context.Background()
+
+    _, err := service.GetUser(ctx, "42")
+    if !errors.Is(err, svc.ErrUserNotFound) {
+        t.Errorf("GetUser() error = %v, want ErrUserNotFound", err)
+    }
+
+    err = service.UpdateUser(ctx, &model.User{ID: "42", Name: "John", Age: 30})
+    if !errors.Is(err, svc.ErrUserNotFound) {
+        t.Errorf("UpdateUser() error = %v, want ErrUserNotFound", err)
+    }
+
+    if err := service.DeleteUser(ctx, "42"); !errors.Is(err, svc.ErrUserNotFound) {
+        t.Errorf("DeleteUser() error = %v, want ErrUserNotFound", err)
+    }
+
+    err = service.CreateUser(ctx, &model.User{Name: "J", Age: 30})
+    if !errors.Is(err, svc.ErrInvalidUser) {
+        t.Errorf("CreateUser() error = %v, want ErrInvalidUser", err)
+    }
+    var validationErr *model.ValidationError
+    if !errors.As(err, &validationErr) || len(validationErr.Violations) != 1 || validationErr.Violations[0].Field != "name" {
+        t.Errorf("CreateUser() error = %#v, want ValidationError for field name", err)
+    }
+}
+
+func TestUserService_PatchUser(t *testing.T) {
+    ctx := context.Background()
+    mergePatch := func(patch string) svc.PatchFunc {
+        return func(doc []byte) ([]byte, error) {
+            return jsonpatch.MergePatch(doc, []byte(patch))
+        }
+    }
+
+    tests := []struct {
+        name        string
+        version     int64
+        patch       string
+        wantErr     error
+        wantPatches []model.UserPatch
+    }{
+        {"changes only patched fields", 0, `{"age": 31}`, nil, []model.UserPatch{{Age: intPtr(31), Version: 1}}},
+        {"no changes skips write", 0, `{"age": 30}`, nil, nil},
+        {"matching version", 1, `{"name": "Johnny"}`, nil, []model.UserPatch{{Name: strPtr("Johnny"), Version: 1}}},
+        {"stale version", 2, `{"age": 31}`, svc.ErrVersionMismatch, nil},
+        {"invalid result", 0, `{"age": 200}`, svc.ErrInvalidUser, nil},
+        {"removed required field", 0, `{"name": null}`, svc.ErrInvalidUser, nil},
+        {"read-only id", 0, `{"id": "2"}`, svc.ErrInvalidUser, nil},
+        {"read-only version", 0, `{"version": 5}`, svc.ErrInvalidUser, nil},
+        {"email", 0, `{"email": "john@example.com"}`, nil, []model.UserPatch{{Email: strPtr("john@example.com"), Version: 1}}},
+        {"invalid email", 0, `{"email": "john"}`, svc.ErrInvalidUser, nil},
+        {"read-only created_at", 0, `{"created_at": "2000-01-01T00:00:00Z"}`, svc.ErrInvalidUser, nil},
+        {"unknown field", 0, `{"nickname": "JJ"}`, svc.ErrInvalidUser, nil},
+        {"malformed patch", 0, `{"age":`, jsonpatch.ErrInvalidPatch, nil},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            repo := newMockRepository()
+            repo.users["1"] = model.User{ID: "1", Name: "John", Age: 30, Version: 1}
+            service := svc.NewUserService(repo)
+
+            _, err := service.PatchUser(ctx, "1", tt.version, mergePatch(tt.patch))
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("PatchUser() error = %v, want %v", err, tt.wantErr)
+            }
+            if !reflect.DeepEqual(repo.patches, tt.wantPatches) {
+                t.Errorf("repository patches = %+v, want %+v", repo.patches, tt.wantPatches)
+            }
+        })
+    }
+
+    repo := newMockRepository()
+    if _, err := svc.NewUserService(repo).PatchUser(ctx, "42", 0, mergePatch(`{}`)); !errors.Is(err, svc.ErrUserNotFound) {
+        t.Errorf("PatchUser() of missing user error = %v, want ErrUserNotFound", err)
+    }
+}
+
+func intPtr(v int) *int {
+    return &v
+}
+
+func strPtr(v string) *string {
+    return &v
+}
+
+func TestUserService_BatchUsers(t *testing.T) {
+    valid := model.BatchOperation{Op: model.BatchOpCreate, User: &model.User{Name: "John", Age: 30}}
+    invalid := model.BatchOperation{Op: model.BatchOpCreate, User: &model.User{Name: "J", Age: 30}}
+    unknown := model.BatchOperation{Op: "upsert", ID: "1"}
+
+    tests := []struct {
+        name       string
+        batch      model.BatchRequest
+        wantErr    error
+        wantErrs   []error
+        wantRepoOp int
+    }{
+        {
+            name:    "Empty batch",
+            batch:   model.BatchRequest{},
+            wantErr: svc.ErrInvalidBatch,
+        },
+        {
+            name:    "Unknown mode",
+            batch:   model.BatchRequest{Mode: "partial", Operations: []model.BatchOperation{valid}},
+            wantErr: svc.ErrInvalidBatch,
+        },
+        {
+            name:    "Too many operations",
+            batch:   model.BatchRequest{Operations: make([]model.BatchOperation, model.MaxBatchOperations+1)},
+            wantErr: svc.ErrInvalidBatch,
+        },
+        {
+            name:       "Atomic by default",
+            batch:      model.BatchRequest{Operations: []model.BatchOperation{valid, valid}},
+            wantErrs:   []error{nil, nil},
+            wantRepoOp: 2,
+        },
+        {
+            name:     "Atomic with invalid operation",
+            batch:    model.BatchRequest{Operations: []model.BatchOperation{valid, invalid}},
+            wantErrs: []error{svc.ErrBatchAborted, svc.ErrInvalidUser},
+        },
+        {
+            name:       "Best effort skips invalid operations",
+            batch:      model.BatchRequest{Mode: model.BatchModeBestEffort, Operations: []model.BatchOperation{invalid, valid, unknown}},
+            wantErrs:   []error{svc.ErrInvalidUser, nil, svc.ErrInvalidBatch},
+            wantRepoOp: 1,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            repo := newMockRepository()
+            service := svc.NewUserService(repo)
+
+            results, err := service.BatchUsers(context.Background(), tt.batch)
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("BatchUsers() error = %v, want %v", err, tt.wantErr)
+            }
+            if len(results) != len(tt.wantErrs) {
+                t.Fatalf("BatchUsers() returned %d results, want %d", len(results), len(tt.wantErrs))
+            }
+            for i, want := range tt.wantErrs {
+                if !errors.Is(results[i].Err, want) {
+                    t.Errorf("result %d error = %v, want %v", i, results[i].Err, want)
+                }
+            }
+
+            ops := 0
+            for _, batch := range repo.batches {
+                ops += len(batch)
+            }
+            if ops != tt.wantRepoOp {
+                t.Errorf("repository received %d operations, want %d", ops, tt.wantRepoOp)
+            }
+        })
+    }
+}